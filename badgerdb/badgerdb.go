@@ -1,8 +1,13 @@
 package badgerdb
 
 import (
+	"errors"
+	"net/url"
+	"time"
+
 	"github.com/dgraph-io/badger"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -27,7 +32,17 @@ func (s Store) Set(k string, v any) error {
 		return err
 	}
 
-	err = s.db.Update(func(txn *badger.Txn) error {
+	return s.SetRaw(k, data)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(txn *badger.Txn) error {
 		return txn.Set([]byte(k), data)
 	})
 	if err != nil {
@@ -36,6 +51,27 @@ func (s Store) Set(k string, v any) error {
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (s Store) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(k), data).WithTTL(ttl))
+	})
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -47,7 +83,22 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 		return false, err
 	}
 
-	var data []byte
+	data, found, err := s.GetRaw(k)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
 	err = s.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get([]byte(k))
 		if err != nil {
@@ -65,12 +116,69 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	})
 	// If no value was found return false
 	if err == badger.ErrKeyNotFound {
-		return false, nil
+		return nil, false, nil
 	} else if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// SetNX stores the given value for the given key, but only if the key doesn't exist yet (or has expired).
+// The check and the write happen in the same transaction,
+// which is retried when it conflicts with a concurrent transaction.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	return true, s.codec.Unmarshal(data, v)
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		created = false
+		err = s.db.Update(func(txn *badger.Txn) error {
+			_, err := txn.Get([]byte(k))
+			if err == nil {
+				return nil
+			} else if err != badger.ErrKeyNotFound {
+				return err
+			}
+			created = true
+			return txn.Set([]byte(k), data)
+		})
+		if err != badger.ErrConflict {
+			break
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (s Store) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	err = s.db.View(func(txn *badger.Txn) error {
+		// The item's value is only read when it's accessed
+		_, err := txn.Get([]byte(k))
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // Delete deletes the stored value for the given key.
@@ -86,6 +194,262 @@ func (s Store) Delete(k string) error {
 	})
 }
 
+// SetMulti stores all given key-value pairs in a single transaction.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The transaction must not exceed BadgerDB's size limits, otherwise badger.ErrTxnTooBig is returned
+// and nothing is stored.
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, s.codec.Marshal)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		for k, v := range data {
+			if err := txn.Set([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMulti retrieves the stored values for the given keys in a single transaction.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be "".
+func (s Store) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(keys))
+	err := s.db.View(func(txn *badger.Txn) error {
+		for _, k := range keys {
+			item, err := txn.Get([]byte(k))
+			if err == badger.ErrKeyNotFound {
+				continue
+			} else if err != nil {
+				return err
+			}
+			v := newValue()
+			err = item.Value(func(data []byte) error {
+				return s.codec.Unmarshal(data, v)
+			})
+			if err != nil {
+				return err
+			}
+			result[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys in a single transaction.
+// The transaction must not exceed BadgerDB's size limits, otherwise badger.ErrTxnTooBig is returned
+// and nothing is deleted.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		for _, k := range keys {
+			if err := txn.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Keys calls fn for each key in the store, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The values aren't read, so iterating is cheap even for large values.
+func (s Store) Keys(fn func(k string) bool) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(keyIteratorOptions())
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if !fn(string(it.Item().Key())) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Iter returns an iterator over the keys in the store, in byte-sorted order.
+// The iterator works on a read-only transaction, so it doesn't see changes
+// that are made after its creation.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	txn := s.db.NewTransaction(false)
+	return &keyIterator{
+		txn: txn,
+		it:  txn.NewIterator(keyIteratorOptions()),
+	}, nil
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+func (s Store) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.Prefix = []byte(prefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			decode := func(v any) error {
+				// The value is only valid within the transaction, which is the case while fn runs.
+				return item.Value(func(data []byte) error {
+					return s.codec.Unmarshal(data, v)
+				})
+			}
+			if !fn(string(item.Key()), decode) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+func keyIteratorOptions() badger.IteratorOptions {
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	return opts
+}
+
+// keyIterator is a gokv.KeyIterator based on a BadgerDB Iterator.
+type keyIterator struct {
+	txn     *badger.Txn
+	it      *badger.Iterator
+	started bool
+}
+
+func (i *keyIterator) Next() bool {
+	if i.it == nil {
+		return false
+	}
+	if !i.started {
+		i.it.Rewind()
+		i.started = true
+	} else {
+		i.it.Next()
+	}
+	return i.it.Valid()
+}
+
+func (i *keyIterator) Key() string {
+	if i.it == nil || !i.it.Valid() {
+		return ""
+	}
+	return string(i.it.Item().Key())
+}
+
+func (i *keyIterator) Err() error {
+	return nil
+}
+
+func (i *keyIterator) Close() error {
+	if i.it == nil {
+		return nil
+	}
+	i.it.Close()
+	i.it = nil
+	i.txn.Discard()
+	return nil
+}
+
+// Update executes the given function within a read-write BadgerDB transaction.
+// If the function returns nil, the transaction is committed, otherwise it's discarded.
+// BadgerDB uses optimistic concurrency control, so if the transaction conflicts with a concurrent one,
+// badger.ErrConflict is returned (wrapped with gokv.ErrConflict) and the function can be executed again.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	err := s.db.Update(func(txn *badger.Txn) error {
+		return fn(storeTx{
+			txn:   txn,
+			codec: s.codec,
+		})
+	})
+	if errors.Is(err, badger.ErrConflict) {
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return err
+}
+
+// View executes the given function within a read-only BadgerDB transaction.
+// The transaction sees a consistent snapshot of the DB.
+func (s Store) View(fn func(tx gokv.Tx) error) error {
+	return s.db.View(func(txn *badger.Txn) error {
+		return fn(storeTx{
+			txn:      txn,
+			readOnly: true,
+			codec:    s.codec,
+		})
+	})
+}
+
+// storeTx is a gokv.Tx for a BadgerDB transaction.
+type storeTx struct {
+	txn      *badger.Txn
+	readOnly bool
+	codec    encoding.Codec
+}
+
+func (t storeTx) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return gokv.ErrReadOnlyTx
+	}
+
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.txn.Set([]byte(k), data)
+}
+
+func (t storeTx) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	item, err := t.txn.Get([]byte(k))
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	err = item.Value(func(data []byte) error {
+		return t.codec.Unmarshal(data, v)
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (t storeTx) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return gokv.ErrReadOnlyTx
+	}
+
+	return t.txn.Delete([]byte(k))
+}
+
 // Close closes the store.
 // It must be called to make sure that all pending updates make their way to disk.
 func (s Store) Close() error {
@@ -138,3 +502,25 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("badgerdb", open)
+}
+
+// open creates a store from a URL like "badgerdb:///tmp/BadgerDB?codec=gob",
+// see util.URLPath() for relative paths.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Dir:   util.URLPath(u),
+		Codec: q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -1,4 +1,7 @@
 /*
 Package badgerdb contains an implementation of the `gokv.Store` interface for BadgerDB.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"badgerdb:///tmp/BadgerDB?codec=gob" (or "badgerdb://BadgerDB" for a relative path).
 */
 package badgerdb
@@ -0,0 +1,74 @@
+package gokv
+
+// BatchStore is a Store that can work with multiple key-value pairs in one call.
+// Implementations use the native bulk operations of the backend where available,
+// so that working with thousands of key-value pairs doesn't need a round trip per key.
+//
+// Whether a batch is applied atomically depends on the implementation.
+// When an error is returned, some of the key-value pairs may have been written or deleted.
+type BatchStore interface {
+	Store
+	// SetMulti stores all given key-value pairs.
+	// No key must be "" and no value must be nil.
+	SetMulti(kvs map[string]any) error
+	// GetMulti retrieves the values for the given keys.
+	// For each key that's found, newValue is called to create a pointer
+	// to an object of the correct type (like the one you'd pass to Get()),
+	// the stored value is unmarshalled into it and it's put into the returned map.
+	// Keys that aren't found are missing from the returned map.
+	// No key must be "".
+	GetMulti(keys []string, newValue func() any) (map[string]any, error)
+	// DeleteMulti deletes the stored values for the given keys.
+	// Deleting non-existing key-value pairs does NOT lead to an error.
+	// No key must be "".
+	DeleteMulti(keys []string) error
+}
+
+// AsBatchStore returns the given store as BatchStore.
+// If the store already implements BatchStore, it's returned as is.
+// Otherwise it's wrapped in an adapter that loops over the key-value pairs
+// and calls the store's regular methods for each of them.
+func AsBatchStore(store Store) BatchStore {
+	if bs, ok := store.(BatchStore); ok {
+		return bs
+	}
+	return batchAdapter{store}
+}
+
+// batchAdapter makes a plain Store satisfy the BatchStore interface.
+type batchAdapter struct {
+	Store
+}
+
+func (a batchAdapter) SetMulti(kvs map[string]any) error {
+	for k, v := range kvs {
+		if err := a.Set(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a batchAdapter) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	result := make(map[string]any, len(keys))
+	for _, k := range keys {
+		v := newValue()
+		found, err := a.Get(k, v)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+func (a batchAdapter) DeleteMulti(keys []string) error {
+	for _, k := range keys {
+		if err := a.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package bbolt
 
 import (
+	"bytes"
+	"net/url"
+
 	bolt "go.etcd.io/bbolt"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -28,7 +32,17 @@ func (s Store) Set(k string, v any) error {
 		return err
 	}
 
-	err = s.db.Update(func(tx *bolt.Tx) error {
+	return s.SetRaw(k, data)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(s.bucketName))
 		return b.Put([]byte(k), data)
 	})
@@ -49,8 +63,38 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 		return false, err
 	}
 
+	data, err := s.get(k)
+	if err != nil {
+		return false, nil
+	}
+
+	// If no value was found return false
+	if data == nil {
+		return false, nil
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err = s.get(k)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, data != nil, nil
+}
+
+// get returns a copy of the stored data for the given key, or nil if no value is found.
+func (s Store) get(k string) ([]byte, error) {
 	var data []byte
-	err = s.db.View(func(tx *bolt.Tx) error {
+	err := s.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(s.bucketName))
 		txData := b.Get([]byte(k))
 		// txData is only valid during the transaction.
@@ -64,16 +108,51 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 		}
 		return nil
 	})
+	return data, err
+}
+
+// SetNX stores the given value for the given key, but only if the key doesn't exist yet.
+// The check and the write happen in the same transaction.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
 	if err != nil {
-		return false, nil
+		return false, err
 	}
 
-	// If no value was found return false
-	if data == nil {
-		return false, nil
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.bucketName))
+		if b.Get([]byte(k)) != nil {
+			return nil
+		}
+		created = true
+		return b.Put([]byte(k), data)
+	})
+	if err != nil {
+		return false, err
 	}
+	return created, nil
+}
 
-	return true, s.codec.Unmarshal(data, v)
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (s Store) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.bucketName))
+		// The value is only valid during the transaction, but we don't need to copy it
+		found = b.Get([]byte(k)) != nil
+		return nil
+	})
+	return found, err
 }
 
 // Delete deletes the stored value for the given key.
@@ -90,6 +169,234 @@ func (s Store) Delete(k string) error {
 	})
 }
 
+// SetMulti stores all given key-value pairs in a single transaction.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, s.codec.Marshal)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.bucketName))
+		for k, v := range data {
+			if err := b.Put([]byte(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMulti retrieves the stored values for the given keys in a single transaction.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be "".
+func (s Store) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(keys))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.bucketName))
+		for _, k := range keys {
+			// The data is only valid during the transaction, which is the case while unmarshalling.
+			data := b.Get([]byte(k))
+			if data == nil {
+				continue
+			}
+			v := newValue()
+			if err := s.codec.Unmarshal(data, v); err != nil {
+				return err
+			}
+			result[k] = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys in a single transaction.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(s.bucketName))
+		for _, k := range keys {
+			if err := b.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Keys calls fn for each key in the store, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The iteration happens within a read transaction, so fn must not write to the store,
+// otherwise it can deadlock.
+func (s Store) Keys(fn func(k string) bool) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(s.bucketName)).Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			if !fn(string(k)) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// Iter returns an iterator over the keys in the store, in byte-sorted order.
+// The iterator holds a read transaction open until it's closed,
+// so don't write to the store before closing it, otherwise it can deadlock.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	tx, err := s.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	return &keyIterator{
+		tx: tx,
+		c:  tx.Bucket([]byte(s.bucketName)).Cursor(),
+	}, nil
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The scan happens within a read transaction, so fn must not write to the store,
+// otherwise it can deadlock.
+func (s Store) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	prefixBytes := []byte(prefix)
+	return s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(s.bucketName)).Cursor()
+		for k, data := c.Seek(prefixBytes); k != nil && bytes.HasPrefix(k, prefixBytes); k, data = c.Next() {
+			// data is only valid during the transaction, which is the case while fn runs.
+			decode := func(v any) error {
+				return s.codec.Unmarshal(data, v)
+			}
+			if !fn(string(k), decode) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// keyIterator is a gokv.KeyIterator based on a bbolt Cursor.
+type keyIterator struct {
+	tx      *bolt.Tx
+	c       *bolt.Cursor
+	started bool
+	key     []byte
+}
+
+func (i *keyIterator) Next() bool {
+	if i.c == nil {
+		return false
+	}
+	if !i.started {
+		i.key, _ = i.c.First()
+		i.started = true
+	} else {
+		i.key, _ = i.c.Next()
+	}
+	return i.key != nil
+}
+
+func (i *keyIterator) Key() string {
+	return string(i.key)
+}
+
+func (i *keyIterator) Err() error {
+	return nil
+}
+
+func (i *keyIterator) Close() error {
+	if i.c == nil {
+		return nil
+	}
+	i.c = nil
+	i.key = nil
+	return i.tx.Rollback()
+}
+
+// Update executes the given function within a read-write bbolt transaction.
+// If the function returns nil, the transaction is committed, otherwise it's rolled back.
+// Only one read-write transaction is allowed at a time, so other writes are blocked in the meantime.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(storeTx{
+			b:     tx.Bucket([]byte(s.bucketName)),
+			codec: s.codec,
+		})
+	})
+}
+
+// View executes the given function within a read-only bbolt transaction.
+// The transaction sees a consistent snapshot of the DB.
+func (s Store) View(fn func(tx gokv.Tx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(storeTx{
+			b:     tx.Bucket([]byte(s.bucketName)),
+			codec: s.codec,
+		})
+	})
+}
+
+// storeTx is a gokv.Tx for a bbolt transaction.
+type storeTx struct {
+	b     *bolt.Bucket
+	codec encoding.Codec
+}
+
+func (t storeTx) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if !t.b.Tx().Writable() {
+		return gokv.ErrReadOnlyTx
+	}
+
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.b.Put([]byte(k), data)
+}
+
+func (t storeTx) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	// The data is only valid during the transaction, which is the case while unmarshalling.
+	data := t.b.Get([]byte(k))
+	if data == nil {
+		return false, nil
+	}
+	return true, t.codec.Unmarshal(data, v)
+}
+
+func (t storeTx) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	if !t.b.Tx().Writable() {
+		return gokv.ErrReadOnlyTx
+	}
+
+	return t.b.Delete([]byte(k))
+}
+
 // Close closes the store.
 // It must be called to make sure that all open transactions finish and to release all DB resources.
 func (s Store) Close() error {
@@ -161,3 +468,26 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("bbolt", open)
+}
+
+// open creates a store from a URL like "bbolt:///tmp/gokv.db?bucket=default&codec=gob",
+// see util.URLPath() for relative paths.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		BucketName: q.String("bucket"),
+		Path:       util.URLPath(u),
+		Codec:      q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -134,6 +134,76 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestIterable tests if the keys of the store can be iterated over.
+func TestIterable(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestIterable(store, t)
+}
+
+// TestPrefixScanner tests if the key-value pairs with a given prefix can be retrieved.
+func TestPrefixScanner(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestPrefixScanner(store, t)
+}
+
+// TestBatchStore tests if multiple key-value pairs can be set, retrieved and deleted at once.
+func TestBatchStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestBatchStore(store, t)
+}
+
+// TestCreateOnlyStore tests if values are only stored when the key doesn't exist yet.
+func TestCreateOnlyStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestCreateOnlyStore(store, t)
+}
+
+// TestTransactional tests if multiple operations can be executed in a transaction.
+func TestTransactional(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestTransactional(store, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestRawStore(store, t)
+}
+
+// TestExistenceChecker tests if the existence of keys is checked correctly.
+func TestExistenceChecker(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestExistenceChecker(store, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestTypedStore(store, t)
+}
+
+// TestOpen tests if the store can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	path := generateRandomTempDbPath(t)
+	defer func() { _ = os.RemoveAll(path) }()
+	test.TestOpen("bbolt://"+path+"?codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	store, path := createStore(t, encoding.JSON)
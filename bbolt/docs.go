@@ -1,4 +1,7 @@
 /*
 Package bbolt contains an implementation of the `gokv.Store` interface for bbolt (formerly known as Bolt / Bolt DB).
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"bbolt:///tmp/bbolt.db?bucket=default&codec=gob" (or "bbolt://bbolt.db" for a relative path).
 */
 package bbolt
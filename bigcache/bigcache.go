@@ -2,10 +2,12 @@ package bigcache
 
 import (
 	"context"
+	"net/url"
 	"time"
 
 	"github.com/allegro/bigcache/v3"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -32,6 +34,16 @@ func (s Store) Set(k string, v any) error {
 	return s.s.Set(k, data)
 }
 
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	return s.s.Set(k, data)
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -54,22 +66,50 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	return true, s.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err = s.s.Get(k)
+	if err != nil {
+		if err == bigcache.ErrEntryNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (s Store) Delete(k string) error {
+	_, err := s.DeleteExisting(k)
+	return err
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (s Store) DeleteExisting(k string) (existed bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return false, err
 	}
 
-	err := s.s.Delete(k)
+	err = s.s.Delete(k)
 	if err != nil {
 		if err == bigcache.ErrEntryNotFound {
-			return nil
+			return false, nil
 		}
-		return err
+		return false, err
 	}
-	return nil
+	return true, nil
 }
 
 // Close closes the store.
@@ -126,3 +166,25 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("bigcache", open)
+}
+
+// open creates a store from a URL like "bigcache://?hard_max_cache_size=256&eviction=10m&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		HardMaxCacheSize: q.Int("hard_max_cache_size"),
+		Eviction:         q.Duration("eviction"),
+		Codec:            q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -1,4 +1,7 @@
 /*
 Package bigcache contains an implementation of the `gokv.Store` interface for BigCache.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"bigcache://?hard_max_cache_size=256&eviction=10m&codec=gob".
 */
 package bigcache
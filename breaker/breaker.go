@@ -0,0 +1,386 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
+
+// State is the state of a circuit breaker.
+type State int
+
+const (
+	// StateClosed means that calls are passed to the wrapped store.
+	StateClosed State = iota
+	// StateOpen means that calls aren't passed to the wrapped store,
+	// but to the fallback store or rejected with ErrOpen.
+	StateOpen
+	// StateHalfOpen means that single trial calls are passed to the wrapped store,
+	// to find out if it has recovered. Other calls are handled like in StateOpen.
+	StateHalfOpen
+)
+
+// String returns the name of the state, like "closed".
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// ErrOpen is returned when a call isn't passed to the wrapped store because the circuit is open
+// and there's no fallback store.
+// It's classified as gokv.ErrUnavailable, so gokv.IsUnavailable() returns true for it.
+var ErrOpen = gokv.WrapError(gokv.ErrUnavailable, errors.New("the circuit breaker is open"))
+
+// Store is a gokv.Store that stops passing calls to the wrapped store when it fails repeatedly,
+// so that callers fail fast instead of waiting for timeouts of a backend that's down.
+//
+// It starts in StateClosed. After FailureThreshold consecutive failures it changes to StateOpen,
+// in which calls are passed to the fallback store, or fail with ErrOpen if there's none.
+// With the first call after the CoolDown it changes to StateHalfOpen, in which one call at a time
+// is passed to the wrapped store as a trial. After SuccessThreshold successful trials it changes
+// to StateClosed again, and after a failed trial back to StateOpen.
+//
+// Values that are written to the fallback store while the circuit is open aren't written to the wrapped store
+// later, so the fallback store is meant for degraded operation, like serving sessions from memory.
+//
+// It implements gokv.ContextStore. The context is passed on to the wrapped store if it implements gokv.ContextStore.
+// Calls that fail because their context is done don't count as failures.
+type Store struct {
+	store            gokv.ContextStore
+	fallback         gokv.ContextStore
+	failureThreshold int
+	successThreshold int
+	coolDown         time.Duration
+	isFailure        func(err error) bool
+	onStateChange    func(from, to State)
+	circuit          *circuit
+}
+
+// circuit holds the mutable state of the breaker.
+type circuit struct {
+	state State
+	// Consecutive failures in StateClosed
+	failures int
+	// Consecutive successful trials in StateHalfOpen
+	successes int
+	openedAt  time.Time
+	// Whether a trial call is in progress in StateHalfOpen
+	trial bool
+	lock  sync.Mutex
+}
+
+// Set stores the given value for the given key in the wrapped store,
+// or in the fallback store if the circuit is open.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	return s.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key in the wrapped store,
+// or in the fallback store if the circuit is open.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetContext(ctx context.Context, k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	return s.do(ctx, func(store gokv.ContextStore) error {
+		return store.SetContext(ctx, k, v)
+	})
+}
+
+// Get retrieves the stored value for the given key from the wrapped store,
+// or from the fallback store if the circuit is open.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	return s.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key from the wrapped store,
+// or from the fallback store if the circuit is open.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+	err = s.do(ctx, func(store gokv.ContextStore) error {
+		var err error
+		found, err = store.GetContext(ctx, k, v)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// Delete deletes the stored value for the given key from the wrapped store,
+// or from the fallback store if the circuit is open.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	return s.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key from the wrapped store,
+// or from the fallback store if the circuit is open.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	return s.do(ctx, func(store gokv.ContextStore) error {
+		return store.DeleteContext(ctx, k)
+	})
+}
+
+// Close closes the wrapped store and the fallback store, regardless of the state of the circuit.
+func (s Store) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext closes the wrapped store and the fallback store, regardless of the state of the circuit.
+func (s Store) CloseContext(ctx context.Context) error {
+	err := s.store.CloseContext(ctx)
+	if s.fallback != nil {
+		err = errors.Join(err, s.fallback.CloseContext(ctx))
+	}
+	return err
+}
+
+// State returns the current state of the circuit.
+// An open circuit changes to StateHalfOpen with the first call after the CoolDown.
+func (s Store) State() State {
+	s.circuit.lock.Lock()
+	defer s.circuit.lock.Unlock()
+	return s.circuit.state
+}
+
+// do calls fn with the wrapped store if the circuit allows it, and with the fallback store otherwise.
+func (s Store) do(ctx context.Context, fn func(store gokv.ContextStore) error) error {
+	trial, ok := s.allow()
+	if !ok {
+		if s.fallback == nil {
+			return ErrOpen
+		}
+		return fn(s.fallback)
+	}
+
+	err := fn(s.store)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The caller gave up, which says nothing about the wrapped store
+		s.release(trial)
+	case err != nil && s.isFailure(err):
+		s.recordFailure(trial)
+	default:
+		s.recordSuccess(trial)
+	}
+	return err
+}
+
+// allow returns whether a call may be passed to the wrapped store, and if it's a trial call.
+func (s Store) allow() (trial, ok bool) {
+	c := s.circuit
+	c.lock.Lock()
+	changed := false
+	defer func() {
+		c.lock.Unlock()
+		if changed {
+			s.stateChanged(StateOpen, StateHalfOpen)
+		}
+	}()
+
+	if c.state == StateOpen && time.Since(c.openedAt) >= s.coolDown {
+		changed = true
+		c.state = StateHalfOpen
+		c.successes = 0
+	}
+	switch c.state {
+	case StateClosed:
+		return false, true
+	case StateHalfOpen:
+		if c.trial {
+			return false, false
+		}
+		c.trial = true
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+func (s Store) recordFailure(trial bool) {
+	c := s.circuit
+	c.lock.Lock()
+	from := c.state
+	if trial {
+		c.trial = false
+	}
+	switch {
+	case c.state == StateClosed:
+		c.failures++
+		if c.failures < s.failureThreshold {
+			c.lock.Unlock()
+			return
+		}
+	case c.state == StateHalfOpen && trial:
+	default:
+		// The call was passed to the wrapped store before the circuit opened
+		c.lock.Unlock()
+		return
+	}
+	c.state = StateOpen
+	c.openedAt = time.Now()
+	c.failures = 0
+	c.lock.Unlock()
+	s.stateChanged(from, StateOpen)
+}
+
+func (s Store) recordSuccess(trial bool) {
+	c := s.circuit
+	c.lock.Lock()
+	if c.state == StateClosed {
+		c.failures = 0
+		c.lock.Unlock()
+		return
+	}
+	if !trial || c.state != StateHalfOpen {
+		c.lock.Unlock()
+		return
+	}
+	c.trial = false
+	c.successes++
+	if c.successes < s.successThreshold {
+		c.lock.Unlock()
+		return
+	}
+	c.state = StateClosed
+	c.successes = 0
+	c.lock.Unlock()
+	s.stateChanged(StateHalfOpen, StateClosed)
+}
+
+// release ends a trial call without an outcome.
+func (s Store) release(trial bool) {
+	if !trial {
+		return
+	}
+	s.circuit.lock.Lock()
+	defer s.circuit.lock.Unlock()
+	s.circuit.trial = false
+}
+
+func (s Store) stateChanged(from, to State) {
+	if s.onStateChange != nil {
+		s.onStateChange(from, to)
+	}
+}
+
+// IsFailure is the default classifier of the errors that count as failures of the wrapped store.
+// It returns true for errors that are classified as gokv.ErrTimeout or gokv.ErrUnavailable,
+// which the implementations use for network errors, timeouts and throttling,
+// and for network errors of stores that don't classify their errors (see util.ClassifyError()).
+// Other errors, for example of marshalling a value or about a missing table,
+// mean that the backend is reachable, so they don't count as failures.
+func IsFailure(err error) bool {
+	err = util.ClassifyError(err)
+	return gokv.IsTimeout(err) || gokv.IsUnavailable(err)
+}
+
+// Options are the options for the breaker store.
+type Options struct {
+	// The store whose calls are guarded by the circuit breaker, for example a redis.Client.
+	// Required.
+	Store gokv.Store
+	// Number of consecutive failures after which the circuit opens.
+	// Optional (5 by default).
+	FailureThreshold int
+	// Number of consecutive successful trial calls in StateHalfOpen after which the circuit closes.
+	// Optional (1 by default).
+	SuccessThreshold int
+	// Duration for which the circuit stays open before trial calls are passed to the wrapped store.
+	// Optional (10 * time.Second by default).
+	CoolDown time.Duration
+	// Store that's used instead of the wrapped store while the circuit is open, for example a gomap.Store.
+	// Optional (nil by default, which means calls fail with ErrOpen while the circuit is open).
+	Fallback gokv.Store
+	// Function that decides if an error of the wrapped store counts as failure.
+	// Optional (IsFailure by default).
+	IsFailure func(err error) bool
+	// Function that's called when the state of the circuit changes, for example for logging or metrics.
+	// It's called synchronously by the call that changed the state, so it shouldn't block.
+	// Optional (nil by default).
+	OnStateChange func(from, to State)
+}
+
+// DefaultOptions is an Options object with default values.
+// FailureThreshold: 5, SuccessThreshold: 1, CoolDown: 10 * time.Second, Fallback: nil, IsFailure: IsFailure
+var DefaultOptions = Options{
+	FailureThreshold: 5,
+	SuccessThreshold: 1,
+	CoolDown:         10 * time.Second,
+	IsFailure:        IsFailure,
+	// No need to set Store because it's required and doesn't have a default,
+	// and no need to set Fallback or OnStateChange because their zero values are fine.
+}
+
+// NewStore creates a new breaker store.
+//
+// The store takes ownership of the wrapped store and the fallback store,
+// so you should call the Close() method on the breaker store instead of on them when you're done working with it.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+	if options.FailureThreshold < 0 || options.SuccessThreshold < 0 || options.CoolDown < 0 {
+		return result, errors.New("the FailureThreshold, SuccessThreshold and CoolDown in the options must not be negative")
+	}
+
+	// Set default values
+	if options.FailureThreshold == 0 {
+		options.FailureThreshold = DefaultOptions.FailureThreshold
+	}
+	if options.SuccessThreshold == 0 {
+		options.SuccessThreshold = DefaultOptions.SuccessThreshold
+	}
+	if options.CoolDown == 0 {
+		options.CoolDown = DefaultOptions.CoolDown
+	}
+	if options.IsFailure == nil {
+		options.IsFailure = DefaultOptions.IsFailure
+	}
+
+	result.store = gokv.AsContextStore(options.Store)
+	if options.Fallback != nil {
+		result.fallback = gokv.AsContextStore(options.Fallback)
+	}
+	result.failureThreshold = options.FailureThreshold
+	result.successThreshold = options.SuccessThreshold
+	result.coolDown = options.CoolDown
+	result.isFailure = options.IsFailure
+	result.onStateChange = options.OnStateChange
+	result.circuit = new(circuit)
+
+	return result, nil
+}
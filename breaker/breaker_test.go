@@ -0,0 +1,373 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/breaker"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.DefaultOptions))
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestStateTransitions tests if the circuit opens after the failure threshold, changes to half-open after the cool-down,
+// opens again after a failed trial and closes after a successful one.
+func TestStateTransitions(t *testing.T) {
+	wrapped := &downStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	recorder := &stateRecorder{}
+	store := createStore(t, breaker.Options{
+		FailureThreshold: 3,
+		CoolDown:         50 * time.Millisecond,
+		OnStateChange:    recorder.record,
+	}, wrapped)
+
+	// Successes reset the failure count
+	wrapped.down.Store(true)
+	checkErrors(t, store, 2, errDown)
+	wrapped.down.Store(false)
+	checkErrors(t, store, 1, nil)
+	wrapped.down.Store(true)
+	checkErrors(t, store, 2, errDown)
+	checkState(t, store, breaker.StateClosed)
+	checkErrors(t, store, 1, errDown)
+	checkState(t, store, breaker.StateOpen)
+
+	// The wrapped store isn't called while the circuit is open
+	wrapped.calls.Store(0)
+	checkErrors(t, store, 3, breaker.ErrOpen)
+	if wrapped.calls.Load() != 0 {
+		t.Errorf("Expected no calls of the wrapped store, but was %v", wrapped.calls.Load())
+	}
+
+	// A failed trial opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	checkErrors(t, store, 1, errDown)
+	checkState(t, store, breaker.StateOpen)
+	checkErrors(t, store, 1, breaker.ErrOpen)
+
+	// A successful trial closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	wrapped.down.Store(false)
+	checkErrors(t, store, 1, nil)
+	checkState(t, store, breaker.StateClosed)
+
+	recorder.check(t, []string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	})
+}
+
+// TestSuccessThreshold tests if the circuit only closes after the configured number of successful trials.
+func TestSuccessThreshold(t *testing.T) {
+	wrapped := &downStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	store := createStore(t, breaker.Options{
+		FailureThreshold: 1,
+		SuccessThreshold: 3,
+		CoolDown:         50 * time.Millisecond,
+	}, wrapped)
+
+	wrapped.down.Store(true)
+	checkErrors(t, store, 1, errDown)
+	checkState(t, store, breaker.StateOpen)
+
+	time.Sleep(60 * time.Millisecond)
+	wrapped.down.Store(false)
+	checkErrors(t, store, 2, nil)
+	checkState(t, store, breaker.StateHalfOpen)
+	checkErrors(t, store, 1, nil)
+	checkState(t, store, breaker.StateClosed)
+}
+
+// TestSingleTrial tests if only one trial call at a time is passed to the wrapped store in the half-open state.
+func TestSingleTrial(t *testing.T) {
+	wrapped := &downStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	store := createStore(t, breaker.Options{FailureThreshold: 1, CoolDown: 10 * time.Millisecond}, wrapped)
+
+	wrapped.down.Store(true)
+	checkErrors(t, store, 1, errDown)
+	time.Sleep(20 * time.Millisecond)
+	wrapped.down.Store(false)
+
+	// The trial call blocks until the other call was rejected
+	wrapped.calls.Store(0)
+	wrapped.block = make(chan struct{})
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+		if err := store.Delete("foo"); err != nil {
+			t.Error(err)
+		}
+	}()
+	for wrapped.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	checkState(t, store, breaker.StateHalfOpen)
+	err := store.Delete("foo")
+	if !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("Expected ErrOpen, but was: %v", err)
+	}
+	close(wrapped.block)
+	waitGroup.Wait()
+	checkState(t, store, breaker.StateClosed)
+}
+
+// TestFallback tests if the fallback store is used while the circuit is open.
+func TestFallback(t *testing.T) {
+	wrapped := &downStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	fallback := gomap.NewStore(gomap.DefaultOptions)
+	store := createStore(t, breaker.Options{FailureThreshold: 1, CoolDown: time.Minute, Fallback: fallback}, wrapped)
+
+	wrapped.down.Store(true)
+	checkErrors(t, store, 1, errDown)
+
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := ""
+	found, err := store.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual != "bar" {
+		t.Errorf("Expected %q, but was %q (found: %v)", "bar", actual, found)
+	}
+	found, err = fallback.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("Expected the value to be stored in the fallback store")
+	}
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err = fallback.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("Expected the value to be deleted from the fallback store")
+	}
+}
+
+// TestNoFailures tests if errors that don't mean that the wrapped store is down don't open the circuit.
+func TestNoFailures(t *testing.T) {
+	wrapped := &downStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	store := createStore(t, breaker.Options{FailureThreshold: 1}, wrapped)
+
+	// Errors of marshalling
+	err := store.Set("foo", make(chan int))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	checkState(t, store, breaker.StateClosed)
+
+	// Done contexts
+	wrapped.down.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.GetContext(ctx, "foo", new(string))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context error, but was: %v", err)
+	}
+	checkState(t, store, breaker.StateClosed)
+
+	// Errors that the classifier doesn't count
+	store = createStore(t, breaker.Options{
+		FailureThreshold: 1,
+		IsFailure:        func(err error) bool { return false },
+	}, wrapped)
+	checkErrors(t, store, 3, errDown)
+	checkState(t, store, breaker.StateClosed)
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// ErrOpen is classified as unavailable
+	if !gokv.IsUnavailable(breaker.ErrOpen) {
+		t.Error("Expected ErrOpen to be classified as gokv.ErrUnavailable")
+	}
+
+	// Test invalid options
+	invalidOptions := []breaker.Options{
+		{},
+		{Store: gomap.NewStore(gomap.DefaultOptions), FailureThreshold: -1},
+		{Store: gomap.NewStore(gomap.DefaultOptions), CoolDown: -time.Second},
+	}
+	for _, options := range invalidOptions {
+		_, err = breaker.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, breaker.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store := createStore(t, breaker.Options{Fallback: gomap.NewStore(gomap.DefaultOptions)}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func createStore(t *testing.T, options breaker.Options, wrapped gokv.Store) breaker.Store {
+	options.Store = wrapped
+	store, err := breaker.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// checkErrors calls Delete() n times and checks if each call returns the expected error.
+func checkErrors(t *testing.T, store breaker.Store, n int, expected error) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		err := store.Delete("foo")
+		if !errors.Is(err, expected) || (expected == nil && err != nil) {
+			t.Errorf("Expected the error %v, but was: %v", expected, err)
+		}
+	}
+}
+
+func checkState(t *testing.T, store breaker.Store, expected breaker.State) {
+	t.Helper()
+	if actual := store.State(); actual != expected {
+		t.Errorf("Expected the state %v, but was %v", expected, actual)
+	}
+}
+
+// stateRecorder records the state changes.
+type stateRecorder struct {
+	changes []string
+	lock    sync.Mutex
+}
+
+func (r *stateRecorder) record(from, to breaker.State) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.changes = append(r.changes, from.String()+" -> "+to.String())
+}
+
+func (r *stateRecorder) check(t *testing.T, expected []string) {
+	t.Helper()
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.changes) != len(expected) {
+		t.Fatalf("Expected the state changes %v, but was %v", expected, r.changes)
+	}
+	for i := range expected {
+		if r.changes[i] != expected[i] {
+			t.Errorf("Expected the state changes %v, but was %v", expected, r.changes)
+			break
+		}
+	}
+}
+
+var errDown = gokv.WrapError(gokv.ErrUnavailable, errors.New("connection refused"))
+
+// downStore is a gokv.Store whose Get() and Delete() fail while it's down.
+// It counts the calls of Delete(), which blocks until block is closed, if it's not nil.
+type downStore struct {
+	gokv.Store
+	down  atomic.Bool
+	calls atomic.Int64
+	block chan struct{}
+}
+
+func (s *downStore) Get(k string, v any) (found bool, err error) {
+	if s.down.Load() {
+		return false, errDown
+	}
+	return s.Store.Get(k, v)
+}
+
+func (s *downStore) Delete(k string) error {
+	s.calls.Add(1)
+	if s.block != nil {
+		<-s.block
+	}
+	if s.down.Load() {
+		return errDown
+	}
+	return s.Store.Delete(k)
+}
@@ -0,0 +1,12 @@
+/*
+Package breaker contains an implementation of the `gokv.Store` interface that guards any other store
+with a circuit breaker.
+
+When a remote store like Redis or DynamoDB is down, every call waits for a timeout before it fails.
+After a configurable number of consecutive failures the circuit breaker opens and calls fail fast with ErrOpen,
+or are served by an optional fallback store (like a `gomap.Store`) for degraded operation.
+After a cool-down single trial calls are passed to the wrapped store again, and the circuit closes when they succeed.
+Which errors count as failures is decided by a pluggable classifier. The default one (IsFailure) counts the errors
+that the implementations classify as `gokv.ErrTimeout` or `gokv.ErrUnavailable` and network errors.
+*/
+package breaker
@@ -16,6 +16,10 @@ cat "$PSScriptRoot/implementations" | foreach {
     cd "$PSScriptRoot/../$_"; go build -v; cd $workingDir
 }
 
+# Tools
+echo "building cmd/gokv"
+cd "$PSScriptRoot/../cmd/gokv"; go build -v; cd $workingDir
+
 # Examples
 echo "building examples"
 cd "$PSScriptRoot/../examples/redis"; go build -v; cd $workingDir
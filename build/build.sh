@@ -22,6 +22,10 @@ cat "$SCRIPT_DIR"/implementations | while read -r MODULE_NAME; do
     (cd "$SCRIPT_DIR"/../"$MODULE_NAME" && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
 done
 
+# Tools
+echo "building cmd/gokv"
+(cd "$SCRIPT_DIR"/../cmd/gokv && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
+
 # Examples
 echo "building examples"
 (cd "$SCRIPT_DIR"/../examples/redis && go build -v) || (cd "$WORKING_DIR" && echo " failed" && exit 1)
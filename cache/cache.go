@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
+
+// WritePolicy defines how values are written to the cache (L1) when they're written to the backing store (L2).
+type WritePolicy int
+
+const (
+	// WriteThrough writes values to L2 and then to L1, so that they can be read from L1 afterwards.
+	WriteThrough WritePolicy = iota
+	// WriteAround writes values to L2 and deletes them from L1,
+	// so that only values that are read are cached.
+	WriteAround
+)
+
+// Number of locks that the keys are distributed across.
+const lockCount = 256
+
+// Store is a gokv.Store that caches the values of a slow store (L2) in a fast store (L1).
+//
+// Values are read from L1 and, if they're not found there, from L2, populating L1 with them (read-through).
+// Set() and Delete() write to L2 and then update or invalidate the key in L1.
+// Reading a key from L2 and populating L1 is mutually exclusive with writing the same key,
+// so that L1 isn't populated with an outdated value by a concurrent Get().
+// Changes that are made to L2 directly (or by other processes) are only visible
+// after the key was evicted from L1 or expired (see L1TTL).
+type Store struct {
+	l1           gokv.Store
+	l2           gokv.Store
+	writePolicy  WritePolicy
+	l1TTL        time.Duration
+	negativeTTL  time.Duration
+	errorHandler func(err error)
+	locks        *[lockCount]sync.RWMutex
+	// Keys that weren't found in L2, with the time when they expire from the negative cache
+	misses     map[string]time.Time
+	missesLock *sync.Mutex
+	janitor    *util.Janitor
+	stats      *stats
+}
+
+type stats struct {
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	negativeHits atomic.Uint64
+}
+
+// Stats are the counters of a cache store.
+type Stats struct {
+	// Number of Get() calls for which the key was found in L1.
+	Hits uint64
+	// Number of Get() calls for which the key wasn't found in L1 and L2 was read.
+	Misses uint64
+	// Number of Get() calls for which the key was in the negative cache, so neither L1 nor L2 were read.
+	NegativeHits uint64
+}
+
+// Set stores the given value for the given key in L2 and, depending on the WritePolicy, in L1.
+// If writing to L1 fails after writing to L2, the key is deleted from L1, so it doesn't contain an outdated value,
+// and the error is returned.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	lock := s.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := s.l2.Set(k, v); err != nil {
+		return err
+	}
+	s.forgetMiss(k)
+	if s.writePolicy == WriteAround {
+		return s.l1.Delete(k)
+	}
+	if err := s.setL1(k, v); err != nil {
+		if deleteErr := s.l1.Delete(k); deleteErr != nil {
+			return errors.Join(err, deleteErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Get retrieves the stored value for the given key, from L1 or, if it's not found there, from L2.
+// A value that's read from L2 is stored in L1. Errors of storing it are passed to the ErrorHandler,
+// because the value itself was retrieved successfully.
+// With a NegativeTTL, a key that wasn't found in L2 isn't looked up again until the TTL has passed.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	if s.isMiss(k) {
+		s.stats.negativeHits.Add(1)
+		return false, nil
+	}
+	found, err = s.l1.Get(k, v)
+	if err != nil {
+		return false, err
+	}
+	if found {
+		s.stats.hits.Add(1)
+		return true, nil
+	}
+	s.stats.misses.Add(1)
+
+	lock := s.lock(k)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	found, err = s.l2.Get(k, v)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		s.rememberMiss(k)
+		return false, nil
+	}
+	if err := s.setL1(k, v); err != nil && s.errorHandler != nil {
+		s.errorHandler(fmt.Errorf("populating L1 with key %q failed: %w", k, err))
+	}
+	return true, nil
+}
+
+// Delete deletes the stored value for the given key from L2 and L1.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	lock := s.lock(k)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := s.l2.Delete(k); err != nil {
+		return err
+	}
+	return s.l1.Delete(k)
+}
+
+// Stats returns the current values of the hit and miss counters.
+func (s Store) Stats() Stats {
+	return Stats{
+		Hits:         s.stats.hits.Load(),
+		Misses:       s.stats.misses.Load(),
+		NegativeHits: s.stats.negativeHits.Load(),
+	}
+}
+
+// Close closes L1 and L2.
+func (s Store) Close() error {
+	s.janitor.Stop()
+	return errors.Join(s.l1.Close(), s.l2.Close())
+}
+
+func (s Store) lock(k string) *sync.RWMutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(k))
+	return &s.locks[h.Sum32()%lockCount]
+}
+
+// setL1 stores the value in L1, with the L1TTL if L1 supports it.
+func (s Store) setL1(k string, v any) error {
+	if s.l1TTL > 0 {
+		if expiringStore, ok := s.l1.(gokv.ExpiringStore); ok {
+			return expiringStore.SetWithTTL(k, v, s.l1TTL)
+		}
+	}
+	return s.l1.Set(k, v)
+}
+
+func (s Store) isMiss(k string) bool {
+	if s.negativeTTL <= 0 {
+		return false
+	}
+	s.missesLock.Lock()
+	defer s.missesLock.Unlock()
+	expiry, ok := s.misses[k]
+	if !ok {
+		return false
+	}
+	if time.Now().After(expiry) {
+		delete(s.misses, k)
+		return false
+	}
+	return true
+}
+
+func (s Store) rememberMiss(k string) {
+	if s.negativeTTL <= 0 {
+		return
+	}
+	s.janitor.Start()
+	s.missesLock.Lock()
+	defer s.missesLock.Unlock()
+	s.misses[k] = time.Now().Add(s.negativeTTL)
+}
+
+func (s Store) forgetMiss(k string) {
+	if s.negativeTTL <= 0 {
+		return
+	}
+	s.missesLock.Lock()
+	defer s.missesLock.Unlock()
+	delete(s.misses, k)
+}
+
+// deleteExpiredMisses is called by the janitor.
+func (s Store) deleteExpiredMisses() {
+	now := time.Now()
+	s.missesLock.Lock()
+	defer s.missesLock.Unlock()
+	for k, expiry := range s.misses {
+		if now.After(expiry) {
+			delete(s.misses, k)
+		}
+	}
+}
+
+// Options are the options for the cache store.
+type Options struct {
+	// The fast store that caches the values, for example a freecache.Store.
+	// Required.
+	L1 gokv.Store
+	// The slow store that's cached, for example a dynamodb.Client.
+	// Required.
+	L2 gokv.Store
+	// How values are written to L1 when they're written to L2.
+	// Optional (WriteThrough by default).
+	WritePolicy WritePolicy
+	// Lifetime of the values in L1, if L1 implements gokv.ExpiringStore.
+	// This limits how long changes that are made to L2 directly are invisible.
+	// Optional (0 by default, which means the values don't expire).
+	L1TTL time.Duration
+	// Lifetime of the entries for keys that weren't found in L2 (negative caching),
+	// during which Get() returns (false, nil) without reading L1 or L2.
+	// Set() removes the key from the negative cache.
+	// Optional (0 by default, which means misses aren't cached).
+	NegativeTTL time.Duration
+	// Function that's called with the errors of populating L1 with the values that were read from L2,
+	// which aren't returned because the value was retrieved successfully.
+	// Optional (the errors are discarded by default).
+	ErrorHandler func(err error)
+}
+
+// DefaultOptions is an Options object with default values.
+// WritePolicy: WriteThrough, L1TTL: 0, NegativeTTL: 0
+var DefaultOptions = Options{
+	WritePolicy: WriteThrough,
+	// No need to set L1, L2, L1TTL, NegativeTTL or ErrorHandler because their zero values are fine.
+}
+
+// NewStore creates a new cache store.
+//
+// The store takes ownership of L1 and L2, so you should call the Close() method
+// on the cache store instead of on L1 and L2 when you're done working with it.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.L1 == nil || options.L2 == nil {
+		return result, errors.New("the L1 and L2 stores in the options must not be nil")
+	}
+	if options.WritePolicy != WriteThrough && options.WritePolicy != WriteAround {
+		return result, fmt.Errorf("invalid WritePolicy %d", options.WritePolicy)
+	}
+
+	result = Store{
+		l1:           options.L1,
+		l2:           options.L2,
+		writePolicy:  options.WritePolicy,
+		l1TTL:        options.L1TTL,
+		negativeTTL:  options.NegativeTTL,
+		errorHandler: options.ErrorHandler,
+		locks:        new([lockCount]sync.RWMutex),
+		misses:       make(map[string]time.Time),
+		missesLock:   new(sync.Mutex),
+		stats:        new(stats),
+	}
+	// The janitor is only started when the first miss is cached
+	interval := options.NegativeTTL
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	result.janitor = util.NewJanitor(interval, result.deleteExpiredMisses)
+
+	return result, nil
+}
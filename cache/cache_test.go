@@ -0,0 +1,329 @@
+package cache_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/cache"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/syncmap"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with the different write policies
+	t.Run("WriteThrough", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{WritePolicy: cache.WriteThrough}, encoding.JSON)
+		test.TestStore(store, t)
+	})
+	t.Run("WriteAround", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{WritePolicy: cache.WriteAround}, encoding.JSON)
+		test.TestStore(store, t)
+	})
+	t.Run("NegativeTTL", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{NegativeTTL: time.Minute}, encoding.Gob)
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{}, encoding.JSON)
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{}, encoding.Gob)
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	// Test with the different write policies
+	t.Run("WriteThrough", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{WritePolicy: cache.WriteThrough}, encoding.JSON)
+		test.TestConcurrentInteractions(t, 1000, store)
+	})
+	t.Run("WriteAround", func(t *testing.T) {
+		store, _, _ := createStore(t, cache.Options{WritePolicy: cache.WriteAround}, encoding.JSON)
+		test.TestConcurrentInteractions(t, 1000, store)
+	})
+}
+
+// TestConcurrentPopulation tests if L1 never contains an outdated value
+// when keys are read and written concurrently.
+func TestConcurrentPopulation(t *testing.T) {
+	store, l1, l2 := createStore(t, cache.Options{WritePolicy: cache.WriteAround}, encoding.JSON)
+
+	waitGroup := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		waitGroup.Add(2)
+		go func(i int) {
+			defer waitGroup.Done()
+			if err := store.Set("foo", i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func() {
+			defer waitGroup.Done()
+			if _, err := store.Get("foo", new(int)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	expected := 0
+	if _, err := l2.Get("foo", &expected); err != nil {
+		t.Fatal(err)
+	}
+	actual := 0
+	found, err := l1.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found && actual != expected {
+		t.Errorf("Expected L1 to contain %v or nothing, but was %v", expected, actual)
+	}
+}
+
+// TestReadThrough tests if values that are only in L2 are read from there and stored in L1.
+func TestReadThrough(t *testing.T) {
+	store, l1, l2 := createStore(t, cache.Options{}, encoding.JSON)
+
+	err := l2.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+	checkValue(t, l1, "foo", "bar")
+	checkValue(t, store, "foo", "bar")
+	checkStats(t, store, cache.Stats{Hits: 1, Misses: 1})
+
+	// Changes of L2 aren't visible while the value is in L1
+	err = l2.Set("foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+
+	// Delete invalidates the key in L1
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, l1, "foo", "")
+	checkValue(t, l2, "foo", "")
+	checkValue(t, store, "foo", "")
+	checkStats(t, store, cache.Stats{Hits: 2, Misses: 2})
+}
+
+// TestWritePolicy tests if Set() writes to L1 according to the WritePolicy.
+func TestWritePolicy(t *testing.T) {
+	t.Run("WriteThrough", func(t *testing.T) {
+		store, l1, l2 := createStore(t, cache.Options{WritePolicy: cache.WriteThrough}, encoding.JSON)
+		err := store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, l1, "foo", "bar")
+		checkValue(t, l2, "foo", "bar")
+	})
+
+	t.Run("WriteAround", func(t *testing.T) {
+		store, l1, l2 := createStore(t, cache.Options{WritePolicy: cache.WriteAround}, encoding.JSON)
+		err := l1.Set("foo", "old")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkValue(t, l1, "foo", "")
+		checkValue(t, l2, "foo", "bar")
+		checkValue(t, store, "foo", "bar")
+		checkValue(t, l1, "foo", "bar")
+	})
+}
+
+// TestNegativeCaching tests if keys that weren't found in L2 aren't looked up again until the NegativeTTL has passed.
+func TestNegativeCaching(t *testing.T) {
+	store, _, l2 := createStore(t, cache.Options{NegativeTTL: 100 * time.Millisecond}, encoding.JSON)
+
+	checkValue(t, store, "foo", "")
+	err := l2.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The miss is cached
+	checkValue(t, store, "foo", "")
+	checkStats(t, store, cache.Stats{Misses: 1, NegativeHits: 1})
+	// Until the TTL has passed
+	time.Sleep(150 * time.Millisecond)
+	checkValue(t, store, "foo", "bar")
+	checkStats(t, store, cache.Stats{Misses: 2, NegativeHits: 1})
+
+	// Set removes the key from the negative cache
+	checkValue(t, store, "bar", "")
+	err = store.Set("bar", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "bar", "baz")
+}
+
+// TestL1TTL tests if values are stored in L1 with the L1TTL.
+func TestL1TTL(t *testing.T) {
+	store, l1, l2 := createStore(t, cache.Options{L1TTL: 100 * time.Millisecond}, encoding.JSON)
+
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l2.Set("foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+	time.Sleep(150 * time.Millisecond)
+	checkValue(t, l1, "foo", "")
+	checkValue(t, store, "foo", "baz")
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store, _, _ := createStore(t, cache.Options{}, encoding.JSON)
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test invalid options
+	invalidOptions := []cache.Options{
+		{},
+		{L1: gomap.NewStore(gomap.DefaultOptions)},
+		{L2: gomap.NewStore(gomap.DefaultOptions)},
+		{L1: gomap.NewStore(gomap.DefaultOptions), L2: gomap.NewStore(gomap.DefaultOptions), WritePolicy: 2},
+	}
+	for _, options := range invalidOptions {
+		_, err = cache.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+
+	// Errors of populating L1 are passed to the ErrorHandler
+	l2 := gomap.NewStore(gomap.DefaultOptions)
+	var handledErr error
+	store, err = cache.NewStore(cache.Options{
+		L1:           readOnlyStore{gomap.NewStore(gomap.DefaultOptions)},
+		L2:           l2,
+		ErrorHandler: func(err error) { handledErr = err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l2.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+	if !errors.Is(handledErr, errReadOnly) {
+		t.Errorf("Expected the error of populating L1, but was: %v", handledErr)
+	}
+	// And errors of writing to L1 are returned
+	err = store.Set("foo", "baz")
+	if !errors.Is(err, errReadOnly) {
+		t.Errorf("Expected the error of writing to L1, but was: %v", err)
+	}
+}
+
+// TestSentinelErrors tests if the errors of the underlying stores can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store, _, _ := createStore(t, cache.Options{}, encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store, _, _ := createStore(t, cache.Options{}, encoding.JSON)
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store, _, _ := createStore(t, cache.Options{NegativeTTL: time.Minute}, encoding.JSON)
+	checkValue(t, store, "foo", "")
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// createStore creates a cache store with a gomap store as L1 and a syncmap store as L2,
+// which are returned as well.
+func createStore(t *testing.T, options cache.Options, codec encoding.Codec) (cache.Store, gokv.Store, gokv.Store) {
+	l1 := gomap.NewStore(gomap.Options{Codec: codec})
+	l2 := syncmap.NewStore(syncmap.Options{Codec: codec})
+	options.L1 = l1
+	options.L2 = l2
+	store, err := cache.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, l1, l2
+}
+
+// checkValue checks the string value for the given key, with "" meaning that it must not be found.
+func checkValue(t *testing.T, store gokv.Store, k, expected string) {
+	t.Helper()
+	actual := ""
+	found, err := store.Get(k, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected == "" && found {
+		t.Errorf("Expected no value for the key %q, but was %q", k, actual)
+	} else if expected != "" && actual != expected {
+		t.Errorf("Expected %q for the key %q, but was %q (found: %v)", expected, k, actual, found)
+	}
+}
+
+func checkStats(t *testing.T, store cache.Store, expected cache.Stats) {
+	t.Helper()
+	actual := store.Stats()
+	if actual != expected {
+		t.Errorf("Expected the stats %+v, but was %+v", expected, actual)
+	}
+}
+
+var errReadOnly = errors.New("the store is read-only")
+
+// readOnlyStore is a gokv.Store that returns an error for Set().
+type readOnlyStore struct {
+	gokv.Store
+}
+
+func (s readOnlyStore) Set(k string, v any) error {
+	return errReadOnly
+}
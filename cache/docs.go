@@ -0,0 +1,10 @@
+/*
+Package cache contains an implementation of the `gokv.Store` interface that caches the values of a slow store
+in a fast one, for example a `dynamodb.Client` or `s3.Client` (L2) in a `freecache.Store` (L1).
+
+Values are read from L1 and, if they're not found there, from L2, populating L1 with them (read-through).
+Set() writes to L2 and then to L1 (write-through) or only invalidates the key in L1 (write-around),
+and Delete() deletes the key from both.
+Misses can be cached with a TTL (negative caching), and the store counts its hits and misses (see Store.Stats()).
+*/
+package cache
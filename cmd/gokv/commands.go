@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/philippgille/gokv"
+)
+
+func init() {
+	// Values that are stored with the gob codec by the set command are decoded from JSON,
+	// so they can contain these types in interfaces.
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+// pair is a key-value pair in the JSON Lines of export and import.
+// The value is the data as it's stored, so it's base64 encoded in the JSON.
+type pair struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+func (c cli) get(args []string) (err error) {
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	raw := flags.Bool("raw", false, "print the value as it's stored, without decoding it")
+	if err := c.parseFlags(flags, args, "[-raw] KEY", 1, 1); err != nil {
+		return err
+	}
+
+	store, codec, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(store, &err)
+
+	k := flags.Arg(0)
+	data, found, err := store.GetRaw(k)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no value found for the key %q", k)
+	}
+	if *raw {
+		_, err = c.stdout.Write(data)
+		return err
+	}
+	v, err := decodeValue(data, codec)
+	if err != nil {
+		return err
+	}
+	return c.printJSON(v, "  ")
+}
+
+func (c cli) set(args []string) (err error) {
+	flags := flag.NewFlagSet("set", flag.ContinueOnError)
+	raw := flags.Bool("raw", false, "store the value as it is, without encoding it with the codec")
+	if err := c.parseFlags(flags, args, "[-raw] KEY [VALUE]", 1, 2); err != nil {
+		return err
+	}
+	var data []byte
+	if flags.NArg() == 2 {
+		data = []byte(flags.Arg(1))
+	} else {
+		data, err = io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+	}
+
+	store, codec, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(store, &err)
+
+	k := flags.Arg(0)
+	if *raw {
+		return store.SetRaw(k, data)
+	}
+	if codec == "json" {
+		// Store the JSON as it is, which for example keeps the precision of large numbers
+		buf := bytes.Buffer{}
+		if err := json.Compact(&buf, data); err != nil {
+			return fmt.Errorf("the value isn't valid JSON: %w", err)
+		}
+		return store.SetRaw(k, buf.Bytes())
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("the value isn't valid JSON: %w", err)
+	}
+	if v == nil {
+		return errors.New("the value must not be null")
+	}
+	return store.Set(k, v)
+}
+
+func (c cli) delete(args []string) (err error) {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	if err := c.parseFlags(flags, args, "KEY...", 1, -1); err != nil {
+		return err
+	}
+
+	store, _, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(store, &err)
+
+	for _, k := range flags.Args() {
+		if err := store.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c cli) list(args []string) (err error) {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	values := flags.Bool("values", false, "print the values as well, decoded as JSON and separated from the key by a tab")
+	if err := c.parseFlags(flags, args, "[-values] [PREFIX]", 0, 1); err != nil {
+		return err
+	}
+
+	store, codec, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(store, &err)
+
+	keys, err := listKeys(store, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if !*values {
+			if _, err := fmt.Fprintln(c.stdout, k); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, found, err := store.GetRaw(k)
+		if err != nil {
+			return err
+		}
+		if !found {
+			// Deleted in the meantime
+			continue
+		}
+		v, err := decodeValue(data, codec)
+		if err != nil {
+			return fmt.Errorf("decoding the value for the key %q failed: %w", k, err)
+		}
+		if _, err := fmt.Fprintf(c.stdout, "%s\t", k); err != nil {
+			return err
+		}
+		if err := c.printJSON(v, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c cli) export(args []string) (err error) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	if err := c.parseFlags(flags, args, "[PREFIX]", 0, 1); err != nil {
+		return err
+	}
+
+	store, _, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(store, &err)
+
+	keys, err := listKeys(store, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.stdout)
+	for _, k := range keys {
+		data, found, err := store.GetRaw(k)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		if err := enc.Encode(pair{Key: k, Value: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c cli) importPairs(args []string) (err error) {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	if err := c.parseFlags(flags, args, "", 0, 0); err != nil {
+		return err
+	}
+
+	store, _, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(store, &err)
+
+	dec := json.NewDecoder(c.stdin)
+	count := 0
+	for {
+		p := pair{}
+		if err := dec.Decode(&p); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid input after %d key-value pairs: %w", count, err)
+		}
+		if p.Value == nil {
+			return fmt.Errorf("the value for the key %q is missing", p.Key)
+		}
+		if err := store.SetRaw(p.Key, p.Value); err != nil {
+			return err
+		}
+		count++
+	}
+	_, err = fmt.Fprintf(c.stderr, "Imported %d key-value pairs\n", count)
+	return err
+}
+
+func (c cli) copy(args []string) (err error) {
+	flags := flag.NewFlagSet("copy", flag.ContinueOnError)
+	to := flags.String("to", "", "URL of the store to copy the key-value pairs to")
+	if err := c.parseFlags(flags, args, "-to URL [PREFIX]", 0, 1); err != nil {
+		return err
+	}
+	if *to == "" {
+		flags.Usage()
+		return errUsage
+	}
+
+	src, _, err := c.open(c.storeURL)
+	if err != nil {
+		return err
+	}
+	defer closeStore(src, &err)
+	dst, _, err := c.open(*to)
+	if err != nil {
+		return err
+	}
+	defer closeStore(dst, &err)
+
+	keys, err := listKeys(src, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, k := range keys {
+		data, found, err := src.GetRaw(k)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		if err := dst.SetRaw(k, data); err != nil {
+			return err
+		}
+		count++
+	}
+	_, err = fmt.Fprintf(c.stderr, "Copied %d key-value pairs\n", count)
+	return err
+}
+
+// listKeys returns the sorted keys that start with the given prefix.
+// They're collected before the values are retrieved,
+// because some stores (like bbolt) don't allow other operations during the iteration.
+func listKeys(store gokv.Store, prefix string) ([]string, error) {
+	var keys []string
+	if scanner, ok := store.(gokv.PrefixScanner); ok {
+		err := scanner.ScanPrefix(prefix, func(k string, _ func(v any) error) bool {
+			keys = append(keys, k)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else if iterable, ok := store.(gokv.Iterable); ok {
+		err := iterable.Keys(func(k string) bool {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("listing keys isn't supported, because the store implements neither gokv.PrefixScanner nor gokv.Iterable")
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// printJSON prints the value as JSON, without escaping HTML characters.
+func (c cli) printJSON(v any, indent string) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	return enc.Encode(v)
+}
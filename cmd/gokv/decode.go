@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// object is a decoded struct, map or protobuf message.
+// Unlike a map it keeps the order of its members when it's printed as JSON.
+type object []member
+
+type member struct {
+	name  string
+	value any
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o object) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Encode() adds a newline after each value, which is insignificant whitespace in JSON
+		if err := enc.Encode(m.name); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := enc.Encode(m.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// objectKey converts a decoded map key to a member name.
+func objectKey(k any) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	if b, err := json.Marshal(k); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(k)
+}
+
+// jsonFloat returns NaN and infinity as string, because JSON doesn't support them.
+func jsonFloat(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
+
+// decodeValue decodes data that was marshalled with the given codec, for printing it as JSON.
+// Values are decoded without knowing their Go type.
+// For protobuf, the fields are named by their number, like `protoc --decode_raw` does.
+func decodeValue(data []byte, codec string) (any, error) {
+	switch codec {
+	case "json":
+		// Keep the original JSON, including the order of the object members and the precision of numbers
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("the value isn't valid JSON: %q", data)
+		}
+		return json.RawMessage(data), nil
+	case "gob":
+		return decodeGob(data)
+	case "protobuf":
+		msg, ok := decodeProtobuf(data)
+		if !ok {
+			return nil, fmt.Errorf("the value isn't a valid protobuf message: %q", data)
+		}
+		return msg, nil
+	default:
+		return nil, fmt.Errorf("decoding values of the codec %q isn't supported, use -raw instead", codec)
+	}
+}
+
+// decodeProtobuf decodes a protobuf message in the wire format without knowing its schema.
+// Length-delimited fields are decoded as nested message if possible, otherwise as string or bytes.
+func decodeProtobuf(data []byte) (object, bool) {
+	msg := object{}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, false
+		}
+		data = data[n:]
+
+		var value any
+		switch typ {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(data)
+		case protowire.Fixed32Type:
+			value, n = protowire.ConsumeFixed32(data)
+		case protowire.Fixed64Type:
+			value, n = protowire.ConsumeFixed64(data)
+		case protowire.BytesType:
+			var b []byte
+			b, n = protowire.ConsumeBytes(data)
+			if nested, ok := decodeProtobuf(b); ok && len(b) > 0 {
+				value = nested
+			} else if utf8.Valid(b) {
+				value = string(b)
+			} else {
+				value = b
+			}
+		case protowire.StartGroupType:
+			var b []byte
+			b, n = protowire.ConsumeGroup(num, data)
+			value, _ = decodeProtobuf(b)
+		default:
+			return nil, false
+		}
+		if n < 0 {
+			return nil, false
+		}
+		data = data[n:]
+		msg = append(msg, member{strconv.Itoa(int(num)), value})
+	}
+	return msg, true
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// IDs of the types that are predefined by encoding/gob.
+const (
+	gobBool      = 1
+	gobInt       = 2
+	gobUint      = 3
+	gobFloat     = 4
+	gobBytes     = 5
+	gobString    = 6
+	gobComplex   = 7
+	gobInterface = 8
+)
+
+type gobKind int
+
+const (
+	gobArrayKind gobKind = iota + 1
+	gobSliceKind
+	gobStructKind
+	gobMapKind
+	// Types that implement gob.GobEncoder or encoding.BinaryMarshaler
+	gobBinaryKind
+	// Types that implement encoding.TextMarshaler
+	gobTextKind
+)
+
+// gobType is a type definition that's sent in the gob data.
+type gobType struct {
+	kind   gobKind
+	name   string
+	key    int
+	elem   int
+	length int
+	fields []gobField
+}
+
+type gobField struct {
+	name string
+	id   int
+}
+
+// gobDecoder decodes gob data without knowing the Go type it was encoded from,
+// using the type definitions that encoding/gob sends along with the values.
+// The wire format is documented in the encoding/gob package.
+type gobDecoder struct {
+	// The current message
+	buf []byte
+	// The messages after the current one
+	rest  []byte
+	types map[int]*gobType
+}
+
+type gobError struct {
+	err error
+}
+
+// decodeGob decodes the value in the given gob data, which must have been encoded by a single gob.Encoder,
+// like encoding.Gob does.
+// Structs and maps are decoded to objects, slices and arrays to []any
+// and values of types that marshal themselves (like time.Time) to their data.
+func decodeGob(data []byte) (v any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			gobErr, ok := r.(gobError)
+			if !ok {
+				panic(r)
+			}
+			err = gobErr.err
+		}
+	}()
+
+	d := gobDecoder{
+		rest:  data,
+		types: make(map[int]*gobType),
+	}
+	id := d.typeSequence(false)
+	v = d.typedValue(id)
+	if len(d.buf) > 0 || len(d.rest) > 0 {
+		d.fail("extra data after the value")
+	}
+	return v, nil
+}
+
+// nextMessage makes the next message the current one.
+func (d *gobDecoder) nextMessage() {
+	if len(d.rest) == 0 {
+		d.fail("unexpected end")
+	}
+	d.buf, d.rest = d.rest, nil
+	n := d.uint()
+	if n > uint64(len(d.buf)) {
+		d.fail("message length %d exceeds the data", n)
+	}
+	d.buf, d.rest = d.buf[:n], d.buf[n:]
+}
+
+// typeSequence decodes the type definitions that precede a value and returns the type ID of the value.
+// The encoder sends the type definitions of an interface's concrete type while it's encoding the value
+// that contains the interface, so like the value they can span multiple messages.
+func (d *gobDecoder) typeSequence(isInterface bool) int {
+	for {
+		if len(d.buf) == 0 {
+			d.nextMessage()
+		}
+		id := d.int()
+		if id >= 0 {
+			return int(id)
+		}
+		if id < math.MinInt32 {
+			d.fail("invalid type ID %d", id)
+		}
+		d.defineType(int(-id))
+		if len(d.buf) > 0 {
+			if !isInterface {
+				d.fail("extra data after the type definition")
+			}
+			// Byte count of the next type definition or value
+			d.uint()
+		}
+	}
+}
+
+func (d *gobDecoder) fail(format string, a ...any) {
+	panic(gobError{fmt.Errorf("invalid gob data: "+format, a...)})
+}
+
+func (d *gobDecoder) uint() uint64 {
+	if len(d.buf) == 0 {
+		d.fail("unexpected end")
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	if b <= 0x7f {
+		return uint64(b)
+	}
+	// Values >= 128 are sent as negated byte count, followed by the big-endian bytes
+	n := -int(int8(b))
+	if n > 8 || n > len(d.buf) {
+		d.fail("invalid uint")
+	}
+	var x uint64
+	for _, b := range d.buf[:n] {
+		x = x<<8 | uint64(b)
+	}
+	d.buf = d.buf[n:]
+	return x
+}
+
+func (d *gobDecoder) int() int64 {
+	// The low bit is the sign, with the other bits being complemented for negative values
+	x := d.uint()
+	if x&1 != 0 {
+		return ^int64(x >> 1)
+	}
+	return int64(x >> 1)
+}
+
+func (d *gobDecoder) float() float64 {
+	// Floats are sent byte-reversed, so that the exponent comes first
+	return math.Float64frombits(bits.ReverseBytes64(d.uint()))
+}
+
+func (d *gobDecoder) bytes() []byte {
+	n := d.uint()
+	if n > uint64(len(d.buf)) {
+		d.fail("length %d exceeds the data", n)
+	}
+	b := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+// count returns the number of elements of a slice, array or map.
+// Each element takes at least one byte, which limits the allocations for invalid data.
+func (d *gobDecoder) count() int {
+	n := d.uint()
+	if n > uint64(len(d.buf)) {
+		d.fail("element count %d exceeds the data", n)
+	}
+	return int(n)
+}
+
+// structFields calls fn for each field that's sent for a struct, until the terminating zero delta.
+func (d *gobDecoder) structFields(fn func(field int)) {
+	field := -1
+	for {
+		delta := d.uint()
+		if delta == 0 {
+			return
+		}
+		if delta > math.MaxInt32 {
+			d.fail("invalid field delta %d", delta)
+		}
+		field += int(delta)
+		fn(field)
+	}
+}
+
+// defineType decodes a wireType and stores it as the type with the given ID.
+func (d *gobDecoder) defineType(id int) {
+	if id < 64 {
+		d.fail("type definition for the predefined type ID %d", id)
+	}
+	t := &gobType{}
+	d.structFields(func(field int) {
+		// wireType{ArrayT, SliceT, StructT, MapT, GobEncoderT, BinaryMarshalerT, TextMarshalerT}
+		switch field {
+		case 0:
+			t.kind = gobArrayKind
+			d.structFields(func(field int) {
+				// arrayType{CommonType, Elem, Len}
+				switch field {
+				case 0:
+					d.commonType(t)
+				case 1:
+					t.elem = int(d.int())
+				case 2:
+					t.length = int(d.int())
+				default:
+					d.fail("unknown arrayType field %d", field)
+				}
+			})
+		case 1:
+			t.kind = gobSliceKind
+			d.structFields(func(field int) {
+				// sliceType{CommonType, Elem}
+				switch field {
+				case 0:
+					d.commonType(t)
+				case 1:
+					t.elem = int(d.int())
+				default:
+					d.fail("unknown sliceType field %d", field)
+				}
+			})
+		case 2:
+			t.kind = gobStructKind
+			d.structFields(func(field int) {
+				// structType{CommonType, Field []fieldType}
+				switch field {
+				case 0:
+					d.commonType(t)
+				case 1:
+					n := d.count()
+					for i := 0; i < n; i++ {
+						f := gobField{}
+						d.structFields(func(field int) {
+							// fieldType{Name, Id}
+							switch field {
+							case 0:
+								f.name = string(d.bytes())
+							case 1:
+								f.id = int(d.int())
+							default:
+								d.fail("unknown fieldType field %d", field)
+							}
+						})
+						t.fields = append(t.fields, f)
+					}
+				default:
+					d.fail("unknown structType field %d", field)
+				}
+			})
+		case 3:
+			t.kind = gobMapKind
+			d.structFields(func(field int) {
+				// mapType{CommonType, Key, Elem}
+				switch field {
+				case 0:
+					d.commonType(t)
+				case 1:
+					t.key = int(d.int())
+				case 2:
+					t.elem = int(d.int())
+				default:
+					d.fail("unknown mapType field %d", field)
+				}
+			})
+		case 4, 5, 6:
+			t.kind = gobBinaryKind
+			if field == 6 {
+				t.kind = gobTextKind
+			}
+			d.structFields(func(field int) {
+				// gobEncoderType{CommonType}
+				if field != 0 {
+					d.fail("unknown gobEncoderType field %d", field)
+				}
+				d.commonType(t)
+			})
+		default:
+			d.fail("unknown wireType field %d", field)
+		}
+	})
+	if t.kind == 0 {
+		d.fail("empty type definition for type ID %d", id)
+	}
+	d.types[id] = t
+}
+
+func (d *gobDecoder) commonType(t *gobType) {
+	d.structFields(func(field int) {
+		// CommonType{Name, Id}
+		switch field {
+		case 0:
+			t.name = string(d.bytes())
+		case 1:
+			d.int()
+		default:
+			d.fail("unknown CommonType field %d", field)
+		}
+	})
+}
+
+// typedValue decodes a value that's preceded by its type ID, which is a top-level value or the content of an interface.
+// Values of other types than structs are sent like a struct with a single field.
+func (d *gobDecoder) typedValue(id int) any {
+	if t := d.types[id]; t != nil && t.kind == gobStructKind {
+		return d.value(id)
+	}
+	if d.uint() != 0 {
+		d.fail("non-zero delta for a singleton value")
+	}
+	return d.value(id)
+}
+
+func (d *gobDecoder) value(id int) any {
+	switch id {
+	case gobBool:
+		return d.uint() != 0
+	case gobInt:
+		return d.int()
+	case gobUint:
+		return d.uint()
+	case gobFloat:
+		return jsonFloat(d.float())
+	case gobBytes:
+		return d.bytes()
+	case gobString:
+		return string(d.bytes())
+	case gobComplex:
+		return fmt.Sprint(complex(d.float(), d.float()))
+	case gobInterface:
+		return d.interfaceValue()
+	}
+
+	t := d.types[id]
+	if t == nil {
+		d.fail("unknown type ID %d", id)
+	}
+	switch t.kind {
+	case gobStructKind:
+		obj := object{}
+		d.structFields(func(field int) {
+			if field >= len(t.fields) {
+				d.fail("unknown field %d of %s", field, t.name)
+			}
+			f := t.fields[field]
+			obj = append(obj, member{f.name, d.value(f.id)})
+		})
+		return obj
+	case gobSliceKind, gobArrayKind:
+		n := d.count()
+		if t.kind == gobArrayKind && n != t.length {
+			d.fail("length %d of %s doesn't match %d", n, t.name, t.length)
+		}
+		elems := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			elems = append(elems, d.value(t.elem))
+		}
+		return elems
+	case gobMapKind:
+		n := d.count()
+		obj := make(object, 0, n)
+		for i := 0; i < n; i++ {
+			k := d.value(t.key)
+			obj = append(obj, member{objectKey(k), d.value(t.elem)})
+		}
+		return obj
+	case gobTextKind:
+		return string(d.bytes())
+	default:
+		return d.bytes()
+	}
+}
+
+func (d *gobDecoder) interfaceValue() any {
+	// The name that the concrete type was registered with, "" for nil
+	name := d.bytes()
+	if len(name) == 0 {
+		return nil
+	}
+	id := d.typeSequence(true)
+	// Byte count of the value
+	d.uint()
+	return d.typedValue(id)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv/encoding"
+)
+
+type gobAddress struct {
+	Street string
+	Number uint16
+}
+
+type gobPerson struct {
+	Name      string
+	Age       int
+	Score     float64
+	Admin     bool
+	Tags      []string
+	Addresses map[string]gobAddress
+	Home      *gobAddress
+	Born      time.Time
+	Extra     any
+	Digest    [2]byte
+	Data      []byte
+}
+
+func init() {
+	gob.Register(gobAddress{})
+}
+
+// TestDecodeGob tests if values that were encoded with encoding.Gob are decoded without knowing their type.
+func TestDecodeGob(t *testing.T) {
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	bornData, err := born.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bornJSON, _ := json.Marshal(bornData)
+
+	testCases := []struct {
+		name     string
+		value    any
+		expected string
+	}{
+		{"bool", true, `true`},
+		{"int", -42, `-42`},
+		{"uint", uint(300), `300`},
+		{"float", 1.5, `1.5`},
+		{"NaN", math.NaN(), `"NaN"`},
+		{"bytes", []byte("foo"), `"Zm9v"`},
+		{"string", "foo", `"foo"`},
+		{"complex", complex(1, 2), `"(1+2i)"`},
+		{"slice", []int{1, 2, 3}, `[1,2,3]`},
+		{"array", [2]string{"a", "b"}, `["a","b"]`},
+		{"map", map[int]bool{1: true}, `{"1":true}`},
+		{"empty struct", gobAddress{}, `{}`},
+		{"struct", gobPerson{
+			Name:      "Alice",
+			Age:       42,
+			Score:     -0.25,
+			Tags:      []string{"a", "b"},
+			Addresses: map[string]gobAddress{"work": {"Main St", 1}},
+			Home:      &gobAddress{"Side St", 2},
+			Born:      born,
+			Extra:     gobAddress{"Extra St", 3},
+			Digest:    [2]byte{1, 2},
+		}, `{"Name":"Alice","Age":42,"Score":-0.25,"Tags":["a","b"],"Addresses":{"work":{"Street":"Main St","Number":1}},` +
+			`"Home":{"Street":"Side St","Number":2},"Born":` + string(bornJSON) + `,"Extra":{"Street":"Extra St","Number":3},"Digest":[1,2]}`},
+		{"interfaces", map[string]any{"a": []any{"b", 1.5, map[string]any{"c": nil}}}, `{"a":["b",1.5,{"c":null}]}`},
+		{"time", born, string(bornJSON)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := encoding.Gob.Marshal(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := decodeGob(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(actual) != tc.expected {
+				t.Errorf("Expected %s, but was %s", tc.expected, actual)
+			}
+		})
+	}
+}
+
+// TestDecodeGobErrors tests if invalid gob data leads to an error instead of a panic.
+func TestDecodeGobErrors(t *testing.T) {
+	data, err := encoding.Gob.Marshal(gobPerson{Name: "Alice", Tags: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(data); i++ {
+		if _, err := decodeGob(data[:i]); err == nil {
+			t.Errorf("Expected an error for the data truncated to %d bytes", i)
+		}
+	}
+	if _, err := decodeGob([]byte("foo")); err == nil {
+		t.Error("Expected an error")
+	}
+}
@@ -0,0 +1,176 @@
+// Command gokv creates, reads, updates and deletes key-value pairs in any gokv store.
+//
+// Usage:
+//
+//	gokv [-store URL] [-codec NAME] COMMAND [ARGUMENTS]
+//
+// The store is configured by a URL that's passed to gokv.Open(),
+// like "bbolt:///var/lib/app/app.db?bucket=default" or "redis://localhost:6379/0",
+// either with the -store flag or with the GOKV_STORE environment variable.
+// The codec (json by default) is selected by the "codec" query parameter of the URL or by the -codec flag.
+// It's also used for decoding the values for printing them, without requiring their Go types.
+//
+// Commands:
+//
+//	get [-raw] KEY             Print the value for the key, decoded and indented as JSON
+//	set [-raw] KEY [VALUE]     Store the value (JSON, or read from stdin if omitted) for the key
+//	delete KEY...              Delete the key-value pairs
+//	list [-values] [PREFIX]    Print the keys (with the prefix), optionally with their values
+//	export [PREFIX]            Write the key-value pairs (with the prefix) to stdout as JSON Lines
+//	import                     Store the key-value pairs that are read from stdin as JSON Lines
+//	copy -to URL [PREFIX]      Copy the key-value pairs (with the prefix) to another store
+//
+// With -raw, the value is printed or stored as is, without decoding or encoding it.
+// list, export and copy require a store that implements gokv.Iterable or gokv.PrefixScanner.
+// export, import and copy don't decode the values, so they keep their codec.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/philippgille/gokv"
+)
+
+const usage = `Usage: gokv [-store URL] [-codec NAME] COMMAND [ARGUMENTS]
+
+The store URL can also be set with the GOKV_STORE environment variable.
+Schemes: %v
+
+Commands:
+  get [-raw] KEY             Print the value for the key, decoded and indented as JSON
+  set [-raw] KEY [VALUE]     Store the value (JSON, or read from stdin if omitted) for the key
+  delete KEY...              Delete the key-value pairs
+  list [-values] [PREFIX]    Print the keys (with the prefix), optionally with their values
+  export [PREFIX]            Write the key-value pairs (with the prefix) to stdout as JSON Lines
+  import                     Store the key-value pairs that are read from stdin as JSON Lines
+  copy -to URL [PREFIX]      Copy the key-value pairs (with the prefix) to another store
+
+Flags:
+`
+
+// errUsage is returned for invalid arguments, after the usage was printed.
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// cli holds the configuration and the in- and outputs of a command.
+type cli struct {
+	storeURL string
+	codec    string
+	stdin    io.Reader
+	stdout   io.Writer
+	stderr   io.Writer
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := cli{
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	flags := flag.NewFlagSet("gokv", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&c.storeURL, "store", os.Getenv("GOKV_STORE"), "URL of the store, see gokv.Open()")
+	flags.StringVar(&c.codec, "codec", "", `codec of the values, like "json", "gob" or "protobuf" (overrides the "codec" query parameter of the URL)`)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usage, gokv.Schemes())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return errUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errUsage
+	}
+
+	commands := map[string]func(args []string) error{
+		"get":    c.get,
+		"set":    c.set,
+		"delete": c.delete,
+		"list":   c.list,
+		"export": c.export,
+		"import": c.importPairs,
+		"copy":   c.copy,
+	}
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		return errUsage
+	}
+	return command(flags.Args()[1:])
+}
+
+// open opens the store with the given URL, applying the -codec flag.
+// It returns the store together with the name of its codec, for decoding values.
+func (c cli) open(rawURL string) (gokv.RawStore, string, error) {
+	if rawURL == "" {
+		return nil, "", errors.New("no store URL is set, use -store or GOKV_STORE")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	q := u.Query()
+	if c.codec != "" {
+		q.Set("codec", c.codec)
+		u.RawQuery = q.Encode()
+	}
+	codec := q.Get("codec")
+	if codec == "" {
+		codec = "json"
+	}
+
+	store, err := gokv.Open(u.String())
+	if err != nil {
+		return nil, "", err
+	}
+	rawStore, ok := store.(gokv.RawStore)
+	if !ok {
+		_ = store.Close()
+		return nil, "", errors.New("the store doesn't implement gokv.RawStore")
+	}
+	return rawStore, codec, nil
+}
+
+// parseFlags parses the flags of a command and checks the number of the remaining arguments.
+// max < 0 means no maximum.
+func (c cli) parseFlags(flags *flag.FlagSet, args []string, argsUsage string, min, max int) error {
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: gokv %s %s\n", flags.Name(), argsUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() < min || (max >= 0 && flags.NArg() > max) {
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// closeStore closes the store and returns the error of closing it if there was no other error.
+func closeStore(store gokv.Store, err *error) {
+	if closeErr := store.Close(); closeErr != nil && *err == nil {
+		*err = closeErr
+	}
+}
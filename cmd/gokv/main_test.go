@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// runCommand runs the tool with the given arguments and returns its stdout and stderr.
+func runCommand(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	err := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+// mustRun runs the tool and fails the test if it returns an error.
+func mustRun(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	stdout, stderr, err := runCommand(t, stdin, args...)
+	if err != nil {
+		t.Fatalf("Running %v failed: %v\n%s", args, err, stderr)
+	}
+	return stdout
+}
+
+// TestCommands tests if the commands work with a bbolt store.
+func TestCommands(t *testing.T) {
+	storeURL := "bbolt://" + filepath.ToSlash(filepath.Join(t.TempDir(), "gokv.db"))
+
+	mustRun(t, "", "-store", storeURL, "set", "foo", `{"Bar": 1, "Baz": [true, null]}`)
+	mustRun(t, `"qux"`, "-store", storeURL, "set", "foo2")
+	mustRun(t, "", "-store", storeURL, "set", "-raw", "other", "not JSON")
+
+	actual := mustRun(t, "", "-store", storeURL, "get", "foo")
+	expected := "{\n  \"Bar\": 1,\n  \"Baz\": [\n    true,\n    null\n  ]\n}\n"
+	if actual != expected {
+		t.Errorf("Expected %q, but was %q", expected, actual)
+	}
+	actual = mustRun(t, "", "-store", storeURL, "get", "-raw", "other")
+	if actual != "not JSON" {
+		t.Errorf("Expected %q, but was %q", "not JSON", actual)
+	}
+
+	actual = mustRun(t, "", "-store", storeURL, "list")
+	if actual != "foo\nfoo2\nother\n" {
+		t.Errorf("Expected the keys foo, foo2 and other, but was %q", actual)
+	}
+	actual = mustRun(t, "", "-store", storeURL, "list", "-values", "foo")
+	expected = "foo\t{\"Bar\":1,\"Baz\":[true,null]}\nfoo2\t\"qux\"\n"
+	if actual != expected {
+		t.Errorf("Expected %q, but was %q", expected, actual)
+	}
+
+	exported := mustRun(t, "", "-store", storeURL, "export", "foo")
+	if strings.Count(exported, "\n") != 2 {
+		t.Errorf("Expected 2 exported key-value pairs, but was %q", exported)
+	}
+
+	mustRun(t, "", "-store", storeURL, "delete", "foo", "foo2")
+	if _, _, err := runCommand(t, "", "-store", storeURL, "get", "foo"); err == nil {
+		t.Error("Expected an error for a deleted key")
+	}
+	actual = mustRun(t, "", "-store", storeURL, "list")
+	if actual != "other\n" {
+		t.Errorf("Expected the key other, but was %q", actual)
+	}
+
+	_, stderr, err := runCommand(t, exported, "-store", storeURL, "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stderr != "Imported 2 key-value pairs\n" {
+		t.Errorf("Expected the number of imported key-value pairs, but was %q", stderr)
+	}
+	actual = mustRun(t, "", "-store", storeURL, "get", "foo2")
+	if actual != "\"qux\"\n" {
+		t.Errorf("Expected %q, but was %q", "\"qux\"\n", actual)
+	}
+
+	// Copy to a store with another implementation
+	dstURL := "file://" + filepath.ToSlash(t.TempDir())
+	_, stderr, err = runCommand(t, "", "-store", storeURL, "copy", "-to", dstURL, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stderr != "Copied 2 key-value pairs\n" {
+		t.Errorf("Expected the number of copied key-value pairs, but was %q", stderr)
+	}
+	actual = mustRun(t, "", "-store", dstURL, "list", "-values")
+	expected = "foo\t{\"Bar\":1,\"Baz\":[true,null]}\nfoo2\t\"qux\"\n"
+	if actual != expected {
+		t.Errorf("Expected %q, but was %q", expected, actual)
+	}
+}
+
+// TestCodecs tests if values are stored with the configured codec and decoded for printing them.
+func TestCodecs(t *testing.T) {
+	t.Run("gob", func(t *testing.T) {
+		t.Setenv("GOKV_STORE", "bbolt://"+filepath.ToSlash(filepath.Join(t.TempDir(), "gokv.db"))+"?codec=gob")
+
+		mustRun(t, "", "set", "foo", `{"Bar": [1.5, "baz", {"qux": null}]}`)
+		actual := mustRun(t, "", "get", "foo")
+		expected := "{\n  \"Bar\": [\n    1.5,\n    \"baz\",\n    {\n      \"qux\": null\n    }\n  ]\n}\n"
+		if actual != expected {
+			t.Errorf("Expected %q, but was %q", expected, actual)
+		}
+		// The -codec flag overrides the codec of the URL
+		if _, _, err := runCommand(t, "", "-codec", "json", "get", "foo"); err == nil {
+			t.Error("Expected an error when decoding a gob value as JSON")
+		}
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		storeURL := "file://" + filepath.ToSlash(t.TempDir()) + "?codec=protobuf"
+
+		var nested []byte
+		nested = protowire.AppendTag(nested, 1, protowire.VarintType)
+		nested = protowire.AppendVarint(nested, 42)
+		var msg []byte
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, "foo")
+		msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+		msg = protowire.AppendBytes(msg, nested)
+		msg = protowire.AppendTag(msg, 3, protowire.Fixed64Type)
+		msg = protowire.AppendFixed64(msg, 7)
+
+		mustRun(t, string(msg), "-store", storeURL, "set", "-raw", "msg")
+		actual := mustRun(t, "", "-store", storeURL, "list", "-values")
+		expected := "msg\t{\"1\":\"foo\",\"2\":{\"1\":42},\"3\":7}\n"
+		if actual != expected {
+			t.Errorf("Expected %q, but was %q", expected, actual)
+		}
+		// Values can't be encoded without their Go type
+		if _, _, err := runCommand(t, "", "-store", storeURL, "set", "foo", `"bar"`); err == nil {
+			t.Error("Expected an error")
+		}
+	})
+}
+
+// TestExportFormat tests if export writes the stored data as JSON Lines.
+func TestExportFormat(t *testing.T) {
+	storeURL := "file://" + filepath.ToSlash(t.TempDir())
+	mustRun(t, "", "-store", storeURL, "set", "foo", `"bar"`)
+
+	actual := mustRun(t, "", "-store", storeURL, "export")
+	p := pair{}
+	if err := json.Unmarshal([]byte(actual), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Key != "foo" || string(p.Value) != `"bar"` {
+		t.Errorf("Expected the key-value pair foo and \"bar\", but was %q", actual)
+	}
+}
+
+// TestUsageErrors tests if invalid arguments lead to errUsage.
+func TestUsageErrors(t *testing.T) {
+	t.Setenv("GOKV_STORE", "")
+	argsList := [][]string{
+		{},
+		{"unknown"},
+		{"-unknown", "get", "foo"},
+		{"-store", "memory:", "get"},
+		{"-store", "memory:", "get", "foo", "bar"},
+		{"-store", "memory:", "delete"},
+		{"-store", "memory:", "import", "foo"},
+		{"-store", "memory:", "copy", "foo"},
+	}
+	for _, args := range argsList {
+		_, stderr, err := runCommand(t, "", args...)
+		if err != errUsage {
+			t.Errorf("Expected errUsage for %v, but was %v", args, err)
+		}
+		if !strings.Contains(stderr, "Usage: gokv") {
+			t.Errorf("Expected the usage for %v, but was %q", args, stderr)
+		}
+	}
+
+	// A missing store URL isn't a usage error
+	if _, _, err := runCommand(t, "", "get", "foo"); err == nil || err == errUsage {
+		t.Errorf("Expected an error about the missing store URL, but was %v", err)
+	}
+}
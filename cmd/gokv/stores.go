@@ -0,0 +1,30 @@
+package main
+
+// The implementations register their URL scheme for gokv.Open().
+// pgx, tablestorage and tablestore don't register a scheme, so they aren't supported.
+import (
+	_ "github.com/philippgille/gokv/badgerdb"
+	_ "github.com/philippgille/gokv/bbolt"
+	_ "github.com/philippgille/gokv/bigcache"
+	_ "github.com/philippgille/gokv/cockroachdb"
+	_ "github.com/philippgille/gokv/consul"
+	_ "github.com/philippgille/gokv/datastore"
+	_ "github.com/philippgille/gokv/dynamodb"
+	_ "github.com/philippgille/gokv/encoding/protobuf"
+	_ "github.com/philippgille/gokv/etcd"
+	_ "github.com/philippgille/gokv/file"
+	_ "github.com/philippgille/gokv/freecache"
+	_ "github.com/philippgille/gokv/gomap"
+	_ "github.com/philippgille/gokv/hazelcast"
+	_ "github.com/philippgille/gokv/ignite"
+	_ "github.com/philippgille/gokv/leveldb"
+	_ "github.com/philippgille/gokv/memcached"
+	_ "github.com/philippgille/gokv/mongodb"
+	_ "github.com/philippgille/gokv/mysql"
+	_ "github.com/philippgille/gokv/noop"
+	_ "github.com/philippgille/gokv/postgresql"
+	_ "github.com/philippgille/gokv/redis"
+	_ "github.com/philippgille/gokv/s3"
+	_ "github.com/philippgille/gokv/syncmap"
+	_ "github.com/philippgille/gokv/zookeeper"
+)
@@ -2,15 +2,17 @@ package cockroachdb
 
 import (
 	gosql "database/sql"
+	"errors"
+	"net/url"
 
-	// pq must just be registered as driver.
-	// We don't need to use the package directly.
-	// This comment is required by golint
-	// ("warning: a blank import should be only in a main or test package, or have a comment justifying it").
-	_ "github.com/lib/pq"
+	// Usually a blank import is enough as it calls the package's init() function and loads the driver,
+	// but we use the package's Error type for classifying errors, so we make this an actual import.
+	"github.com/lib/pq"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/sql"
+	"github.com/philippgille/gokv/util"
 )
 
 const defaultDBname = "gokv"
@@ -78,12 +80,12 @@ func NewClient(options Options) (Client, error) {
 
 	db, err := gosql.Open("postgres", options.ConnectionURL)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	err = db.Ping()
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	// Limit number of concurrent connections.
@@ -98,39 +100,143 @@ func NewClient(options Options) (Client, error) {
 	// Use a "column family" so that a the row is a single entry in CockroachDB's underlying key-value store.
 	// See: https://forum.cockroachlabs.com/t/can-i-use-cockroachdb-as-a-kv-store/56.
 	// And: https://github.com/cockroachdb/docs/blob/b68c9ad8097d1efec4d2b6d849f6788a0e857215/v2.1/column-families.md
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k STRING PRIMARY KEY, v BYTES NOT NULL, FAMILY kv (k, v))")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k STRING PRIMARY KEY, v BYTES NOT NULL, ver INT8 NOT NULL DEFAULT 1, FAMILY kv (k, v, ver))")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
+	}
+	// Tables that were created by older versions of this package don't have the version column yet.
+	// ALTER TABLE starts a schema change, so we check the schema first
+	// (unquoted table names are stored in lower case).
+	var verColumns int
+	err = db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = lower($1) AND column_name = 'ver'", options.TableName).Scan(&verColumns)
+	if err != nil {
+		return result, classifyError(err)
+	}
+	if verColumns == 0 {
+		_, err = db.Exec("ALTER TABLE " + options.TableName + " ADD COLUMN IF NOT EXISTS ver INT8 NOT NULL DEFAULT 1 FAMILY kv")
+		if err != nil {
+			return result, classifyError(err)
+		}
 	}
 
 	// Create prepared statements that will be reused for every Set()/Get() operation.
 	// Note: Prepared statements are handled differently from other programming languages in Go,
 	// see: http://go-database-sql.org/prepared.html.
 
-	// Use "UPSERT" instead of "INSERT ON CONFLICT" because it doesn't do a read operation to determine the write operation,
-	// which makes it faster.
-	upsertStmt, err := db.Prepare("UPSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2)")
+	// "UPSERT" would be faster than "INSERT ON CONFLICT" because it doesn't do a read operation to determine the write operation,
+	// but it can't increment the version column.
+	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, ver) VALUES ($1, $2, $3) ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v, ver = " + options.TableName + ".ver + 1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	deleteStmt, err := db.Prepare("DELETE FROM " + options.TableName + " where k = $1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
+	}
+	scanPrefixStmt, err := db.Prepare("SELECT k, v FROM " + options.TableName + " WHERE k LIKE $1 ESCAPE '!' ORDER BY k")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	getVersionedStmt, err := db.Prepare("SELECT v, ver FROM " + options.TableName + " WHERE k = $1")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	createStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, ver) VALUES ($1, $2, $3) ON CONFLICT (k) DO NOTHING")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	updateIfVersionStmt, err := db.Prepare("UPDATE " + options.TableName + " SET v = $1, ver = ver + 1 WHERE k = $2 AND ver = $3")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	hasStmt, err := db.Prepare("SELECT 1 FROM " + options.TableName + " WHERE k = $1")
+	if err != nil {
+		return result, classifyError(err)
 	}
 
 	c := sql.Client{
-		C:          db,
-		UpsertStmt: upsertStmt,
-		GetStmt:    getStmt,
-		DeleteStmt: deleteStmt,
-		Codec:      options.Codec,
+		C:              db,
+		UpsertStmt:     upsertStmt,
+		GetStmt:        getStmt,
+		DeleteStmt:     deleteStmt,
+		ScanPrefixStmt: scanPrefixStmt,
+
+		GetVersionedStmt:    getVersionedStmt,
+		CreateStmt:          createStmt,
+		UpdateIfVersionStmt: updateIfVersionStmt,
+		HasStmt:             hasStmt,
+		// Multi-row statements depend on the number of rows, so they can't be prepared upfront.
+		UpsertMultiQuery: func(n int) string {
+			return "INSERT INTO " + options.TableName + " (k, v, ver) VALUES " + sql.PlaceholderTuples(n, 3, sql.DollarNumber) + " ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v, ver = " + options.TableName + ".ver + 1"
+		},
+		GetMultiQuery: func(n int) string {
+			return "SELECT k, v FROM " + options.TableName + " WHERE k IN (" + sql.PlaceholderList(n, sql.DollarNumber) + ")"
+		},
+		DeleteMultiQuery: func(n int) string {
+			return "DELETE FROM " + options.TableName + " WHERE k IN (" + sql.PlaceholderList(n, sql.DollarNumber) + ")"
+		},
+		ClassifyError: classifyError,
+		Codec:         options.Codec,
 	}
 
 	result.Client = &c
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("cockroachdb", open)
+}
+
+// open creates a client from a connection URL with the "cockroachdb" scheme instead of "postgres"
+// and the additional query parameters "table", "max_open_connections" and "codec",
+// like "cockroachdb://root@localhost:26257/gokv?sslmode=disable&table=Item&codec=gob".
+// The other query parameters are left in the connection URL.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		TableName:          q.String("table"),
+		MaxOpenConnections: q.Int("max_open_connections"),
+		Codec:              q.Codec(),
+	}
+	connURL := *u
+	connURL.Scheme = "postgres"
+	connURL.RawQuery = q.Rest().Encode()
+	options.ConnectionURL = connURL.String()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the PostgreSQL driver with the matching gokv error, like gokv.ErrConflict.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		// serialization_failure, which CockroachDB returns for transactions that must be retried,
+		// and deadlock_detected
+		case pqErr.Code == "40001", pqErr.Code == "40P01":
+			return gokv.WrapError(gokv.ErrConflict, err)
+		// query_canceled, which is also returned when the statement_timeout is exceeded
+		case pqErr.Code == "57014":
+			return gokv.WrapError(gokv.ErrTimeout, err)
+		// Connection exceptions, too_many_connections, admin_shutdown and cannot_connect_now
+		case pqErr.Code.Class() == "08", pqErr.Code == "53300", pqErr.Code == "57P01", pqErr.Code == "57P03":
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
@@ -1,4 +1,7 @@
 /*
 Package cockroachdb contains an implementation of the `gokv.Store` interface for CockroachDB.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"cockroachdb://root@localhost:26257/gokv?sslmode=disable&table=Item&max_open_connections=100&codec=gob" (other query parameters are left in the connection URL).
 */
 package cockroachdb
@@ -0,0 +1,390 @@
+package combiner
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
+
+// WritePolicy defines how values are written to the combined stores.
+type WritePolicy int
+
+const (
+	// WriteAll writes to all stores concurrently and waits for all of them.
+	WriteAll WritePolicy = iota
+	// WritePrimary writes to the primary store (the first one) and, if that succeeds,
+	// to the secondary stores asynchronously in the background.
+	// The writes to each secondary store are applied in the order of the calls.
+	WritePrimary
+)
+
+// ErrorPolicy defines when an operation fails if some of the combined stores return an error.
+type ErrorPolicy int
+
+const (
+	// FailIfAny fails the operation if any store that's involved returns an error.
+	FailIfAny ErrorPolicy = iota
+	// FailIfAll fails the operation only if all stores return an error.
+	// When reading, a store that returns an error is skipped, like a store that doesn't contain the key.
+	// The errors of the single stores are passed to the ErrorHandler.
+	FailIfAll
+)
+
+// Number of locks that the keys are distributed across.
+const lockCount = 256
+
+// Store is a gokv.Store that forwards its calls to multiple stores,
+// for example to a fast in-memory cache and a durable database.
+type Store struct {
+	stores       []gokv.Store
+	writePolicy  WritePolicy
+	errorPolicy  ErrorPolicy
+	readRepair   bool
+	errorHandler func(err error)
+	// One queue per store for the asynchronous writes and read repairs, so that they're applied in order
+	queues []chan func() error
+	// Read repairs of a key are mutually exclusive with writing the same key with WriteAll
+	locks *[lockCount]sync.Mutex
+	// Guards closing the queues
+	lock    *sync.RWMutex
+	closed  *bool
+	workers *sync.WaitGroup
+}
+
+// Set stores the given value for the given key in the combined stores, according to the WritePolicy.
+// With WritePrimary, the value must not be changed after Set() returns,
+// because it's marshalled by the secondary stores in the background.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	return s.write(k, func(store gokv.Store) error {
+		return store.Set(k, v)
+	})
+}
+
+// Get retrieves the stored value for the given key.
+// The stores are read in the order in which they were passed in the Options, until the key is found in one of them.
+// With ReadRepair, the value is then written to the other stores in the background if they don't contain it
+// or contain a different value. With WritePrimary, values are only repaired if they were found in the primary store,
+// because the secondary stores can lag behind it, for example with a deletion that's not applied yet.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// Each store's value is unmarshalled into a new object, so v is only changed when the value is found,
+// and not by a store that fails while unmarshalling.
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	// A non-pointer is passed on as it is, so the stores return their error for it
+	isPointer := reflect.TypeOf(v).Kind() == reflect.Pointer
+	var errs []error
+	for i, store := range s.stores {
+		target := v
+		if isPointer {
+			target = reflect.New(reflect.TypeOf(v).Elem()).Interface()
+		}
+		found, err := store.Get(k, target)
+		if err != nil {
+			err = storeError(i, err)
+			if s.errorPolicy == FailIfAny {
+				return false, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if found {
+			if isPointer {
+				reflect.ValueOf(v).Elem().Set(reflect.ValueOf(target).Elem())
+			}
+			s.handleErrors(errs)
+			if s.readRepair && (s.writePolicy == WriteAll || i == 0) {
+				s.repair(k, reflect.TypeOf(v), i)
+			}
+			return true, nil
+		}
+	}
+	if len(errs) == len(s.stores) {
+		return false, errors.Join(errs...)
+	}
+	s.handleErrors(errs)
+	return false, nil
+}
+
+// Delete deletes the stored value for the given key from the combined stores, according to the WritePolicy.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	return s.write(k, func(store gokv.Store) error {
+		return store.Delete(k)
+	})
+}
+
+// Wait blocks until all asynchronous writes and read repairs that were started before the call are done.
+func (s Store) Wait() {
+	done := &sync.WaitGroup{}
+	for i := range s.queues {
+		done.Add(1)
+		if !s.enqueue(i, func() error {
+			done.Done()
+			return nil
+		}) {
+			done.Done()
+		}
+	}
+	done.Wait()
+}
+
+// Close waits for the asynchronous writes and read repairs and then closes all combined stores.
+func (s Store) Close() error {
+	s.lock.Lock()
+	if !*s.closed {
+		*s.closed = true
+		for _, queue := range s.queues {
+			close(queue)
+		}
+	}
+	s.lock.Unlock()
+	s.workers.Wait()
+
+	var errs []error
+	for i, store := range s.stores {
+		if err := store.Close(); err != nil {
+			errs = append(errs, storeError(i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s Store) write(k string, op func(store gokv.Store) error) error {
+	if s.writePolicy == WritePrimary {
+		if err := op(s.stores[0]); err != nil {
+			return storeError(0, err)
+		}
+		for i := 1; i < len(s.stores); i++ {
+			store := s.stores[i]
+			s.enqueue(i, func() error {
+				return op(store)
+			})
+		}
+		return nil
+	}
+
+	lock := s.keyLock(k)
+	lock.Lock()
+	errs := make([]error, len(s.stores))
+	wg := sync.WaitGroup{}
+	for i, store := range s.stores {
+		wg.Add(1)
+		go func(i int, store gokv.Store) {
+			defer wg.Done()
+			if err := op(store); err != nil {
+				errs[i] = storeError(i, err)
+			}
+		}(i, store)
+	}
+	wg.Wait()
+	lock.Unlock()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	if s.errorPolicy == FailIfAny || failed == len(s.stores) {
+		return errors.Join(errs...)
+	}
+	s.handleErrors(errs)
+	return nil
+}
+
+// repair writes the value for the given key from the store with the index src
+// to all other stores that don't contain it or contain a different value.
+// The value is read from the source store again when the repair is applied, while holding the lock of the key,
+// so that a value that was written or deleted in the meantime isn't overwritten with an old one.
+// With WritePrimary the source is the primary store, whose writes are applied to a secondary store
+// through the same queue as the repair, so they can't overtake it either.
+func (s Store) repair(k string, ptrType reflect.Type, src int) {
+	if ptrType.Kind() != reflect.Ptr {
+		return
+	}
+	for i, store := range s.stores {
+		if i == src {
+			continue
+		}
+		store := store
+		s.enqueue(i, func() error {
+			lock := s.keyLock(k)
+			lock.Lock()
+			defer lock.Unlock()
+
+			v := reflect.New(ptrType.Elem()).Interface()
+			found, err := s.stores[src].Get(k, v)
+			if err != nil {
+				return fmt.Errorf("read repair of key %q: %w", k, storeError(src, err))
+			}
+			if !found {
+				return nil
+			}
+			current := reflect.New(ptrType.Elem()).Interface()
+			found, err = store.Get(k, current)
+			if err == nil && found && reflect.DeepEqual(current, v) {
+				return nil
+			}
+			// An error when reading can be caused by a value that can't be unmarshalled, which is fixed by the write
+			if err = store.Set(k, v); err != nil {
+				return fmt.Errorf("read repair of key %q: %w", k, storeError(i, err))
+			}
+			return nil
+		})
+	}
+}
+
+func (s Store) keyLock(k string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(k))
+	return &s.locks[h.Sum32()%lockCount]
+}
+
+// enqueue adds the operation to the queue of the store with the given index.
+// It returns false if the store is already closed.
+func (s Store) enqueue(i int, op func() error) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if *s.closed {
+		return false
+	}
+	s.queues[i] <- op
+	return true
+}
+
+func (s Store) handleErrors(errs []error) {
+	if s.errorHandler == nil {
+		return
+	}
+	for _, err := range errs {
+		if err != nil {
+			s.errorHandler(err)
+		}
+	}
+}
+
+// storeError adds the index of the store to its error, while keeping it unwrappable.
+func storeError(i int, err error) error {
+	return fmt.Errorf("store %d: %w", i, err)
+}
+
+// Options are the options for the combiner store.
+type Options struct {
+	// The stores to combine, in the order in which they're read.
+	// The first one is the primary store for WritePolicy WritePrimary.
+	// At least one store is required.
+	Stores []gokv.Store
+	// How values are written to the stores.
+	// Optional (WriteAll by default).
+	WritePolicy WritePolicy
+	// When an operation fails if some of the stores return an error.
+	// Optional (FailIfAny by default).
+	ErrorPolicy ErrorPolicy
+	// Write a value that's found in one store to the other stores in the background,
+	// if they don't contain it or contain a different one.
+	// This fills stores that missed writes, for example an empty cache after a restart
+	// or a secondary store that was unavailable during an asynchronous write.
+	// With WritePrimary only values that are found in the primary store are repaired.
+	// Optional (false by default).
+	ReadRepair bool
+	// Number of asynchronous writes and read repairs per store that can be pending.
+	// When a queue is full, calls block until there's space again.
+	// Optional (1000 by default).
+	QueueSize int
+	// Function that's called with the errors that aren't returned,
+	// which are the ones of asynchronous writes and read repairs,
+	// and with FailIfAll the ones of single stores when the operation succeeded on another store.
+	// It's called concurrently from multiple goroutines.
+	// Optional (the errors are discarded by default).
+	ErrorHandler func(err error)
+}
+
+// DefaultOptions is an Options object with default values.
+// WritePolicy: WriteAll, ErrorPolicy: FailIfAny, ReadRepair: false, QueueSize: 1000
+var DefaultOptions = Options{
+	WritePolicy: WriteAll,
+	ErrorPolicy: FailIfAny,
+	QueueSize:   1000,
+	// No need to set Stores, ReadRepair or ErrorHandler because their zero values are fine.
+}
+
+// NewStore creates a new combiner store.
+//
+// The store takes ownership of the combined stores, so you should call the Close() method
+// on the combiner store instead of the combined stores when you're done working with it.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if len(options.Stores) == 0 {
+		return result, errors.New("the Stores in the options must contain at least one store")
+	}
+	for i, store := range options.Stores {
+		if store == nil {
+			return result, fmt.Errorf("store %d in the options is nil", i)
+		}
+	}
+	if options.WritePolicy != WriteAll && options.WritePolicy != WritePrimary {
+		return result, fmt.Errorf("invalid WritePolicy %d", options.WritePolicy)
+	}
+	if options.ErrorPolicy != FailIfAny && options.ErrorPolicy != FailIfAll {
+		return result, fmt.Errorf("invalid ErrorPolicy %d", options.ErrorPolicy)
+	}
+
+	// Set default values
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultOptions.QueueSize
+	}
+
+	result = Store{
+		stores:       append([]gokv.Store(nil), options.Stores...),
+		writePolicy:  options.WritePolicy,
+		errorPolicy:  options.ErrorPolicy,
+		readRepair:   options.ReadRepair,
+		errorHandler: options.ErrorHandler,
+		queues:       make([]chan func() error, len(options.Stores)),
+		locks:        new([lockCount]sync.Mutex),
+		lock:         new(sync.RWMutex),
+		closed:       new(bool),
+		workers:      new(sync.WaitGroup),
+	}
+	for i := range result.queues {
+		queue := make(chan func() error, options.QueueSize)
+		result.queues[i] = queue
+		result.workers.Add(1)
+		go func() {
+			defer result.workers.Done()
+			for op := range queue {
+				if err := op(); err != nil && result.errorHandler != nil {
+					result.errorHandler(err)
+				}
+			}
+		}()
+	}
+
+	return result, nil
+}
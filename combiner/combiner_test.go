@@ -0,0 +1,500 @@
+package combiner_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/combiner"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with the different write policies
+	t.Run("WriteAll", func(t *testing.T) {
+		store := createStore(t, combiner.WriteAll, encoding.JSON, encoding.Gob)
+		test.TestStore(store, t)
+	})
+	t.Run("WritePrimary", func(t *testing.T) {
+		store := createStore(t, combiner.WritePrimary, encoding.JSON, encoding.Gob)
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, combiner.WriteAll, encoding.JSON, encoding.JSON)
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, combiner.WriteAll, encoding.Gob, encoding.Gob)
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store := createStore(t, combiner.WritePrimary, encoding.JSON, encoding.JSON)
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store := createStore(t, combiner.WriteAll, encoding.JSON, encoding.JSON)
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test invalid options
+	invalidOptions := []combiner.Options{
+		{},
+		{Stores: []gokv.Store{nil}},
+		{Stores: []gokv.Store{gomap.NewStore(gomap.DefaultOptions)}, WritePolicy: 2},
+		{Stores: []gokv.Store{gomap.NewStore(gomap.DefaultOptions)}, ErrorPolicy: 2},
+	}
+	for _, options := range invalidOptions {
+		_, err = combiner.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestSentinelErrors tests if the errors of the combined stores can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, combiner.WriteAll, encoding.JSON, encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, combiner.WriteAll, encoding.JSON, encoding.Gob)
+	test.TestTypedStore(store, t)
+}
+
+// TestWritePrimary tests if values are written to the secondary stores asynchronously.
+func TestWritePrimary(t *testing.T) {
+	primary := gomap.NewStore(gomap.DefaultOptions)
+	secondary := gomap.NewStore(gomap.DefaultOptions)
+	unavailable := test.FailingStore{Err: gokv.ErrUnavailable}
+	errs := &errorRecorder{}
+	store, err := combiner.NewStore(combiner.Options{
+		Stores:       []gokv.Store{primary, unavailable, secondary},
+		WritePolicy:  combiner.WritePrimary,
+		ErrorHandler: errs.record,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err = store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Wait()
+	checkValue(t, secondary, "foo", "bar")
+	// The error of the unavailable store is passed to the ErrorHandler
+	if len(errs.get()) != 1 || !gokv.IsUnavailable(errs.get()[0]) {
+		t.Errorf("Expected an error for which gokv.IsUnavailable() returns true, but was: %v", errs.get())
+	}
+
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Wait()
+	checkValue(t, secondary, "foo", "")
+
+	// The write fails if the primary store fails, and the secondary stores aren't written to then
+	store, err = combiner.NewStore(combiner.Options{
+		Stores:      []gokv.Store{unavailable, secondary},
+		WritePolicy: combiner.WritePrimary,
+		ErrorPolicy: combiner.FailIfAll,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("foo", "bar")
+	if !gokv.IsUnavailable(err) {
+		t.Errorf("Expected an error for which gokv.IsUnavailable() returns true, but was: %v", err)
+	}
+	store.Wait()
+	checkValue(t, secondary, "foo", "")
+}
+
+// TestReadFallback tests if the stores are read in order until the key is found.
+func TestReadFallback(t *testing.T) {
+	first := gomap.NewStore(gomap.DefaultOptions)
+	second := gomap.NewStore(gomap.DefaultOptions)
+	store, err := combiner.NewStore(combiner.Options{
+		Stores: []gokv.Store{first, second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err = second.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+	// The value is only found in the first store if it's there
+	err = first.Set("foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "baz")
+	// Without ReadRepair the stores aren't changed
+	checkValue(t, second, "foo", "bar")
+}
+
+// TestReadRepair tests if values are written to the stores that are missing them or contain a different value.
+func TestReadRepair(t *testing.T) {
+	first := gomap.NewStore(gomap.DefaultOptions)
+	second := gomap.NewStore(gomap.Options{Codec: encoding.Gob})
+	third := gomap.NewStore(gomap.DefaultOptions)
+	store, err := combiner.NewStore(combiner.Options{
+		Stores:     []gokv.Store{first, second, third},
+		ReadRepair: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Missing in the first store, stale in the third
+	err = second.Set("foo", test.Foo{Bar: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = third.Set("foo", test.Foo{Bar: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := test.Foo{}
+	found, err := store.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual.Bar != "new" {
+		t.Errorf("Expected the value %v, but was %v (found: %v)", test.Foo{Bar: "new"}, actual, found)
+	}
+	store.Wait()
+	for _, s := range []gokv.Store{first, third} {
+		actual = test.Foo{}
+		found, err = s.Get("foo", &actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !found || actual.Bar != "new" {
+			t.Errorf("Expected the repaired value %v, but was %v (found: %v)", test.Foo{Bar: "new"}, actual, found)
+		}
+	}
+}
+
+// TestReadRepairConcurrentDelete tests if a read repair doesn't bring back a value that's deleted while it's applied.
+func TestReadRepairConcurrentDelete(t *testing.T) {
+	first := gomap.NewStore(gomap.DefaultOptions)
+	second := &pausingStore{Store: gomap.NewStore(gomap.DefaultOptions), reached: make(chan struct{}), resume: make(chan struct{})}
+	store, err := combiner.NewStore(combiner.Options{
+		Stores:     []gokv.Store{first, second},
+		ReadRepair: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err = first.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+
+	// The repair read the value from the first store and is about to compare it with the one in the second store
+	<-second.reached
+	deleted := make(chan error, 1)
+	go func() {
+		deleted <- store.Delete("foo")
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if len(deleted) > 0 {
+		t.Error("Expected the deletion to wait for the read repair")
+	}
+	close(second.resume)
+	if err = <-deleted; err != nil {
+		t.Fatal(err)
+	}
+	store.Wait()
+
+	checkValue(t, first, "foo", "")
+	checkValue(t, second, "foo", "")
+}
+
+// TestReadRepairWritePrimary tests if with WritePrimary the primary store isn't repaired from a secondary store,
+// which can contain a value that was already deleted from the primary store.
+func TestReadRepairWritePrimary(t *testing.T) {
+	first := gomap.NewStore(gomap.DefaultOptions)
+	second := gomap.NewStore(gomap.DefaultOptions)
+	store, err := combiner.NewStore(combiner.Options{
+		Stores:      []gokv.Store{first, second},
+		WritePolicy: combiner.WritePrimary,
+		ReadRepair:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	err = second.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "bar")
+	store.Wait()
+	checkValue(t, first, "foo", "")
+
+	// Values from the primary store are repaired
+	err = first.Set("foo", "baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, store, "foo", "baz")
+	store.Wait()
+	checkValue(t, second, "foo", "baz")
+}
+
+// TestGetDecodeError tests if a value that one of the stores fails to unmarshal doesn't end up in the passed object,
+// neither when the key is found in another store nor when it isn't.
+func TestGetDecodeError(t *testing.T) {
+	type value struct {
+		A string
+		B int
+	}
+	broken := gomap.NewStore(gomap.DefaultOptions)
+	// A is unmarshalled before unmarshalling B fails
+	err := broken.SetRaw("foo", []byte(`{"A":"partial","B":"not a number"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodeErr := gokv.WrapError(gokv.ErrDecode, errors.New("invalid JSON"))
+	working := gomap.NewStore(gomap.DefaultOptions)
+	store, err := combiner.NewStore(combiner.Options{
+		Stores:      []gokv.Store{broken, test.FailingStore{Err: decodeErr}, working},
+		ErrorPolicy: combiner.FailIfAll,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	actual := value{}
+	found, err := store.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found || actual != (value{}) {
+		t.Errorf("Expected no value and an unchanged object, but found was %v with %+v", found, actual)
+	}
+
+	err = working.Set("foo", value{B: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err = store.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual != (value{B: 1}) {
+		t.Errorf("Expected %+v, but found was %v with %+v", value{B: 1}, found, actual)
+	}
+}
+
+// TestErrorPolicy tests if operations fail according to the ErrorPolicy.
+func TestErrorPolicy(t *testing.T) {
+	t.Run("FailIfAny", func(t *testing.T) {
+		working := gomap.NewStore(gomap.DefaultOptions)
+		store, err := combiner.NewStore(combiner.Options{
+			Stores:      []gokv.Store{test.FailingStore{Err: gokv.ErrTimeout}, working},
+			ErrorPolicy: combiner.FailIfAny,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		err = store.Set("foo", "bar")
+		if !gokv.IsTimeout(err) {
+			t.Errorf("Expected an error for which gokv.IsTimeout() returns true, but was: %v", err)
+		}
+		// With WriteAll the other stores are written to anyway
+		checkValue(t, working, "foo", "bar")
+		_, err = store.Get("foo", new(string))
+		if !gokv.IsTimeout(err) {
+			t.Errorf("Expected an error for which gokv.IsTimeout() returns true, but was: %v", err)
+		}
+		err = store.Delete("foo")
+		if !gokv.IsTimeout(err) {
+			t.Errorf("Expected an error for which gokv.IsTimeout() returns true, but was: %v", err)
+		}
+	})
+
+	t.Run("FailIfAll", func(t *testing.T) {
+		errs := &errorRecorder{}
+		store, err := combiner.NewStore(combiner.Options{
+			Stores:       []gokv.Store{test.FailingStore{Err: gokv.ErrTimeout}, gomap.NewStore(gomap.DefaultOptions)},
+			ErrorPolicy:  combiner.FailIfAll,
+			ErrorHandler: errs.record,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Error(err)
+		}
+		checkValue(t, store, "foo", "bar")
+		err = store.Delete("foo")
+		if err != nil {
+			t.Error(err)
+		}
+		checkValue(t, store, "foo", "")
+		if len(errs.get()) != 4 {
+			t.Errorf("Expected 4 errors to be passed to the ErrorHandler, but was: %v", errs.get())
+		}
+
+		// The operations fail if all stores fail
+		store, err = combiner.NewStore(combiner.Options{
+			Stores:      []gokv.Store{test.FailingStore{Err: gokv.ErrTimeout}, test.FailingStore{Err: gokv.ErrUnavailable}},
+			ErrorPolicy: combiner.FailIfAll,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Set("foo", "bar")
+		if !gokv.IsTimeout(err) || !gokv.IsUnavailable(err) {
+			t.Errorf("Expected an error that contains the errors of both stores, but was: %v", err)
+		}
+		_, err = store.Get("foo", new(string))
+		if !gokv.IsTimeout(err) || !gokv.IsUnavailable(err) {
+			t.Errorf("Expected an error that contains the errors of both stores, but was: %v", err)
+		}
+	})
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store := createStore(t, combiner.WritePrimary, encoding.JSON, encoding.JSON)
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Error(err)
+	}
+	err = store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	// The errors of closing the combined stores are returned
+	closeErr := errors.New("close failed")
+	store, err = combiner.NewStore(combiner.Options{
+		Stores: []gokv.Store{gomap.NewStore(gomap.DefaultOptions), test.FailingStore{Err: closeErr}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Close()
+	if !errors.Is(err, closeErr) {
+		t.Errorf("Expected the error of closing the failing store, but was: %v", err)
+	}
+}
+
+func createStore(t *testing.T, writePolicy combiner.WritePolicy, codec1, codec2 encoding.Codec) combiner.Store {
+	options := combiner.Options{
+		Stores: []gokv.Store{
+			gomap.NewStore(gomap.Options{Codec: codec1}),
+			gomap.NewStore(gomap.Options{Codec: codec2}),
+		},
+		WritePolicy: writePolicy,
+	}
+	store, err := combiner.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// checkValue checks the string value for the given key, with "" meaning that it must not be found.
+func checkValue(t *testing.T, store gokv.Store, k, expected string) {
+	t.Helper()
+	actual := ""
+	found, err := store.Get(k, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected == "" && found {
+		t.Errorf("Expected no value for the key %q, but was %q", k, actual)
+	} else if expected != "" && actual != expected {
+		t.Errorf("Expected %q for the key %q, but was %q (found: %v)", expected, k, actual, found)
+	}
+}
+
+// pausingStore is a gokv.Store whose first Get() signals that it was reached and then waits until resume is closed.
+type pausingStore struct {
+	gokv.Store
+	reached chan struct{}
+	resume  chan struct{}
+	once    sync.Once
+}
+
+func (s *pausingStore) Get(k string, v any) (bool, error) {
+	s.once.Do(func() {
+		close(s.reached)
+		<-s.resume
+	})
+	return s.Store.Get(k, v)
+}
+
+// errorRecorder records the errors that are passed to the ErrorHandler.
+type errorRecorder struct {
+	errs []error
+	lock sync.Mutex
+}
+
+func (r *errorRecorder) record(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+func (r *errorRecorder) get() []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]error(nil), r.errs...)
+}
@@ -0,0 +1,13 @@
+/*
+Package combiner contains an implementation of the `gokv.Store` interface that forwards its calls to multiple stores.
+
+It allows you to combine stores with different properties, for example a fast in-memory cache like `bigcache`
+with a durable database like `postgresql`, or `memcached` with `s3`.
+Any `gokv.Store` can be combined, including other combiner stores.
+
+How values are written is defined by the WritePolicy (all stores synchronously, or the primary store synchronously
+and the secondary stores asynchronously), how errors of single stores are handled by the ErrorPolicy.
+Values are read from the stores in order until one of them contains the key,
+and with ReadRepair the other stores are updated in the background.
+*/
+package combiner
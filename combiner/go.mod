@@ -0,0 +1,13 @@
+module github.com/philippgille/gokv/combiner
+
+go 1.20
+
+require (
+	github.com/philippgille/gokv v0.7.0
+	github.com/philippgille/gokv/encoding v0.7.0
+	github.com/philippgille/gokv/gomap v0.7.0
+	github.com/philippgille/gokv/test v0.7.0
+	github.com/philippgille/gokv/util v0.7.0
+)
+
+require github.com/go-test/deep v1.1.0 // indirect
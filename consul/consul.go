@@ -1,8 +1,15 @@
 package consul
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
 	"github.com/hashicorp/consul/api"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -18,6 +25,11 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to Consul.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -35,11 +47,34 @@ func (c Client) Set(k string, v any) error {
 		Key:   k,
 		Value: data,
 	}
-	_, err = c.c.Put(&kvPair, nil)
+	writeOptions := (&api.WriteOptions{}).WithContext(ctx)
+	_, err = c.c.Put(&kvPair, writeOptions)
 	if err != nil {
+		return classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair := api.KVPair{
+		Key:   k,
+		Value: data,
+	}
+	_, err := c.c.Put(&kvPair, nil)
+	if err != nil {
+		return classifyError(err)
+	}
+
 	return nil
 }
 
@@ -50,6 +85,11 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to Consul.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
@@ -57,9 +97,10 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	if c.folder != "" {
 		k = c.folder + "/" + k
 	}
-	kvPair, _, err := c.c.Get(k, nil)
+	queryOptions := (&api.QueryOptions{}).WithContext(ctx)
+	kvPair, _, err := c.c.Get(k, queryOptions)
 	if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 	// If no value was found return false
 	if kvPair == nil {
@@ -70,10 +111,96 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair, _, err := c.c.Get(k, nil)
+	if err != nil {
+		return nil, false, classifyError(err)
+	}
+	// If no value was found return false
+	if kvPair == nil {
+		return nil, false, nil
+	}
+	return kvPair.Value, true, nil
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version, which is Consul's ModifyIndex of the key.
+// If no value is found it returns (0, false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair, _, err := c.c.Get(k, nil)
+	if err != nil {
+		return 0, false, classifyError(err)
+	}
+	// If no value was found return false
+	if kvPair == nil {
+		return 0, false, nil
+	}
+
+	return kvPair.ModifyIndex, true, c.codec.Unmarshal(kvPair.Value, v)
+}
+
+// SetIfVersion stores the given value for the given key with Consul's check-and-set operation,
+// but only if the key's ModifyIndex is the given version.
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	if c.folder != "" {
+		k = c.folder + "/" + k
+	}
+	kvPair := api.KVPair{
+		Key:         k,
+		Value:       data,
+		ModifyIndex: version,
+	}
+	swapped, _, err = c.c.CAS(&kvPair, nil)
+	return swapped, classifyError(err)
+}
+
+// SetNX stores the given value for the given key with a check-and-set operation on index 0,
+// which only succeeds if the key doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	return c.SetIfVersion(k, v, 0)
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to Consul.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
@@ -81,8 +208,153 @@ func (c Client) Delete(k string) error {
 	if c.folder != "" {
 		k = c.folder + "/" + k
 	}
-	_, err := c.c.Delete(k, nil)
-	return err
+	writeOptions := (&api.WriteOptions{}).WithContext(ctx)
+	_, err := c.c.Delete(k, writeOptions)
+	return classifyError(err)
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+// When a folder is configured, only keys in that folder are considered,
+// and the keys that are passed to fn don't contain the folder.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	folderPrefix := ""
+	if c.folder != "" {
+		folderPrefix = c.folder + "/"
+	}
+	kvPairs, _, err := c.c.List(folderPrefix+prefix, nil)
+	if err != nil {
+		return classifyError(err)
+	}
+
+	for _, kvPair := range kvPairs {
+		data := kvPair.Value
+		decode := func(v any) error {
+			return c.codec.Unmarshal(data, v)
+		}
+		if !fn(strings.TrimPrefix(kvPair.Key, folderPrefix), decode) {
+			break
+		}
+	}
+	return nil
+}
+
+// Watch sends an event to the returned channel for each change of the given key,
+// using Consul's blocking queries.
+// The events' Revision is Consul's ModifyIndex of the change (or the index of the deletion),
+// and the value is part of the event.
+// Changes that happen in quick succession can be collapsed into one event.
+// Watching stops and the channel is closed when the context is canceled.
+// The key must not be "".
+func (c Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	key := k
+	if c.folder != "" {
+		key = c.folder + "/" + k
+	}
+	return c.watch(ctx, func(q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+		kvPair, meta, err := c.c.Get(key, q)
+		if err != nil || kvPair == nil {
+			return nil, meta, err
+		}
+		return api.KVPairs{kvPair}, meta, nil
+	})
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+func (c Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	folderPrefix := ""
+	if c.folder != "" {
+		folderPrefix = c.folder + "/"
+	}
+	return c.watch(ctx, func(q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+		return c.c.List(folderPrefix+prefix, q)
+	})
+}
+
+// watch repeatedly executes the given blocking query
+// and sends events for the differences between the results.
+func (c Client) watch(ctx context.Context, query func(q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)) (<-chan gokv.Event, error) {
+	folderPrefix := ""
+	if c.folder != "" {
+		folderPrefix = c.folder + "/"
+	}
+
+	// The initial query determines the state that the changes are compared to
+	kvPairs, meta, err := query((&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	modifyIndexes := make(map[string]uint64, len(kvPairs))
+	for _, kvPair := range kvPairs {
+		modifyIndexes[kvPair.Key] = kvPair.ModifyIndex
+	}
+	waitIndex := meta.LastIndex
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		for {
+			kvPairs, meta, err := query((&api.QueryOptions{WaitIndex: waitIndex}).WithContext(ctx))
+			if err != nil {
+				// The error when the context is canceled is expected
+				if ctx.Err() == nil {
+					select {
+					case events <- gokv.Event{Err: classifyError(err)}:
+					case <-ctx.Done():
+					}
+				}
+				return
+			}
+			// The index can go backwards, for example after a snapshot was restored,
+			// in which case Consul recommends to start over.
+			if meta.LastIndex < waitIndex {
+				waitIndex = 0
+			} else {
+				waitIndex = meta.LastIndex
+			}
+
+			var changes []gokv.Event
+			newModifyIndexes := make(map[string]uint64, len(kvPairs))
+			for _, kvPair := range kvPairs {
+				newModifyIndexes[kvPair.Key] = kvPair.ModifyIndex
+				if modifyIndex, ok := modifyIndexes[kvPair.Key]; ok && modifyIndex == kvPair.ModifyIndex {
+					continue
+				}
+				data := kvPair.Value
+				changes = append(changes, gokv.Event{
+					Type:     gokv.EventSet,
+					Key:      strings.TrimPrefix(kvPair.Key, folderPrefix),
+					Revision: kvPair.ModifyIndex,
+					Decode: func(v any) error {
+						return c.codec.Unmarshal(data, v)
+					},
+				})
+			}
+			for key := range modifyIndexes {
+				if _, ok := newModifyIndexes[key]; !ok {
+					changes = append(changes, gokv.Event{
+						Type:     gokv.EventDelete,
+						Key:      strings.TrimPrefix(key, folderPrefix),
+						Revision: meta.LastIndex,
+					})
+				}
+			}
+			modifyIndexes = newModifyIndexes
+
+			for _, event := range changes {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
 }
 
 // Close closes the client.
@@ -91,6 +363,12 @@ func (c Client) Close() error {
 	return nil
 }
 
+// CloseContext is like Close.
+// In the Consul implementation this doesn't have any effect.
+func (c Client) CloseContext(_ context.Context) error {
+	return nil
+}
+
 // Options are the options for the Consul client.
 type Options struct {
 	// URI scheme for the Consul server.
@@ -137,7 +415,7 @@ func NewClient(options Options) (Client, error) {
 	config.Address = options.Address
 	client, err := api.NewClient(config)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	result.c = client.KV()
@@ -146,3 +424,44 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("consul", open)
+}
+
+// open creates a client from a URL like "consul://127.0.0.1:8500/folder?scheme=https&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Scheme:  q.String("scheme"),
+		Address: u.Host,
+		Folder:  strings.Trim(u.Path, "/"),
+		Codec:   q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the Consul client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.Code == http.StatusTooManyRequests, statusErr.Code >= 500:
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		case statusErr.Code == http.StatusConflict:
+			return gokv.WrapError(gokv.ErrConflict, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
@@ -124,6 +124,59 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestContextStore(client, t)
+}
+
+// TestPrefixScanner tests if the key-value pairs with a given prefix can be retrieved.
+func TestPrefixScanner(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestPrefixScanner(client, t)
+}
+
+// TestVersionedStore tests if values are only stored when the given version is current.
+func TestVersionedStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestVersionedStore(client, t)
+}
+
+// TestCreateOnlyStore tests if values are only stored when the key doesn't exist yet.
+func TestCreateOnlyStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestCreateOnlyStore(client, t)
+}
+
+// TestWatcher tests if events are sent for changes of watched keys.
+func TestWatcher(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestRawStore(client, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestSentinelErrors(client, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestTypedStore(client, t)
+}
+
+// TestOpen tests if the client can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	test.TestOpen("consul:///test_"+strconv.FormatInt(time.Now().Unix(), 10)+"?codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	client := createClient(t, encoding.JSON)
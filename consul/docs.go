@@ -1,4 +1,7 @@
 /*
 Package consul contains an implementation of the `gokv.Store` interface for Consul.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"consul://127.0.0.1:8500/folder?scheme=https&codec=gob".
 */
 package consul
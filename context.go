@@ -0,0 +1,75 @@
+package gokv
+
+import (
+	"context"
+)
+
+// ContextStore is a Store that additionally offers methods that take a context.Context.
+// Implementations pass the context on to the client library they use,
+// so that cancellation and deadlines of the caller reach the backend.
+// Implementations that have a configured timeout apply it in addition to the context's deadline,
+// so whichever is earlier wins.
+//
+// The methods behave exactly like their counterparts without the "Context" suffix,
+// see the Store interface for details.
+type ContextStore interface {
+	Store
+	// SetContext stores the given value for the given key.
+	// The key must not be "" and the value must not be nil.
+	SetContext(ctx context.Context, k string, v any) error
+	// GetContext retrieves the value for the given key.
+	// If no value is found it returns (false, nil).
+	// The key must not be "" and the pointer must not be nil.
+	GetContext(ctx context.Context, k string, v any) (found bool, err error)
+	// DeleteContext deletes the stored value for the given key.
+	// Deleting a non-existing key-value pair does NOT lead to an error.
+	// The key must not be "".
+	DeleteContext(ctx context.Context, k string) error
+	// CloseContext must be called when the work with the key-value store is done.
+	// The context can be used to limit the time that's spent on a graceful shutdown.
+	CloseContext(ctx context.Context) error
+}
+
+// AsContextStore returns the given store as ContextStore.
+// If the store already implements ContextStore, it's returned as is.
+// Otherwise it's wrapped in an adapter that checks the context before forwarding each call
+// to the store's regular method. The adapter can't interrupt a call that's already in progress,
+// because the wrapped store has no way of receiving the context.
+func AsContextStore(store Store) ContextStore {
+	if cs, ok := store.(ContextStore); ok {
+		return cs
+	}
+	return contextAdapter{store}
+}
+
+// contextAdapter makes a plain Store satisfy the ContextStore interface.
+type contextAdapter struct {
+	Store
+}
+
+func (a contextAdapter) SetContext(ctx context.Context, k string, v any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Set(k, v)
+}
+
+func (a contextAdapter) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return a.Get(k, v)
+}
+
+func (a contextAdapter) DeleteContext(ctx context.Context, k string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Delete(k)
+}
+
+func (a contextAdapter) CloseContext(ctx context.Context) error {
+	// Closing is not skipped when the context is done already,
+	// because otherwise resources of the wrapped store would leak.
+	return a.Close()
+}
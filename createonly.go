@@ -0,0 +1,13 @@
+package gokv
+
+// CreateOnlyStore is a Store that can atomically store a value only if the key doesn't exist yet.
+// This is useful for idempotency keys, locks and leader election,
+// where a Get() followed by a Set() would be racy.
+type CreateOnlyStore interface {
+	Store
+	// SetNX stores the given value for the given key, but only if the key doesn't exist yet
+	// ("set if not exists").
+	// It returns false (and no error) if the key already exists, in which case the stored value isn't changed.
+	// The key must not be "" and the value must not be nil.
+	SetNX(k string, v any) (created bool, err error)
+}
@@ -3,11 +3,15 @@ package datastore
 import (
 	"context"
 	"errors"
+	"net/url"
 	"time"
 
 	"cloud.google.com/go/datastore"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -35,6 +39,12 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to Cloud Datastore.
+// The configured timeout still applies.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -45,7 +55,7 @@ func (c Client) Set(k string, v any) error {
 		return err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	tctx, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 	key := datastore.Key{
 		Kind: kind,
@@ -56,7 +66,28 @@ func (c Client) Set(k string, v any) error {
 	}
 	_, err = c.c.Put(tctx, &key, &src)
 
-	return err
+	return classifyError(err)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	key := datastore.Key{
+		Kind: kind,
+		Name: k,
+	}
+	src := entity{
+		V: data,
+	}
+	_, err := c.c.Put(tctx, &key, &src)
+
+	return classifyError(err)
 }
 
 // Get retrieves the stored value for the given key.
@@ -66,11 +97,17 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to Cloud Datastore.
+// The configured timeout still applies.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	tctx, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 	key := datastore.Key{
 		Kind: kind,
@@ -82,28 +119,59 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 		if err == datastore.ErrNoSuchEntity {
 			return false, nil
 		}
-		return false, err
+		return false, classifyError(err)
 	}
 	data := dst.V
 
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	key := datastore.Key{
+		Kind: kind,
+		Name: k,
+	}
+	dst := new(entity)
+	err = c.c.Get(tctx, &key, dst)
+	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil, false, nil
+		}
+		return nil, false, classifyError(err)
+	}
+	return dst.V, true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to Cloud Datastore.
+// The configured timeout still applies.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	tctx, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 	key := datastore.Key{
 		Kind: kind,
 		Name: k,
 	}
-	return c.c.Delete(tctx, &key)
+	return classifyError(c.c.Delete(tctx, &key))
 }
 
 // Close closes the client.
@@ -111,6 +179,12 @@ func (c Client) Close() error {
 	return c.c.Close()
 }
 
+// CloseContext is like Close.
+// The context is ignored, because the Cloud Datastore client doesn't support one for closing.
+func (c Client) CloseContext(_ context.Context) error {
+	return c.Close()
+}
+
 // Options are the options for the Cloud Datastore client.
 type Options struct {
 	// ID of the Google Cloud project.
@@ -168,7 +242,7 @@ func NewClient(options Options) (Client, error) {
 		dsClient, err = datastore.NewClient(context.Background(), options.ProjectID, option.WithCredentialsFile(options.CredentialsFile))
 	}
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	result.c = dsClient
@@ -177,3 +251,50 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("datastore", open)
+}
+
+// open creates a client from a URL like "datastore://project-id?credentials_file=/path/to/credentials.json&timeout=2s&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		ProjectID:       u.Host,
+		CredentialsFile: q.String("credentials_file"),
+		Codec:           q.Codec(),
+	}
+	if q.Has("timeout") {
+		timeout := q.Duration("timeout")
+		options.Timeout = &timeout
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the Cloud Datastore client with the matching gokv error, like gokv.ErrUnavailable.
+// The client returns gRPC errors, whose status code is used for that.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, datastore.ErrConcurrentTransaction) {
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case codes.Unavailable, codes.ResourceExhausted:
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	case codes.Aborted:
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return util.ClassifyError(err)
+}
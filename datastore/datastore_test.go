@@ -133,6 +133,44 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestContextStore(client, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestRawStore(client, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestSentinelErrors(client, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestTypedStore(client, t)
+}
+
+// TestOpen tests if the client can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	err := os.Setenv("DATASTORE_EMULATOR_HOST", "localhost:8081")
+	if err != nil {
+		t.Fatalf("Emulator environment variable couldn't be set: %v\n", err)
+	}
+
+	test.TestOpen("datastore://gokv?codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	client := createClient(t, encoding.JSON)
@@ -1,4 +1,7 @@
 /*
 Package datastore contains an implementation of the `gokv.Store` interface for Google Cloud Datastore.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"datastore://project-id?credentials_file=/path/to/credentials.json&timeout=2s&codec=gob".
 */
 package datastore
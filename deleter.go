@@ -0,0 +1,13 @@
+package gokv
+
+// Deleter is implemented by stores that can report whether a deleted key-value pair existed,
+// for example to distinguish actual deletions from no-ops in audit logs.
+// It's only implemented by stores whose backend reports this anyway,
+// so that it doesn't require an additional request.
+type Deleter interface {
+	// DeleteExisting deletes the stored value for the given key, like Delete(),
+	// and returns whether the key-value pair existed.
+	// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+	// The key must not be "".
+	DeleteExisting(k string) (existed bool, err error)
+}
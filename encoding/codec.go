@@ -1,10 +1,20 @@
 package encoding
 
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
 // Codec encodes/decodes Go values to/from slices of bytes.
 type Codec interface {
 	// Marshal encodes a Go value to a slice of bytes.
+	// Errors should be wrapped with gokv.WrapError(gokv.ErrEncode, err),
+	// so that callers can recognize them with gokv.IsEncode().
 	Marshal(v any) ([]byte, error)
 	// Unmarshal decodes a slice of bytes into a Go value.
+	// Errors should be wrapped with gokv.WrapError(gokv.ErrDecode, err),
+	// so that callers can recognize them with gokv.IsDecode().
 	Unmarshal(data []byte, v any) error
 }
 
@@ -15,3 +25,46 @@ var (
 	// Gob is a GobCodec that encodes/decodes Go values to/from gob.
 	Gob = GobCodec{}
 )
+
+var (
+	codecsLock sync.RWMutex
+	codecs     = map[string]Codec{
+		"json": JSON,
+		"gob":  Gob,
+	}
+)
+
+// Register makes a codec available by name, for example for the "codec" query parameter of the URLs
+// that are passed to gokv.Open(). "json" and "gob" are registered by default,
+// other codec packages like encoding/protobuf register themselves in their init() function.
+// Names are case-insensitive.
+// Register panics if the name is empty, if the codec is nil or if a codec is already registered with the name.
+func Register(name string, codec Codec) {
+	if name == "" {
+		panic("encoding: Register name is empty")
+	}
+	if codec == nil {
+		panic("encoding: Register codec is nil")
+	}
+	name = strings.ToLower(name)
+
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	if _, ok := codecs[name]; ok {
+		panic("encoding: Register called twice for codec " + name)
+	}
+	codecs[name] = codec
+}
+
+// ByName returns the codec that's registered with the given name, like "json" or "gob".
+// Names are case-insensitive.
+// It returns an error if no codec is registered with the name.
+func ByName(name string) (Codec, error) {
+	codecsLock.RLock()
+	codec, ok := codecs[strings.ToLower(name)]
+	codecsLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no codec is registered with the name %q (forgotten import?)", name)
+	}
+	return codec, nil
+}
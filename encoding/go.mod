@@ -1,3 +1,5 @@
 module github.com/philippgille/gokv/encoding
 
 go 1.20
+
+require github.com/philippgille/gokv v0.7.0
@@ -3,6 +3,8 @@ package encoding
 import (
 	"bytes"
 	"encoding/gob"
+
+	"github.com/philippgille/gokv"
 )
 
 // GobCodec encodes/decodes Go values to/from gob.
@@ -10,19 +12,21 @@ import (
 type GobCodec struct{}
 
 // Marshal encodes a Go value to gob.
+// Errors are classified as gokv.ErrEncode.
 func (c GobCodec) Marshal(v any) ([]byte, error) {
 	buffer := new(bytes.Buffer)
 	encoder := gob.NewEncoder(buffer)
 	err := encoder.Encode(v)
 	if err != nil {
-		return nil, err
+		return nil, gokv.WrapError(gokv.ErrEncode, err)
 	}
 	return buffer.Bytes(), nil
 }
 
 // Unmarshal decodes a gob value into a Go value.
+// Errors are classified as gokv.ErrDecode.
 func (c GobCodec) Unmarshal(data []byte, v any) error {
 	reader := bytes.NewReader(data)
 	decoder := gob.NewDecoder(reader)
-	return decoder.Decode(v)
+	return gokv.WrapError(gokv.ErrDecode, decoder.Decode(v))
 }
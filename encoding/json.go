@@ -2,6 +2,8 @@ package encoding
 
 import (
 	"encoding/json"
+
+	"github.com/philippgille/gokv"
 )
 
 // JSONcodec encodes/decodes Go values to/from JSON.
@@ -9,11 +11,17 @@ import (
 type JSONcodec struct{}
 
 // Marshal encodes a Go value to JSON.
+// Errors are classified as gokv.ErrEncode.
 func (c JSONcodec) Marshal(v any) ([]byte, error) {
-	return json.Marshal(v)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, gokv.WrapError(gokv.ErrEncode, err)
+	}
+	return data, nil
 }
 
 // Unmarshal decodes a JSON value into a Go value.
+// Errors are classified as gokv.ErrDecode.
 func (c JSONcodec) Unmarshal(data []byte, v any) error {
-	return json.Unmarshal(data, v)
+	return gokv.WrapError(gokv.ErrDecode, json.Unmarshal(data, v))
 }
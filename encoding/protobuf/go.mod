@@ -2,4 +2,8 @@ module github.com/philippgille/gokv/encoding/protobuf
 
 go 1.20
 
-require google.golang.org/protobuf v1.33.0
+require (
+	github.com/philippgille/gokv v0.7.0
+	github.com/philippgille/gokv/encoding v0.7.0
+	google.golang.org/protobuf v1.33.0
+)
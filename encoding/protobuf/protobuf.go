@@ -4,6 +4,9 @@ import (
 	"errors"
 
 	"google.golang.org/protobuf/proto"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
 )
 
 // Convenience variable for simpler usage in gokv store options.
@@ -13,25 +16,35 @@ import (
 //	}
 var Codec = PBcodec{}
 
+func init() {
+	encoding.Register("protobuf", Codec)
+}
+
 // PBcodec encodes/decodes Go values to/from protocol buffers.
 type PBcodec struct{}
 
 // Marshal encodes a proto message struct into the binary wire format.
 // Passed value can't be any Go value, but must be an object of a proto message struct.
+// Errors are classified as gokv.ErrEncode.
 func (c PBcodec) Marshal(v any) ([]byte, error) {
 	msg, ok := v.(proto.Message)
 	if !ok {
-		return nil, errors.New("error casting interface to proto")
+		return nil, gokv.WrapError(gokv.ErrEncode, errors.New("error casting interface to proto"))
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, gokv.WrapError(gokv.ErrEncode, err)
 	}
-	return proto.Marshal(msg)
+	return data, nil
 }
 
 // Unmarshal parses a wire-format message in proto message struct.
 // Passed value can't be any Go value, but must be an object of a proto message struct.
+// Errors of parsing the message are classified as gokv.ErrDecode.
 func (c PBcodec) Unmarshal(data []byte, v any) error {
 	msg, ok := v.(proto.Message)
 	if !ok {
 		return errors.New("error casting interface to proto")
 	}
-	return proto.Unmarshal(data, msg)
+	return gokv.WrapError(gokv.ErrDecode, proto.Unmarshal(data, msg))
 }
@@ -0,0 +1,94 @@
+package gokv
+
+import (
+	"errors"
+)
+
+// Errors that are returned by all implementations for invalid arguments.
+var (
+	// ErrKeyEmpty is returned when the passed key is an empty string.
+	ErrKeyEmpty = errors.New("the passed key is an empty string, which is invalid")
+	// ErrValueNil is returned when the passed value (or pointer to a value) is nil.
+	ErrValueNil = errors.New("the passed value is nil, which is not allowed")
+)
+
+// ErrNotSupported is returned when an operation isn't supported,
+// for example because a wrapped store doesn't implement the required optional interface.
+// The returned errors wrap it with WrapError(), so their message explains why the operation isn't supported.
+var ErrNotSupported = errors.New("the operation isn't supported")
+
+// Sentinel errors for classifying the errors of the backends.
+// Implementations return the backend's error, wrapped with WrapError() if it belongs to one of these classes,
+// so you can check the class with errors.Is() or the Is...() functions without importing the backend's client package,
+// while errors.As() and errors.Is() still work for the backend's error.
+var (
+	// ErrTimeout means that an operation didn't finish in time,
+	// for example because a context deadline or a configured timeout was exceeded.
+	ErrTimeout = errors.New("the operation timed out")
+	// ErrUnavailable means that the backend couldn't be reached or is temporarily unable to handle requests,
+	// for example because the connection was refused or the request was throttled.
+	// Retrying the operation later can succeed.
+	ErrUnavailable = errors.New("the backend is unavailable")
+	// ErrConflict means that an operation failed because of a concurrent change,
+	// for example a transaction that was aborted due to a conflict with another one.
+	// Retrying the operation can succeed.
+	ErrConflict = errors.New("the operation conflicts with a concurrent change")
+	// ErrDecode means that a stored value couldn't be unmarshalled.
+	ErrDecode = errors.New("the value couldn't be decoded")
+	// ErrEncode means that a value couldn't be marshalled, for example because its type isn't supported by the codec.
+	ErrEncode = errors.New("the value couldn't be encoded")
+)
+
+// IsTimeout returns true if the error is or wraps ErrTimeout.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// IsUnavailable returns true if the error is or wraps ErrUnavailable.
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// IsConflict returns true if the error is or wraps ErrConflict.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsDecode returns true if the error is or wraps ErrDecode.
+func IsDecode(err error) bool {
+	return errors.Is(err, ErrDecode)
+}
+
+// IsEncode returns true if the error is or wraps ErrEncode.
+func IsEncode(err error) bool {
+	return errors.Is(err, ErrEncode)
+}
+
+// WrapError returns an error that wraps both the given error and the given sentinel error (like ErrTimeout),
+// so that errors.Is() returns true for both. Its message is the one of the given error.
+// It's meant to be used by implementations for classifying the errors of their backend.
+// If err is nil, nil is returned, and if err already wraps the sentinel error, err is returned as is.
+func WrapError(sentinel, err error) error {
+	if err == nil || errors.Is(err, sentinel) {
+		return err
+	}
+	return classifiedError{
+		err:      err,
+		sentinel: sentinel,
+	}
+}
+
+// classifiedError is an error that's classified with a sentinel error.
+type classifiedError struct {
+	err      error
+	sentinel error
+}
+
+func (e classifiedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the original error and the sentinel error, for errors.Is() and errors.As().
+func (e classifiedError) Unwrap() []error {
+	return []error{e.err, e.sentinel}
+}
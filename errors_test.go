@@ -0,0 +1,43 @@
+package gokv_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/philippgille/gokv"
+)
+
+// TestWrapError tests if wrapped errors match both the sentinel error and the original error.
+func TestWrapError(t *testing.T) {
+	err := gokv.WrapError(gokv.ErrTimeout, context.DeadlineExceeded)
+	if !gokv.IsTimeout(err) {
+		t.Error("The wrapped error should be classified as timeout")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("The wrapped error should still match the original error")
+	}
+	if gokv.IsUnavailable(err) || gokv.IsConflict(err) || gokv.IsDecode(err) || gokv.IsEncode(err) {
+		t.Error("The wrapped error should only be classified as timeout")
+	}
+	if err.Error() != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected the message of the original error, but was: %v", err)
+	}
+
+	var pathErr *fs.PathError
+	err = gokv.WrapError(gokv.ErrUnavailable, &fs.PathError{Op: "open", Path: "foo", Err: fs.ErrNotExist})
+	if !errors.As(err, &pathErr) {
+		t.Error("errors.As() should work for the original error")
+	}
+
+	// Wrapping again must not change the error
+	wrapped := gokv.WrapError(gokv.ErrUnavailable, err)
+	if wrapped != err {
+		t.Error("An error that's already classified should be returned as is")
+	}
+
+	if gokv.WrapError(gokv.ErrConflict, nil) != nil {
+		t.Error("Wrapping nil should return nil")
+	}
+}
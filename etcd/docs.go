@@ -1,4 +1,7 @@
 /*
 Package etcd contains an implementation of the `gokv.Store` interface for etcd.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"etcd://host1:2379,host2:2379?timeout=200ms&codec=gob".
 */
 package etcd
@@ -3,10 +3,16 @@ package etcd
 import (
 	"context"
 	"errors"
+	"net/url"
+	"strings"
 	"time"
 
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -24,6 +30,12 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to etcd.
+// The configured timeout still applies.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -34,16 +46,62 @@ func (c Client) Set(k string, v any) error {
 		return err
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 	_, err = c.c.Put(ctxWithTimeout, k, string(data))
 	if err != nil {
+		return classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	_, err := c.c.Put(ctxWithTimeout, k, string(data))
+	if err != nil {
+		return classifyError(err)
+	}
+
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// A new lease is granted for each call and the key is attached to it.
+// etcd leases only support seconds, so the TTL is rounded up to the next full second,
+// and the etcd server can extend very short TTLs to its minimum lease TTL.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (c Client) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	lease, err := c.c.Grant(ctxWithTimeout, util.TTLSeconds(ttl))
+	if err != nil {
+		return classifyError(err)
+	}
+	_, err = c.c.Put(ctxWithTimeout, k, string(data), clientv3.WithLease(lease.ID))
+	return classifyError(err)
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -51,15 +109,21 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to etcd.
+// The configured timeout still applies.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 	getRes, err := c.c.Get(ctxWithTimeout, k)
 	if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 	kvs := getRes.Kvs
 	// If no value was found return false
@@ -71,18 +135,361 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	getRes, err := c.c.Get(ctxWithTimeout, k)
+	if err != nil {
+		return nil, false, classifyError(err)
+	}
+	kvs := getRes.Kvs
+	// If no value was found return false
+	if len(kvs) == 0 {
+		return nil, false, nil
+	}
+	return kvs[0].Value, true, nil
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version, which is etcd's ModRevision of the key.
+// If no value is found it returns (0, false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	getRes, err := c.c.Get(ctxWithTimeout, k)
+	if err != nil {
+		return 0, false, classifyError(err)
+	}
+	kvs := getRes.Kvs
+	// If no value was found return false
+	if len(kvs) == 0 {
+		return 0, false, nil
+	}
+
+	return uint64(kvs[0].ModRevision), true, c.codec.Unmarshal(kvs[0].Value, v)
+}
+
+// SetIfVersion stores the given value for the given key in a transaction,
+// but only if the key's ModRevision is the given version.
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	// The ModRevision of a key that doesn't exist is 0
+	txnRes, err := c.c.Txn(ctxWithTimeout).
+		If(clientv3.Compare(clientv3.ModRevision(k), "=", int64(version))).
+		Then(clientv3.OpPut(k, string(data))).
+		Commit()
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return txnRes.Succeeded, nil
+}
+
+// SetNX stores the given value for the given key in a transaction,
+// but only if the key doesn't exist yet (its CreateRevision is 0).
+// It returns false (and no error) if the key already exists.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	txnRes, err := c.c.Txn(ctxWithTimeout).
+		If(clientv3.Compare(clientv3.CreateRevision(k), "=", 0)).
+		Then(clientv3.OpPut(k, string(data))).
+		Commit()
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return txnRes.Succeeded, nil
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (c Client) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	getRes, err := c.c.Get(ctxWithTimeout, k, clientv3.WithCountOnly())
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return getRes.Count > 0, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to etcd.
+// The configured timeout still applies.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
-	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 	_, err := c.c.Delete(ctxWithTimeout, k)
-	return err
+	return classifyError(err)
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (c Client) DeleteExisting(k string) (existed bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	deleteRes, err := c.c.Delete(ctxWithTimeout, k)
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return deleteRes.Deleted > 0, nil
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+// All key-value pairs are retrieved with a single request, to which the configured timeout applies.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	getRes, err := c.c.Get(ctxWithTimeout, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return classifyError(err)
+	}
+
+	for _, kv := range getRes.Kvs {
+		data := kv.Value
+		decode := func(v any) error {
+			return c.codec.Unmarshal(data, v)
+		}
+		if !fn(string(kv.Key), decode) {
+			break
+		}
+	}
+	return nil
+}
+
+// Watch sends an event to the returned channel for each change of the given key, using etcd's watch API.
+// The events' Revision is etcd's ModRevision of the change, and the value is part of the event.
+// Watching stops and the channel is closed when the context is canceled.
+// The configured timeout applies to the request that determines the revision to start watching from.
+// The key must not be "".
+func (c Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return c.watch(ctx, k)
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+func (c Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watch(ctx, prefix, clientv3.WithPrefix())
+}
+
+func (c Client) watch(ctx context.Context, k string, opts ...clientv3.OpOption) (<-chan gokv.Event, error) {
+	// Watch from the revision after the current one, so that no change after this method returns is missed,
+	// even if etcd establishes the watch later.
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, c.timeOut)
+	getRes, err := c.c.Get(ctxWithTimeout, k, append(opts, clientv3.WithCountOnly())...)
+	cancel()
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	watchChan := c.c.Watch(ctx, k, append(opts, clientv3.WithRev(getRes.Header.Revision+1))...)
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		for watchRes := range watchChan {
+			if err := watchRes.Err(); err != nil {
+				// The error when the context is canceled is expected
+				if ctx.Err() == nil {
+					select {
+					case events <- gokv.Event{Err: classifyError(err)}:
+					case <-ctx.Done():
+					}
+				}
+				return
+			}
+			for _, ev := range watchRes.Events {
+				event := gokv.Event{
+					Key:      string(ev.Kv.Key),
+					Revision: uint64(ev.Kv.ModRevision),
+				}
+				if ev.Type == clientv3.EventTypePut {
+					event.Type = gokv.EventSet
+					data := ev.Kv.Value
+					event.Decode = func(v any) error {
+						return c.codec.Unmarshal(data, v)
+					}
+				} else {
+					event.Type = gokv.EventDelete
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Update executes the given function within an etcd software transactional memory (STM),
+// which buffers the writes and commits them in a single etcd transaction.
+// If the function returns nil, the writes are committed, otherwise they're discarded.
+// If a key that was read has been changed concurrently, the function is executed again.
+// The configured timeout applies to the whole transaction, including retries.
+//
+// etcd's STM doesn't distinguish between missing keys and empty values,
+// so a value whose encoding is empty (like an empty protobuf message) is reported as not found.
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	_, err := concurrency.NewSTM(c.c, func(stm concurrency.STM) error {
+		return fn(stmTx{
+			stm:   stm,
+			codec: c.codec,
+		})
+	}, concurrency.WithAbortContext(ctxWithTimeout))
+	return classifyError(err)
+}
+
+// View executes the given function on a snapshot of etcd's key space.
+// The revision of the first read is used for all following reads.
+// The configured timeout applies to the whole transaction.
+func (c Client) View(fn func(tx gokv.Tx) error) error {
+	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+	return fn(&snapshotTx{
+		ctx:   ctxWithTimeout,
+		c:     c.c,
+		codec: c.codec,
+	})
+}
+
+// stmTx is a gokv.Tx for an etcd STM.
+type stmTx struct {
+	stm   concurrency.STM
+	codec encoding.Codec
+}
+
+func (t stmTx) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	t.stm.Put(k, string(data))
+	return nil
+}
+
+func (t stmTx) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data := t.stm.Get(k)
+	if data == "" {
+		return false, nil
+	}
+	return true, t.codec.Unmarshal([]byte(data), v)
+}
+
+func (t stmTx) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	t.stm.Del(k)
+	return nil
+}
+
+// snapshotTx is a read-only gokv.Tx that reads all keys at the same revision.
+type snapshotTx struct {
+	ctx   context.Context
+	c     *clientv3.Client
+	rev   int64
+	codec encoding.Codec
+}
+
+func (t *snapshotTx) Set(k string, v any) error {
+	return gokv.ErrReadOnlyTx
+}
+
+func (t *snapshotTx) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	var opts []clientv3.OpOption
+	if t.rev != 0 {
+		opts = append(opts, clientv3.WithRev(t.rev))
+	}
+	getRes, err := t.c.Get(t.ctx, k, opts...)
+	if err != nil {
+		return false, classifyError(err)
+	}
+	if t.rev == 0 {
+		t.rev = getRes.Header.Revision
+	}
+	if len(getRes.Kvs) == 0 {
+		return false, nil
+	}
+	return true, t.codec.Unmarshal(getRes.Kvs[0].Value, v)
+}
+
+func (t *snapshotTx) Delete(k string) error {
+	return gokv.ErrReadOnlyTx
 }
 
 // Close closes the client.
@@ -91,6 +498,12 @@ func (c Client) Close() error {
 	return c.c.Close()
 }
 
+// CloseContext is like Close.
+// The context is ignored, because the etcd client doesn't support one for closing.
+func (c Client) CloseContext(_ context.Context) error {
+	return c.Close()
+}
+
 // Options are the options for the etcd client.
 type Options struct {
 	// Addresses of the etcd servers in the cluster, including port.
@@ -140,14 +553,14 @@ func NewClient(options Options) (Client, error) {
 
 	cli, err := clientv3.New(config)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	ctxWithTimeout, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	statusRes, err := cli.Status(ctxWithTimeout, options.Endpoints[0])
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	} else if statusRes == nil {
 		return result, errors.New("the status response from etcd was nil")
 	}
@@ -158,3 +571,56 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("etcd", open)
+}
+
+// open creates a client from a URL like "etcd://host1:2379,host2:2379?timeout=200ms&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Codec: q.Codec(),
+	}
+	if u.Host != "" {
+		options.Endpoints = strings.Split(u.Host, ",")
+	}
+	if q.Has("timeout") {
+		timeout := q.Duration("timeout")
+		options.Timeout = &timeout
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the etcd client with the matching gokv error, like gokv.ErrUnavailable.
+// The client returns gRPC errors, whose status code is used for that.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var code codes.Code
+	// Errors of the etcd server (rpctypes.EtcdError) have a Code() method, other gRPC errors have a status
+	var etcdErr interface{ Code() codes.Code }
+	if errors.As(err, &etcdErr) {
+		code = etcdErr.Code()
+	} else if s, ok := status.FromError(err); ok {
+		code = s.Code()
+	}
+	switch code {
+	case codes.DeadlineExceeded:
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case codes.Unavailable, codes.ResourceExhausted:
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	case codes.Aborted:
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return util.ClassifyError(err)
+}
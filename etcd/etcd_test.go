@@ -141,6 +141,160 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestContextStore(client, t)
+}
+
+// TestPrefixScanner tests if the key-value pairs with a given prefix can be retrieved.
+func TestPrefixScanner(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestPrefixScanner(client, t)
+}
+
+// TestExpiringStore tests if key-value pairs that are stored with a TTL expire.
+func TestExpiringStore(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestExpiringStore(client, t)
+}
+
+// TestVersionedStore tests if values are only stored when the given version is current.
+func TestVersionedStore(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestVersionedStore(client, t)
+}
+
+// TestCreateOnlyStore tests if values are only stored when the key doesn't exist yet.
+func TestCreateOnlyStore(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestCreateOnlyStore(client, t)
+}
+
+// TestTransactional tests if multiple operations can be executed in a transaction.
+func TestTransactional(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestTransactional(client, t)
+}
+
+// TestWatcher tests if events are sent for changes of watched keys.
+func TestWatcher(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestWatcher(client, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestRawStore(client, t)
+}
+
+// TestDeleter tests if deleting reports whether the key-value pair existed.
+func TestDeleter(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestDeleter(client, t)
+}
+
+// TestExistenceChecker tests if the existence of keys is checked correctly.
+func TestExistenceChecker(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestExistenceChecker(client, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestSentinelErrors(client, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	client := createClient(t, encoding.JSON)
+	defer func() { _ = client.Close() }()
+	test.TestTypedStore(client, t)
+}
+
+// TestOpen tests if the client can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	// For some reason this test fails in GitHub Actions, but not locally.
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		t.Skip("Skipping test in GitHub Actions. Run this locally before a release!")
+	}
+
+	test.TestOpen("etcd://localhost:2379?timeout=2s&codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	client := createClient(t, encoding.JSON)
@@ -0,0 +1,22 @@
+package gokv
+
+import (
+	"time"
+)
+
+// ExpiringStore is a Store that can store key-value pairs with a lifetime.
+// Most implementations rely on the expiry mechanism of the backend.
+//
+// After the TTL has passed the key-value pair isn't returned by Get() anymore,
+// but depending on the implementation it might take a while until it's actually deleted
+// from the backend and its resources are freed.
+// The precision of the TTL depends on the implementation as well.
+// Some backends only support seconds, in which case the TTL is rounded up to the next full second.
+type ExpiringStore interface {
+	Store
+	// SetWithTTL stores the given value for the given key,
+	// with the key-value pair expiring after the given TTL.
+	// Storing the key again with Set() removes the TTL.
+	// The key must not be "", the value must not be nil and the TTL must be positive.
+	SetWithTTL(k string, v any, ttl time.Duration) error
+}
@@ -1,5 +1,8 @@
 /*
 Package file contains an implementation of the `gokv.Store` interface for local files.
 Each key-value pair is a file with the key as name and the value as content.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"file:///tmp/gokv?ext=json&codec=json" (or "file://gokv" for a relative path, "ext=" disables the filename extension).
 */
 package file
@@ -1,11 +1,15 @@
 package file
 
 import (
+	"context"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -21,7 +25,10 @@ type Store struct {
 	fileLocks         map[string]*sync.RWMutex
 	filenameExtension string
 	directory         string
-	codec             encoding.Codec
+	watchers          *util.Broadcaster[gokv.Event]
+	// Incremented atomically with each change.
+	revision *uint64
+	codec    encoding.Codec
 }
 
 // Set stores the given value for the given key.
@@ -37,6 +44,21 @@ func (s Store) Set(k string, v any) error {
 		return err
 	}
 
+	return s.write(k, data)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	// The data is kept for the events of watchers, so it must not be changed by the caller afterwards.
+	return s.write(k, append([]byte(nil), data...))
+}
+
+func (s Store) write(k string, data []byte) error {
 	escapedKey := url.PathEscape(k)
 
 	// Prepare file lock.
@@ -51,7 +73,11 @@ func (s Store) Set(k string, v any) error {
 	// File lock and file handling.
 	lock.Lock()
 	defer lock.Unlock()
-	return os.WriteFile(filePath, data, 0o600)
+	if err := os.WriteFile(filePath, data, 0o600); err != nil {
+		return err
+	}
+	s.publish(gokv.EventSet, k, data)
+	return nil
 }
 
 // Get retrieves the stored value for the given key.
@@ -65,6 +91,26 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 		return false, err
 	}
 
+	data, found, err := s.read(k)
+	if err != nil || !found {
+		return false, err
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return s.read(k)
+}
+
+func (s Store) read(k string) ([]byte, bool, error) {
 	escapedKey := url.PathEscape(k)
 
 	// Prepare file lock.
@@ -83,20 +129,58 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	lock.RUnlock()
 	if err != nil {
 		if os.IsNotExist(err) {
-			return false, nil
+			return nil, false, nil
 		}
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Has returns true if a file exists for the given key, without reading it.
+// The key must not be "".
+func (s Store) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
 		return false, err
 	}
 
-	return true, s.codec.Unmarshal(data, v)
+	escapedKey := url.PathEscape(k)
+
+	// Prepare file lock.
+	lock := s.prepFileLock(escapedKey)
+
+	filename := escapedKey
+	if s.filenameExtension != "" {
+		filename += "." + s.filenameExtension
+	}
+	filePath := filepath.Clean(s.directory + "/" + filename)
+
+	// File lock and file handling.
+	lock.RLock()
+	defer lock.RUnlock()
+	_, err = os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (s Store) Delete(k string) error {
+	_, err := s.DeleteExisting(k)
+	return err
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (s Store) DeleteExisting(k string) (existed bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return false, err
 	}
 
 	escapedKey := url.PathEscape(k)
@@ -113,16 +197,123 @@ func (s Store) Delete(k string) error {
 	// File lock and file handling.
 	lock.Lock()
 	defer lock.Unlock()
-	err := os.Remove(filePath)
+	err = os.Remove(filePath)
 	if os.IsNotExist(err) {
-		return nil
+		return false, nil
+	} else if err != nil {
+		return false, err
 	}
-	return err
+	s.publish(gokv.EventDelete, k, nil)
+	return true, nil
+}
+
+// Keys calls fn for each key in the store, in the order of the filenames.
+// The iteration stops when fn returns false.
+// It works on a snapshot of the directory listing, so fn can safely write to the store.
+// Files that don't have the configured filename extension are skipped.
+func (s Store) Keys(fn func(k string) bool) error {
+	keys, err := s.listKeys()
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if !fn(k) {
+			break
+		}
+	}
+	return nil
+}
+
+// Iter returns an iterator over a snapshot of the keys in the store, in the order of the filenames.
+// Files that don't have the configured filename extension are skipped.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	keys, err := s.listKeys()
+	if err != nil {
+		return nil, err
+	}
+	return util.NewKeySliceIterator(keys), nil
+}
+
+// listKeys reads the store's directory and turns the filenames back into keys.
+func (s Store) listKeys() ([]string, error) {
+	entries, err := os.ReadDir(s.directory)
+	if err != nil {
+		return nil, err
+	}
+	var suffix string
+	if s.filenameExtension != "" {
+		suffix = "." + s.filenameExtension
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		escapedKey, ok := strings.CutSuffix(entry.Name(), suffix)
+		if !ok || escapedKey == "" {
+			continue
+		}
+		k, err := url.PathUnescape(escapedKey)
+		if err != nil {
+			// Not a file that was written by this store
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Watch sends an event to the returned channel for each change of the given key,
+// until the context is canceled or the store is closed.
+// Only changes that are made via this store are detected,
+// not changes of the files by other processes or other store instances.
+// The events' Revision is a counter that's incremented with each change of the store.
+// Events are queued for each watcher, so slow receivers don't block writes.
+// When more than util.DefaultMaxQueueLen events are queued, because they aren't received,
+// they're dropped and watching stops with an event whose Err is gokv.ErrEventsDropped.
+// The key must not be "".
+func (s Store) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return s.watchers.Subscribe(ctx, func(key string) bool {
+		return key == k
+	}), nil
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+func (s Store) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return s.watchers.Subscribe(ctx, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}), nil
+}
+
+// publish sends an event for the change to the watchers.
+// The caller must hold the file lock, so that the events for a key are sent in the order of the changes.
+func (s Store) publish(eventType gokv.EventType, k string, data []byte) {
+	revision := atomic.AddUint64(s.revision, 1)
+	if !s.watchers.HasSubscribers() {
+		return
+	}
+	event := gokv.Event{
+		Type:     eventType,
+		Key:      k,
+		Revision: revision,
+	}
+	if eventType == gokv.EventSet {
+		event.Decode = func(v any) error {
+			return s.codec.Unmarshal(data, v)
+		}
+	}
+	s.watchers.Publish(k, event)
 }
 
 // Close closes the store.
-// When called, some resources of the store are left for garbage collection.
+// When called, the channels of all watchers are closed
+// and some resources of the store are left for garbage collection.
 func (s Store) Close() error {
+	s.watchers.Close()
 	s.locksLock.Lock()
 	defer s.locksLock.Unlock()
 	for k := range s.fileLocks {
@@ -196,8 +387,37 @@ func NewStore(options Options) (Store, error) {
 	result.directory = options.Directory
 	result.locksLock = new(sync.Mutex)
 	result.fileLocks = make(map[string]*sync.RWMutex)
+	result.watchers = util.NewBroadcaster(util.DefaultMaxQueueLen, gokv.Event{Err: gokv.ErrEventsDropped})
+	result.revision = new(uint64)
 	result.filenameExtension = *options.FilenameExtension
 	result.codec = options.Codec
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("file", open)
+}
+
+// open creates a store from a URL like "file:///tmp/gokv?ext=json&codec=json",
+// see util.URLPath() for relative paths.
+// "ext=" disables the filename extension.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Directory: util.URLPath(u),
+		Codec:     q.Codec(),
+	}
+	if q.Has("ext") {
+		ext := q.String("ext")
+		options.FilenameExtension = &ext
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -133,6 +133,62 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestIterable tests if the keys of the store can be iterated over.
+func TestIterable(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestIterable(store, t)
+}
+
+// TestWatcher tests if events are sent for changes of watched keys.
+func TestWatcher(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestWatcher(store, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestRawStore(store, t)
+}
+
+// TestDeleter tests if deleting reports whether the key-value pair existed.
+func TestDeleter(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestDeleter(store, t)
+}
+
+// TestExistenceChecker tests if the existence of keys is checked correctly.
+func TestExistenceChecker(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestExistenceChecker(store, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store, path := createStore(t, encoding.JSON)
+	defer cleanUp(store, path)
+	test.TestTypedStore(store, t)
+}
+
+// TestOpen tests if the store can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	path := generateRandomTempDBpath(t)
+	defer func() { _ = os.RemoveAll(path) }()
+	test.TestOpen("file://"+path+"?ext=gob&codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	store, path := createStore(t, encoding.JSON)
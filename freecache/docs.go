@@ -1,4 +1,7 @@
 /*
 Package freecache contains an implementation of the `gokv.Store` interface for FreeCache.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"freecache://?size=268435456&codec=gob".
 */
 package freecache
@@ -1,8 +1,12 @@
 package freecache
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/coocood/freecache"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -31,6 +35,36 @@ func (s Store) Set(k string, v any) error {
 	return s.s.Set([]byte(k), data, 0)
 }
 
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	return s.s.Set([]byte(k), data, 0)
+}
+
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// FreeCache only supports seconds, so the TTL is rounded up to the next full second.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (s Store) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.s.Set([]byte(k), data, int(util.TTLSeconds(ttl)))
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -53,16 +87,43 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	return true, s.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err = s.s.Get([]byte(k))
+	if err != nil {
+		if err == freecache.ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (s Store) Delete(k string) error {
+	_, err := s.DeleteExisting(k)
+	return err
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (s Store) DeleteExisting(k string) (existed bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return false, err
 	}
 
-	s.s.Del([]byte(k))
-	return nil
+	return s.s.Del([]byte(k)), nil
 }
 
 // Close closes the store.
@@ -116,3 +177,20 @@ func NewStore(options Options) Store {
 		codec: options.Codec,
 	}
 }
+
+func init() {
+	gokv.Register("freecache", open)
+}
+
+// open creates a store from a URL like "freecache://?size=268435456&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Size:  q.Int("size"),
+		Codec: q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	return NewStore(options), nil
+}
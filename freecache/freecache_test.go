@@ -119,6 +119,41 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestExpiringStore tests if key-value pairs that are stored with a TTL expire.
+func TestExpiringStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestExpiringStore(store, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestRawStore(store, t)
+}
+
+// TestDeleter tests if deleting reports whether the key-value pair existed.
+func TestDeleter(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestDeleter(store, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestTypedStore(store, t)
+}
+
+// TestOpen tests if the store can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	test.TestOpen("freecache://?codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	store := createStore(t, encoding.JSON)
@@ -1,4 +1,7 @@
 /*
 Package gomap contains an implementation of the `gokv.Store` interface for a Go map.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"memory://?cleanup_interval=1m&codec=gob".
 */
 package gomap
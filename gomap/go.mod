@@ -3,6 +3,7 @@ module github.com/philippgille/gokv/gomap
 go 1.20
 
 require (
+	github.com/philippgille/gokv v0.7.0
 	github.com/philippgille/gokv/encoding v0.7.0
 	github.com/philippgille/gokv/test v0.7.0
 	github.com/philippgille/gokv/util v0.7.0
@@ -10,5 +11,4 @@ require (
 
 require (
 	github.com/go-test/deep v1.1.0 // indirect
-	github.com/philippgille/gokv v0.7.0 // indirect
 )
@@ -1,17 +1,29 @@
 package gomap
 
 import (
+	"context"
+	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Store is a gokv.Store implementation for a Go map with a sync.RWMutex for concurrent access.
 type Store struct {
-	m     map[string][]byte
-	lock  *sync.RWMutex
-	codec encoding.Codec
+	m map[string][]byte
+	// Expiry times of the key-value pairs that were stored with a TTL.
+	// Guarded by the same lock as m.
+	expiries map[string]time.Time
+	lock     *sync.RWMutex
+	janitor  *util.Janitor
+	watchers *util.Broadcaster[gokv.Event]
+	// Incremented with each change, guarded by the lock.
+	revision *uint64
+	codec    encoding.Codec
 }
 
 // Set stores the given value for the given key.
@@ -27,9 +39,54 @@ func (s Store) Set(k string, v any) error {
 		return err
 	}
 
+	s.set(k, data)
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The data is copied, so the slice can be changed afterwards.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	s.set(k, append([]byte(nil), data...))
+	return nil
+}
+
+func (s Store) set(k string, data []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.m[k] = data
+	delete(s.expiries, k)
+	s.publish(gokv.EventSet, k, data)
+}
+
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// Expired key-value pairs aren't returned anymore and are deleted by a background goroutine,
+// which is started with the first call of this method and runs in the configured CleanupInterval.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (s Store) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.janitor.Start()
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.m[k] = data
+	s.expiries[k] = time.Now().Add(ttl)
+	s.publish(gokv.EventSet, k, data)
 	return nil
 }
 
@@ -44,55 +101,240 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 		return false, err
 	}
 
+	data, found := s.get(k)
+	if !found {
+		return false, nil
+	}
+
+	return true, s.codec.Unmarshal(data, v)
+}
+
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// The returned slice is a copy, so it can be changed.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, found = s.get(k)
+	if !found {
+		return nil, false, nil
+	}
+	return append([]byte(nil), data...), true, nil
+}
+
+func (s Store) get(k string) ([]byte, bool) {
 	s.lock.RLock()
 	data, found := s.m[k]
+	expired := s.isExpired(k, time.Now())
 	// Unlock right after reading instead of with defer(),
 	// because following unmarshalling will take some time
 	// and we don't want to block writing threads until that's done.
 	s.lock.RUnlock()
-	if !found {
+	return data, found && !expired
+}
+
+// SetNX stores the given value for the given key, but only if the key doesn't exist yet (or has expired).
+// It returns false (and no error) if the key already exists.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (s Store) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.m[k]; found && !s.isExpired(k, time.Now()) {
 		return false, nil
 	}
+	s.m[k] = data
+	delete(s.expiries, k)
+	s.publish(gokv.EventSet, k, data)
+	return true, nil
+}
 
-	return true, s.codec.Unmarshal(data, v)
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (s Store) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	_, found = s.get(k)
+	return found, nil
 }
 
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (s Store) Delete(k string) error {
+	_, err := s.DeleteExisting(k)
+	return err
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// An expired key-value pair is deleted as well, but reported as non-existing.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (s Store) DeleteExisting(k string) (existed bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return false, err
 	}
 
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	if _, found := s.m[k]; !found {
+		return false, nil
+	}
+	expired := s.isExpired(k, time.Now())
 	delete(s.m, k)
+	delete(s.expiries, k)
+	s.publish(gokv.EventDelete, k, nil)
+	return !expired, nil
+}
+
+// Keys calls fn for each key in the store, except for expired ones.
+// The iteration stops when fn returns false.
+// It works on a snapshot of the keys, so fn can safely write to the store.
+// The order of the keys is random.
+func (s Store) Keys(fn func(k string) bool) error {
+	for _, k := range s.snapshotKeys() {
+		if !fn(k) {
+			break
+		}
+	}
 	return nil
 }
 
+// Iter returns an iterator over a snapshot of the keys in the store, except for expired ones.
+// The order of the keys is random.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	return util.NewKeySliceIterator(s.snapshotKeys()), nil
+}
+
+func (s Store) snapshotKeys() []string {
+	now := time.Now()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		if !s.isExpired(k, now) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Watch sends an event to the returned channel for each change of the given key,
+// until the context is canceled or the store is closed.
+// Expired key-value pairs lead to an EventDelete when they're deleted by the background goroutine.
+// The events' Revision is a counter that's incremented with each change of the store.
+// Events are queued for each watcher, so slow receivers don't block writes.
+// When more than util.DefaultMaxQueueLen events are queued, because they aren't received,
+// they're dropped and watching stops with an event whose Err is gokv.ErrEventsDropped.
+// The key must not be "".
+func (s Store) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return s.watchers.Subscribe(ctx, func(key string) bool {
+		return key == k
+	}), nil
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+func (s Store) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return s.watchers.Subscribe(ctx, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}), nil
+}
+
+// publish sends an event for the change to the watchers.
+// The caller must hold the write lock, so that the events are sent in the order of the changes.
+func (s Store) publish(eventType gokv.EventType, k string, data []byte) {
+	*s.revision++
+	if !s.watchers.HasSubscribers() {
+		return
+	}
+	event := gokv.Event{
+		Type:     eventType,
+		Key:      k,
+		Revision: *s.revision,
+	}
+	if eventType == gokv.EventSet {
+		event.Decode = func(v any) error {
+			return s.codec.Unmarshal(data, v)
+		}
+	}
+	s.watchers.Publish(k, event)
+}
+
+// isExpired returns true if the key-value pair has a TTL that has passed at the given time.
+// The caller must hold the lock.
+func (s Store) isExpired(k string, now time.Time) bool {
+	expiry, ok := s.expiries[k]
+	return ok && !now.Before(expiry)
+}
+
+// deleteExpired deletes all expired key-value pairs.
+// It's called periodically by the janitor.
+func (s Store) deleteExpired() {
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for k := range s.expiries {
+		if s.isExpired(k, now) {
+			delete(s.m, k)
+			delete(s.expiries, k)
+			s.publish(gokv.EventDelete, k, nil)
+		}
+	}
+}
+
 // Close closes the store.
-// When called, the store's internal Go map's entries are deleted.
+// When called, the store's internal Go map's entries are deleted,
+// the background goroutine that deletes expired key-value pairs is stopped
+// and the channels of all watchers are closed.
 func (s Store) Close() error {
+	s.janitor.Stop()
+	s.watchers.Close()
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	for k := range s.m {
 		delete(s.m, k)
 	}
+	for k := range s.expiries {
+		delete(s.expiries, k)
+	}
 	return nil
 }
 
 // Options are the options for the Go map store.
 type Options struct {
+	// Interval in which key-value pairs that were stored with SetWithTTL() and have expired are deleted.
+	// Expired key-value pairs are never returned, this is only about freeing their memory.
+	// Optional (1 minute by default).
+	CleanupInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
 }
 
 // DefaultOptions is an Options object with default values.
-// Codec: encoding.JSON
+// CleanupInterval: 1 minute, Codec: encoding.JSON
 var DefaultOptions = Options{
-	Codec: encoding.JSON,
+	CleanupInterval: time.Minute,
+	Codec:           encoding.JSON,
 }
 
 // NewStore creates a new Go map store.
@@ -100,13 +342,38 @@ var DefaultOptions = Options{
 // You should call the Close() method on the store when you're done working with it.
 func NewStore(options Options) Store {
 	// Set default options
+	if options.CleanupInterval <= 0 {
+		options.CleanupInterval = DefaultOptions.CleanupInterval
+	}
 	if options.Codec == nil {
 		options.Codec = DefaultOptions.Codec
 	}
 
-	return Store{
-		m:     make(map[string][]byte),
-		lock:  new(sync.RWMutex),
-		codec: options.Codec,
+	s := Store{
+		m:        make(map[string][]byte),
+		expiries: make(map[string]time.Time),
+		lock:     new(sync.RWMutex),
+		watchers: util.NewBroadcaster(util.DefaultMaxQueueLen, gokv.Event{Err: gokv.ErrEventsDropped}),
+		revision: new(uint64),
+		codec:    options.Codec,
+	}
+	s.janitor = util.NewJanitor(options.CleanupInterval, s.deleteExpired)
+	return s
+}
+
+func init() {
+	gokv.Register("memory", open)
+}
+
+// open creates a store from a URL like "memory://?cleanup_interval=1m&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		CleanupInterval: q.Duration("cleanup_interval"),
+		Codec:           q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
 	}
+	return NewStore(options), nil
 }
@@ -1,11 +1,15 @@
 package gomap_test
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/gomap"
 	"github.com/philippgille/gokv/test"
+	"github.com/philippgille/gokv/util"
 )
 
 // TestStore tests if reading from, writing to and deleting from the store works properly.
@@ -121,6 +125,110 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestContext tests if the store works as gokv.ContextStore via gokv.AsContextStore().
+func TestContext(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestContextStore(gokv.AsContextStore(store), t)
+}
+
+// TestIterable tests if the keys of the store can be iterated over.
+func TestIterable(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestIterable(store, t)
+}
+
+// TestBatchStore tests if the store works as gokv.BatchStore via gokv.AsBatchStore().
+func TestBatchStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestBatchStore(gokv.AsBatchStore(store), t)
+}
+
+// TestExpiringStore tests if key-value pairs that are stored with a TTL expire.
+func TestExpiringStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestExpiringStore(store, t)
+}
+
+// TestCreateOnlyStore tests if values are only stored when the key doesn't exist yet.
+func TestCreateOnlyStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestCreateOnlyStore(store, t)
+}
+
+// TestWatcher tests if events are sent for changes of watched keys.
+func TestWatcher(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestWatcher(store, t)
+}
+
+// TestWatcherOverflow tests if watching stops with gokv.ErrEventsDropped
+// when the events of a watcher that doesn't receive them can't be queued anymore.
+func TestWatcherOverflow(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := store.Watch(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The events that were already taken from the queue can be up to a full queue as well
+	for i := 0; i < 2*util.DefaultMaxQueueLen+1; i++ {
+		err = store.Set("foo", i)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var last gokv.Event
+	count := 0
+	for event := range events {
+		last = event
+		count++
+	}
+	if !errors.Is(last.Err, gokv.ErrEventsDropped) {
+		t.Errorf("Expected the last event to have the error gokv.ErrEventsDropped, but was: %v", last.Err)
+	}
+	if count > util.DefaultMaxQueueLen+1 {
+		t.Errorf("Expected at most %v events, but were %v", util.DefaultMaxQueueLen+1, count)
+	}
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestRawStore(store, t)
+}
+
+// TestDeleter tests if deleting reports whether the key-value pair existed.
+func TestDeleter(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestDeleter(store, t)
+}
+
+// TestExistenceChecker tests if the existence of keys is checked correctly.
+func TestExistenceChecker(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestExistenceChecker(store, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestTypedStore(store, t)
+}
+
+// TestOpen tests if the store can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	test.TestOpen("memory://?codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	store := createStore(t, encoding.JSON)
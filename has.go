@@ -0,0 +1,10 @@
+package gokv
+
+// ExistenceChecker is implemented by stores that can check whether a key exists
+// without retrieving and unmarshalling its value,
+// which is cheaper for large values, like S3 objects or DynamoDB items.
+type ExistenceChecker interface {
+	// Has returns true if a value is stored for the given key.
+	// The key must not be "".
+	Has(k string) (found bool, err error)
+}
@@ -1,4 +1,7 @@
 /*
 Package hazelcast contains an implementation of the `gokv.Store` interface for Hazelcast.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"hazelcast://localhost:5701/gokv?codec=gob" (the path is the MapName).
 */
 package hazelcast
@@ -2,11 +2,17 @@ package hazelcast
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	hazelcast "github.com/hazelcast/hazelcast-go-client"
+	"github.com/hazelcast/hazelcast-go-client/hzerrors"
 	"github.com/hazelcast/hazelcast-go-client/logger"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -23,6 +29,11 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to Hazelcast.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -33,14 +44,49 @@ func (c Client) Set(k string, v any) error {
 		return err
 	}
 
-	err = c.m.Set(context.Background(), k, data)
+	err = c.m.Set(ctx, k, data)
 	if err != nil {
+		return classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	err := c.m.Set(context.Background(), k, data)
+	if err != nil {
+		return classifyError(err)
+	}
+
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// Storing the key again with Set() applies the map's default TTL, which is infinite unless configured otherwise on the server.
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (c Client) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return classifyError(c.m.SetWithTTL(context.Background(), k, data, ttl))
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -48,13 +94,18 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to Hazelcast.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	hazelcastValue, err := c.m.Get(context.Background(), k)
+	hazelcastValue, err := c.m.Get(ctx, k)
 	if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 	// If no value was found return false
 	if hazelcastValue == nil {
@@ -68,22 +119,56 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	hazelcastValue, err := c.m.Get(context.Background(), k)
+	if err != nil {
+		return nil, false, classifyError(err)
+	}
+	// If no value was found return false
+	if hazelcastValue == nil {
+		return nil, false, nil
+	}
+	data, ok := hazelcastValue.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("the returned value for key %v was expected to be a slice of bytes, but was type: %T", k, hazelcastValue)
+	}
+
+	return data, true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // The key must not be longer than 250 bytes (this is a restriction of Hazelcast).
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to Hazelcast.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
-	return c.m.Delete(context.Background(), k)
+	return classifyError(c.m.Delete(ctx, k))
 }
 
 // Close closes the client.
 // This must be called to properly shut down connections and services (e.g. HeartBeatService).
 func (c Client) Close() error {
-	return c.c.Shutdown(context.Background())
+	return c.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but uses the given context for shutting down the client.
+func (c Client) CloseContext(ctx context.Context) error {
+	return c.c.Shutdown(ctx)
 }
 
 // Options are the options for the Hazelcast client.
@@ -129,12 +214,12 @@ func NewClient(options Options) (Client, error) {
 	config.Logger.Level = logger.OffLevel
 	client, err := hazelcast.StartNewClientWithConfig(context.Background(), config)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	hazelcastMap, err := client.GetMap(context.Background(), options.MapName)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	result.c = client
@@ -143,3 +228,44 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("hazelcast", open)
+}
+
+// open creates a client from a URL like "hazelcast://localhost:5701/gokv?codec=gob".
+// The URL path is the MapName.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Address: u.Host,
+		MapName: strings.Trim(u.Path, "/"),
+		Codec:   q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the Hazelcast client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	var retryableErr *hzerrors.RetryableError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, hzerrors.ErrOperationTimeout), errors.Is(err, hzerrors.ErrTimeout):
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case errors.Is(err, hzerrors.ErrClientOffline), errors.Is(err, hzerrors.ErrClientNotActive),
+		errors.Is(err, hzerrors.ErrHazelcastInstanceNotActive), errors.Is(err, hzerrors.ErrHazelcastOverLoad),
+		errors.As(err, &retryableErr):
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	case errors.Is(err, hzerrors.ErrConcurrentModification):
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return util.ClassifyError(err)
+}
@@ -120,6 +120,41 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestContext tests if the context-aware methods work properly.
+func TestContext(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestContextStore(client, t)
+}
+
+// TestExpiringStore tests if key-value pairs that are stored with a TTL expire.
+func TestExpiringStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestExpiringStore(client, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestRawStore(client, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestSentinelErrors(client, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestTypedStore(client, t)
+}
+
+// TestOpen tests if the client can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	test.TestOpen("hazelcast://localhost:5701/gokv?codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	client := createClient(t, encoding.JSON)
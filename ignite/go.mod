@@ -4,6 +4,7 @@ go 1.20
 
 require (
 	github.com/amsokol/ignite-go-client v0.12.2
+	github.com/philippgille/gokv v0.7.0
 	github.com/philippgille/gokv/encoding v0.7.0
 	github.com/philippgille/gokv/test v0.7.0
 	github.com/philippgille/gokv/util v0.7.0
@@ -12,5 +13,4 @@ require (
 require (
 	github.com/go-test/deep v1.1.0 // indirect
 	github.com/google/uuid v1.1.1 // indirect
-	github.com/philippgille/gokv v0.7.0 // indirect
 )
@@ -0,0 +1,10 @@
+/*
+Package instrument contains an implementation of the `gokv.Store` interface that records metrics
+of the operations of any other store.
+
+It records the number of operations, the number of errors, the latencies and the sizes of the marshalled values
+per store and operation, in a Recorder. The package contains a PrometheusRecorder, which serves the metrics
+in the Prometheus text exposition format, and an ExpvarRecorder, which publishes them via the expvar package.
+Other metrics systems can be supported by implementing the Recorder interface.
+*/
+package instrument
@@ -0,0 +1,86 @@
+package instrument
+
+import (
+	"errors"
+	"expvar"
+	"fmt"
+)
+
+// ExpvarRecorder is a Recorder that publishes the metrics as a variable of the expvar package,
+// so they're served as JSON by the expvar handler at "/debug/vars".
+//
+// The variable is an object with the store names as keys, each containing an object with the operations as keys,
+// like {"sessions": {"get": {"count": 3, "errors": 0, "durationSeconds": {...}, "valueSizeBytes": {...}}}}.
+// The histograms contain the cumulative counts per upper bound (including "+Inf"), the sum and the count
+// of the observations, like the ones of a PrometheusRecorder.
+type ExpvarRecorder struct {
+	metrics
+}
+
+// NewExpvarRecorder creates a new ExpvarRecorder and publishes its metrics as the expvar variable with the given name.
+// It returns an error if a variable with the name already exists,
+// because expvar variables can't be unpublished.
+func NewExpvarRecorder(name string, options RecorderOptions) (*ExpvarRecorder, error) {
+	if name == "" {
+		return nil, errors.New("the name of the expvar variable must not be empty")
+	}
+	if expvar.Get(name) != nil {
+		return nil, fmt.Errorf("an expvar variable with the name %q already exists", name)
+	}
+	m, err := newMetrics(options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExpvarRecorder{metrics: m}
+	expvar.Publish(name, expvar.Func(result.value))
+	return result, nil
+}
+
+type expvarSeries struct {
+	Count           uint64          `json:"count"`
+	Errors          uint64          `json:"errors"`
+	DurationSeconds expvarHistogram `json:"durationSeconds"`
+	ValueSizeBytes  expvarHistogram `json:"valueSizeBytes"`
+}
+
+type expvarHistogram struct {
+	Buckets map[string]uint64 `json:"buckets"`
+	Sum     float64           `json:"sum"`
+	Count   uint64            `json:"count"`
+}
+
+// value returns the value of the expvar variable, which is marshalled to JSON by the expvar package.
+func (r *ExpvarRecorder) value() any {
+	keys, series := r.snapshot()
+	result := make(map[string]map[string]expvarSeries)
+	for i, key := range keys {
+		operations, ok := result[key.store]
+		if !ok {
+			operations = make(map[string]expvarSeries)
+			result[key.store] = operations
+		}
+		operations[key.operation] = expvarSeries{
+			Count:           series[i].count,
+			Errors:          series[i].errors,
+			DurationSeconds: toExpvarHistogram(r.durationBuckets, series[i].durations),
+			ValueSizeBytes:  toExpvarHistogram(r.sizeBuckets, series[i].sizes),
+		}
+	}
+	return result
+}
+
+func toExpvarHistogram(buckets []float64, h histogram) expvarHistogram {
+	result := expvarHistogram{
+		Buckets: make(map[string]uint64, len(buckets)+1),
+		Sum:     h.sum,
+		Count:   h.count,
+	}
+	var cumulative uint64
+	for i, bound := range buckets {
+		cumulative += h.counts[i]
+		result.Buckets[formatFloat(bound)] = cumulative
+	}
+	result.Buckets["+Inf"] = h.count
+	return result
+}
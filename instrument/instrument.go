@@ -0,0 +1,159 @@
+package instrument
+
+import (
+	"errors"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/util"
+)
+
+// Names of the operations that are passed to the Recorder.
+const (
+	OpSet    = "set"
+	OpGet    = "get"
+	OpDelete = "delete"
+	OpClose  = "close"
+)
+
+// Recorder records the metrics of the operations of instrumented stores.
+// Its methods are called concurrently, so they must be safe for concurrent use.
+//
+// The package contains a PrometheusRecorder and an ExpvarRecorder,
+// but you can implement your own recorder for any other metrics system.
+type Recorder interface {
+	// RecordOperation is called after each operation of the store with the given name.
+	// The operation is one of the Op constants, err is the error that the operation returned.
+	// Get() calls that don't find a value aren't errors.
+	RecordOperation(store, operation string, duration time.Duration, err error)
+	// RecordValueSize is called with the size of the marshalled value after each successful Set()
+	// and each Get() that found a value.
+	RecordValueSize(store, operation string, size int)
+}
+
+// Store is a gokv.Store that records the number of operations, the number of errors,
+// the latencies and the value sizes of the operations of the wrapped store in a Recorder,
+// labeled with the name of the store.
+type Store struct {
+	store    gokv.Store
+	name     string
+	recorder Recorder
+	codec    encoding.Codec
+}
+
+// Set stores the given value for the given key and records the operation.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := s.store.Set(k, v)
+	s.recorder.RecordOperation(s.name, OpSet, time.Since(start), err)
+	if err != nil {
+		return err
+	}
+	s.recordValueSize(OpSet, v)
+	return nil
+}
+
+// Get retrieves the stored value for the given key and records the operation.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	found, err = s.store.Get(k, v)
+	s.recorder.RecordOperation(s.name, OpGet, time.Since(start), err)
+	if err != nil || !found {
+		return false, err
+	}
+	s.recordValueSize(OpGet, v)
+	return true, nil
+}
+
+// Delete deletes the stored value for the given key and records the operation.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := s.store.Delete(k)
+	s.recorder.RecordOperation(s.name, OpDelete, time.Since(start), err)
+	return err
+}
+
+// Close closes the wrapped store and records the operation.
+func (s Store) Close() error {
+	start := time.Now()
+	err := s.store.Close()
+	s.recorder.RecordOperation(s.name, OpClose, time.Since(start), err)
+	return err
+}
+
+// recordValueSize records the size of the value, marshalled with the codec, if there's one.
+func (s Store) recordValueSize(operation string, v any) {
+	if s.codec == nil {
+		return
+	}
+	if data, err := s.codec.Marshal(v); err == nil {
+		s.recorder.RecordValueSize(s.name, operation, len(data))
+	}
+}
+
+// Options are the options for the instrument store.
+type Options struct {
+	// The store whose operations are recorded.
+	// Required.
+	Store gokv.Store
+	// Name of the store, which the metrics are labeled with, like "sessions".
+	// Required.
+	Name string
+	// The recorder of the metrics, for example a PrometheusRecorder.
+	// Required.
+	Recorder Recorder
+	// The codec that the wrapped store uses, for recording the sizes of the values.
+	// The values are marshalled with it an additional time, only for measuring their size.
+	// Optional (nil by default, which means that the value sizes aren't recorded).
+	Codec encoding.Codec
+}
+
+// DefaultOptions is an Options object with default values.
+// Codec: nil
+var DefaultOptions = Options{
+	// No need to set Store, Name or Recorder because they're required and don't have a default,
+	// and no need to set Codec because its zero value is fine.
+}
+
+// NewStore creates a new instrument store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+	if options.Name == "" {
+		return result, errors.New("the Name in the options must not be empty")
+	}
+	if options.Recorder == nil {
+		return result, errors.New("the Recorder in the options must not be nil")
+	}
+
+	result.store = options.Store
+	result.name = options.Name
+	result.recorder = options.Recorder
+	result.codec = options.Codec
+
+	return result, nil
+}
@@ -0,0 +1,267 @@
+package instrument_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/instrument"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, gomap.NewStore(gomap.Options{Codec: encoding.JSON}), encoding.JSON)
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, gomap.NewStore(gomap.Options{Codec: encoding.Gob}), encoding.Gob)
+		test.TestStore(store, t)
+	})
+
+	// Test without a codec
+	t.Run("NoCodec", func(t *testing.T) {
+		store, _ := createStore(t, gomap.NewStore(gomap.DefaultOptions), nil)
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, gomap.NewStore(gomap.Options{Codec: encoding.JSON}), encoding.JSON)
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, gomap.NewStore(gomap.Options{Codec: encoding.Gob}), encoding.Gob)
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _ := createStore(t, gomap.NewStore(gomap.DefaultOptions), encoding.JSON)
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestRecording tests if the operations, errors and value sizes are passed to the recorder.
+func TestRecording(t *testing.T) {
+	t.Run("Codec", func(t *testing.T) {
+		recorder := &fakeRecorder{}
+		store, err := instrument.NewStore(instrument.Options{
+			Store:    gomap.NewStore(gomap.DefaultOptions),
+			Name:     "sessions",
+			Recorder: recorder,
+			Codec:    encoding.JSON,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Get("foo", new(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Get("bar", new(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Delete("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Fails to unmarshal the JSON string into an int
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.Get("foo", new(int))
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+
+		// The size of `"bar"` is 5
+		expected := []record{
+			{store: "sessions", operation: instrument.OpSet},
+			{store: "sessions", operation: instrument.OpSet, size: 5},
+			{store: "sessions", operation: instrument.OpGet},
+			{store: "sessions", operation: instrument.OpGet, size: 5},
+			{store: "sessions", operation: instrument.OpGet},
+			{store: "sessions", operation: instrument.OpDelete},
+			{store: "sessions", operation: instrument.OpSet},
+			{store: "sessions", operation: instrument.OpSet, size: 5},
+			{store: "sessions", operation: instrument.OpGet, failed: true},
+		}
+		checkRecords(t, recorder, expected)
+	})
+
+	// Without a codec no value sizes are recorded
+	recorder := &fakeRecorder{}
+	store, err := instrument.NewStore(instrument.Options{
+		Store:    gomap.NewStore(gomap.DefaultOptions),
+		Name:     "sessions",
+		Recorder: recorder,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, recorder, []record{
+		{store: "sessions", operation: instrument.OpSet},
+		{store: "sessions", operation: instrument.OpGet},
+	})
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store, recorder := createStore(t, gomap.NewStore(gomap.DefaultOptions), encoding.JSON)
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	// Invalid arguments don't reach the wrapped store, so they're not recorded
+	checkRecords(t, recorder, nil)
+
+	// Test errors of the wrapped store
+	store, recorder = createStore(t, test.FailingStore{Err: errFailing}, nil)
+	err = store.Set("foo", "bar")
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	err = store.Close()
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	checkRecords(t, recorder, []record{
+		{store: "test", operation: instrument.OpSet, failed: true},
+		{store: "test", operation: instrument.OpClose, failed: true},
+	})
+
+	// Test invalid options
+	invalidOptions := []instrument.Options{
+		{Name: "test", Recorder: &fakeRecorder{}},
+		{Store: gomap.NewStore(gomap.DefaultOptions), Recorder: &fakeRecorder{}},
+		{Store: gomap.NewStore(gomap.DefaultOptions), Name: "test"},
+	}
+	for _, options := range invalidOptions {
+		_, err = instrument.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store, _ := createStore(t, gomap.NewStore(gomap.DefaultOptions), encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store, _ := createStore(t, gomap.NewStore(gomap.DefaultOptions), encoding.JSON)
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store, _ := createStore(t, gomap.NewStore(gomap.DefaultOptions), encoding.JSON)
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// createStore creates an instrument store with the name "test" for the wrapped store,
+// with a fakeRecorder, which is returned as well.
+func createStore(t *testing.T, wrapped gokv.Store, codec encoding.Codec) (instrument.Store, *fakeRecorder) {
+	recorder := &fakeRecorder{}
+	options := instrument.DefaultOptions
+	options.Store = wrapped
+	options.Name = "test"
+	options.Recorder = recorder
+	options.Codec = codec
+	store, err := instrument.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, recorder
+}
+
+// record is a call of a fakeRecorder method. Calls of RecordValueSize() have a size.
+type record struct {
+	store     string
+	operation string
+	failed    bool
+	size      int
+}
+
+// fakeRecorder is an instrument.Recorder that stores all calls.
+type fakeRecorder struct {
+	records []record
+	lock    sync.Mutex
+}
+
+func (r *fakeRecorder) RecordOperation(store, operation string, duration time.Duration, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = append(r.records, record{store: store, operation: operation, failed: err != nil})
+}
+
+func (r *fakeRecorder) RecordValueSize(store, operation string, size int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.records = append(r.records, record{store: store, operation: operation, size: size})
+}
+
+func checkRecords(t *testing.T, recorder *fakeRecorder, expected []record) {
+	t.Helper()
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	if len(recorder.records) != len(expected) {
+		t.Fatalf("Expected the records %+v, but was %+v", expected, recorder.records)
+	}
+	for i := range expected {
+		if recorder.records[i] != expected[i] {
+			t.Errorf("Expected the records %+v, but was %+v", expected, recorder.records)
+			break
+		}
+	}
+}
+
+var errFailing = errors.New("the store is failing")
@@ -0,0 +1,148 @@
+package instrument
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the default upper bounds of the latency histogram buckets, in seconds.
+var DefaultDurationBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the default upper bounds of the value size histogram buckets, in bytes.
+var DefaultSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+// RecorderOptions are the options for the recorders in this package.
+type RecorderOptions struct {
+	// Upper bounds of the latency histogram buckets, in seconds, in increasing order.
+	// Optional (DefaultDurationBuckets by default).
+	DurationBuckets []float64
+	// Upper bounds of the value size histogram buckets, in bytes, in increasing order.
+	// Optional (DefaultSizeBuckets by default).
+	SizeBuckets []float64
+}
+
+// DefaultRecorderOptions is a RecorderOptions object with default values.
+// DurationBuckets: DefaultDurationBuckets, SizeBuckets: DefaultSizeBuckets
+var DefaultRecorderOptions = RecorderOptions{
+	DurationBuckets: DefaultDurationBuckets,
+	SizeBuckets:     DefaultSizeBuckets,
+}
+
+// metrics holds the metrics of all stores and operations.
+// It's the common base of the recorders in this package, which only differ in how they expose the metrics.
+type metrics struct {
+	durationBuckets []float64
+	sizeBuckets     []float64
+	series          map[seriesKey]*series
+	lock            *sync.Mutex
+}
+
+type seriesKey struct {
+	store     string
+	operation string
+}
+
+// series holds the metrics of one operation of one store.
+type series struct {
+	count     uint64
+	errors    uint64
+	durations histogram
+	sizes     histogram
+}
+
+// histogram counts the observations per bucket, like a Prometheus histogram.
+// The counts are not cumulative, the last one is for the observations that are greater than all upper bounds.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newMetrics(options RecorderOptions) (metrics, error) {
+	if options.DurationBuckets == nil {
+		options.DurationBuckets = DefaultRecorderOptions.DurationBuckets
+	}
+	if options.SizeBuckets == nil {
+		options.SizeBuckets = DefaultRecorderOptions.SizeBuckets
+	}
+	if !sort.Float64sAreSorted(options.DurationBuckets) || !sort.Float64sAreSorted(options.SizeBuckets) {
+		return metrics{}, errors.New("the buckets in the options must be in increasing order")
+	}
+
+	return metrics{
+		durationBuckets: options.DurationBuckets,
+		sizeBuckets:     options.SizeBuckets,
+		series:          make(map[seriesKey]*series),
+		lock:            new(sync.Mutex),
+	}, nil
+}
+
+// RecordOperation increments the operation and error counters and observes the duration.
+func (m metrics) RecordOperation(store, operation string, duration time.Duration, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	s := m.get(store, operation)
+	s.count++
+	if err != nil {
+		s.errors++
+	}
+	s.durations.observe(m.durationBuckets, duration.Seconds())
+}
+
+// RecordValueSize observes the size.
+func (m metrics) RecordValueSize(store, operation string, size int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.get(store, operation).sizes.observe(m.sizeBuckets, float64(size))
+}
+
+// get returns the series for the store and operation, creating it if it doesn't exist yet.
+// The lock must be held.
+func (m metrics) get(store, operation string) *series {
+	key := seriesKey{store: store, operation: operation}
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{
+			durations: histogram{counts: make([]uint64, len(m.durationBuckets)+1)},
+			sizes:     histogram{counts: make([]uint64, len(m.sizeBuckets)+1)},
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// snapshot returns copies of all series, sorted by store and operation.
+func (m metrics) snapshot() ([]seriesKey, []series) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	keys := make([]seriesKey, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].store != keys[j].store {
+			return keys[i].store < keys[j].store
+		}
+		return keys[i].operation < keys[j].operation
+	})
+	result := make([]series, 0, len(keys))
+	for _, key := range keys {
+		s := *m.series[key]
+		s.durations.counts = append([]uint64(nil), s.durations.counts...)
+		s.sizes.counts = append([]uint64(nil), s.sizes.counts...)
+		result = append(result, s)
+	}
+	return keys, result
+}
+
+func (h *histogram) observe(buckets []float64, value float64) {
+	i := sort.SearchFloat64s(buckets, value)
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
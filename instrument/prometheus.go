@@ -0,0 +1,96 @@
+package instrument
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrometheusRecorder is a Recorder that exposes the metrics in the Prometheus text exposition format.
+// It's an http.Handler, so you can serve the metrics for a Prometheus server to scrape, for example with
+// http.Handle("/metrics", recorder).
+//
+// It exposes the following metrics, all labeled with "store" and "operation":
+//
+//   - gokv_operations_total: Counter of the operations
+//   - gokv_operation_errors_total: Counter of the operations that returned an error
+//   - gokv_operation_duration_seconds: Histogram of the latencies of the operations
+//   - gokv_value_size_bytes: Histogram of the sizes of the marshalled values
+//
+// It doesn't depend on the Prometheus client library. If you already use it,
+// implement the Recorder interface with its collectors instead.
+type PrometheusRecorder struct {
+	metrics
+}
+
+// NewPrometheusRecorder creates a new PrometheusRecorder.
+func NewPrometheusRecorder(options RecorderOptions) (*PrometheusRecorder, error) {
+	m, err := newMetrics(options)
+	if err != nil {
+		return nil, err
+	}
+	return &PrometheusRecorder{metrics: m}, nil
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (r *PrometheusRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = r.WriteMetrics(w)
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format to w.
+func (r *PrometheusRecorder) WriteMetrics(w io.Writer) error {
+	keys, series := r.snapshot()
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP gokv_operations_total Number of operations of the store.")
+	fmt.Fprintln(bw, "# TYPE gokv_operations_total counter")
+	for i, key := range keys {
+		fmt.Fprintf(bw, "gokv_operations_total{%s} %d\n", labels(key), series[i].count)
+	}
+	fmt.Fprintln(bw, "# HELP gokv_operation_errors_total Number of operations of the store that returned an error.")
+	fmt.Fprintln(bw, "# TYPE gokv_operation_errors_total counter")
+	for i, key := range keys {
+		fmt.Fprintf(bw, "gokv_operation_errors_total{%s} %d\n", labels(key), series[i].errors)
+	}
+	fmt.Fprintln(bw, "# HELP gokv_operation_duration_seconds Latency of the operations of the store.")
+	fmt.Fprintln(bw, "# TYPE gokv_operation_duration_seconds histogram")
+	for i, key := range keys {
+		writeHistogram(bw, "gokv_operation_duration_seconds", labels(key), r.durationBuckets, series[i].durations)
+	}
+	fmt.Fprintln(bw, "# HELP gokv_value_size_bytes Size of the marshalled values.")
+	fmt.Fprintln(bw, "# TYPE gokv_value_size_bytes histogram")
+	for i, key := range keys {
+		// Operations without values (like Delete()) have no size histogram
+		if series[i].sizes.count > 0 {
+			writeHistogram(bw, "gokv_value_size_bytes", labels(key), r.sizeBuckets, series[i].sizes)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func writeHistogram(w io.Writer, name, labels string, buckets []float64, h histogram) {
+	var cumulative uint64
+	for i, bound := range buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(key seriesKey) string {
+	return fmt.Sprintf(`store="%s",operation="%s"`,
+		labelValueReplacer.Replace(key.store), labelValueReplacer.Replace(key.operation))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,135 @@
+package instrument_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv/instrument"
+)
+
+// TestPrometheusRecorder tests if the metrics are exposed in the Prometheus text exposition format.
+func TestPrometheusRecorder(t *testing.T) {
+	recorder, err := instrument.NewPrometheusRecorder(instrument.RecorderOptions{
+		DurationBuckets: []float64{0.01, 0.1},
+		SizeBuckets:     []float64{10, 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordOperations(recorder)
+
+	res := httptest.NewRecorder()
+	recorder.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := res.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the content type of the text exposition format, but was %q", contentType)
+	}
+	body := res.Body.String()
+
+	expectedLines := []string{
+		"# TYPE gokv_operations_total counter",
+		`gokv_operations_total{store="sessions",operation="get"} 3`,
+		`gokv_operations_total{store="sessions",operation="set"} 1`,
+		`gokv_operations_total{store="users \"v2\"",operation="delete"} 1`,
+		"# TYPE gokv_operation_errors_total counter",
+		`gokv_operation_errors_total{store="sessions",operation="get"} 1`,
+		`gokv_operation_errors_total{store="sessions",operation="set"} 0`,
+		"# TYPE gokv_operation_duration_seconds histogram",
+		`gokv_operation_duration_seconds_bucket{store="sessions",operation="get",le="0.01"} 1`,
+		`gokv_operation_duration_seconds_bucket{store="sessions",operation="get",le="0.1"} 2`,
+		`gokv_operation_duration_seconds_bucket{store="sessions",operation="get",le="+Inf"} 3`,
+		`gokv_operation_duration_seconds_sum{store="sessions",operation="get"} 1.055`,
+		`gokv_operation_duration_seconds_count{store="sessions",operation="get"} 3`,
+		"# TYPE gokv_value_size_bytes histogram",
+		`gokv_value_size_bytes_bucket{store="sessions",operation="set",le="10"} 0`,
+		`gokv_value_size_bytes_bucket{store="sessions",operation="set",le="100"} 1`,
+		`gokv_value_size_bytes_bucket{store="sessions",operation="set",le="+Inf"} 1`,
+		`gokv_value_size_bytes_sum{store="sessions",operation="set"} 42`,
+		`gokv_value_size_bytes_count{store="sessions",operation="set"} 1`,
+	}
+	for _, line := range expectedLines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected the line %q in the response body:\n%s", line, body)
+		}
+	}
+	// Operations without values have no size histogram
+	if strings.Contains(body, `gokv_value_size_bytes_count{store="users \"v2\""`) {
+		t.Errorf("Expected no size histogram for the delete operation:\n%s", body)
+	}
+
+	// Test invalid options
+	_, err = instrument.NewPrometheusRecorder(instrument.RecorderOptions{DurationBuckets: []float64{1, 0.1}})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestExpvarRecorder tests if the metrics are published as expvar variable.
+func TestExpvarRecorder(t *testing.T) {
+	recorder, err := instrument.NewExpvarRecorder("gokv_test", instrument.RecorderOptions{
+		DurationBuckets: []float64{0.01, 0.1},
+		SizeBuckets:     []float64{10, 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordOperations(recorder)
+
+	type histogram struct {
+		Buckets map[string]uint64
+		Sum     float64
+		Count   uint64
+	}
+	type series struct {
+		Count           uint64
+		Errors          uint64
+		DurationSeconds histogram
+		ValueSizeBytes  histogram
+	}
+	actual := map[string]map[string]series{}
+	err = json.Unmarshal([]byte(expvar.Get("gokv_test").String()), &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := actual["sessions"]["get"]
+	if get.Count != 3 || get.Errors != 1 {
+		t.Errorf("Expected 3 operations and 1 error, but was %+v", get)
+	}
+	expectedBuckets := map[string]uint64{"0.01": 1, "0.1": 2, "+Inf": 3}
+	for bound, count := range expectedBuckets {
+		if get.DurationSeconds.Buckets[bound] != count {
+			t.Errorf("Expected the duration buckets %v, but was %v", expectedBuckets, get.DurationSeconds.Buckets)
+			break
+		}
+	}
+	set := actual["sessions"]["set"]
+	if set.ValueSizeBytes.Count != 1 || set.ValueSizeBytes.Sum != 42 || set.ValueSizeBytes.Buckets["100"] != 1 {
+		t.Errorf("Expected one value size of 42 bytes, but was %+v", set.ValueSizeBytes)
+	}
+	if actual[`users "v2"`]["delete"].Count != 1 {
+		t.Errorf("Expected 1 delete operation, but was %+v", actual)
+	}
+
+	// Test invalid names
+	_, err = instrument.NewExpvarRecorder("gokv_test", instrument.DefaultRecorderOptions)
+	if err == nil {
+		t.Error("Expected an error for an existing name")
+	}
+	_, err = instrument.NewExpvarRecorder("", instrument.DefaultRecorderOptions)
+	if err == nil {
+		t.Error("Expected an error for an empty name")
+	}
+}
+
+func recordOperations(recorder instrument.Recorder) {
+	recorder.RecordOperation("sessions", instrument.OpSet, 5*time.Millisecond, nil)
+	recorder.RecordValueSize("sessions", instrument.OpSet, 42)
+	recorder.RecordOperation("sessions", instrument.OpGet, 5*time.Millisecond, nil)
+	recorder.RecordOperation("sessions", instrument.OpGet, 50*time.Millisecond, nil)
+	recorder.RecordOperation("sessions", instrument.OpGet, time.Second, errFailing)
+	recorder.RecordOperation(`users "v2"`, instrument.OpDelete, time.Millisecond, nil)
+}
@@ -0,0 +1,42 @@
+package gokv
+
+// Iterable is implemented by stores whose keys can be enumerated.
+// It's meant for admin tooling, reindexing and debugging,
+// so don't expect it to be fast for stores with lots of key-value pairs.
+//
+// The order of the keys depends on the implementation.
+// Whether changes that are made to the store during an iteration are reflected in it
+// also depends on the implementation. Some implementations (like bbolt) iterate
+// within a read transaction and don't allow writing to the store until the iteration is done.
+type Iterable interface {
+	// Keys calls fn for each key in the store.
+	// The iteration stops when fn returns false.
+	Keys(fn func(k string) bool) error
+	// Iter returns a cursor-style iterator over the keys in the store.
+	// The iterator must be closed when it's not needed anymore.
+	Iter() (KeyIterator, error)
+}
+
+// KeyIterator is a cursor over the keys of a store.
+//
+// Usage:
+//
+//	iter, err := store.Iter()
+//	if err != nil { ... }
+//	defer iter.Close()
+//	for iter.Next() {
+//		fmt.Println(iter.Key())
+//	}
+//	if err := iter.Err(); err != nil { ... }
+type KeyIterator interface {
+	// Next advances the iterator to the next key.
+	// It must be called before the first call to Key().
+	// It returns false when there are no more keys or when an error occurred.
+	Next() bool
+	// Key returns the key that the iterator currently points to.
+	Key() string
+	// Err returns the error that occurred during the iteration, if any.
+	Err() error
+	// Close releases the resources of the iterator.
+	Close() error
+}
@@ -1,4 +1,7 @@
 /*
 Package leveldb contains an implementation of the `gokv.Store` interface for LevelDB.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"leveldb:///tmp/leveldb?write_sync=true&codec=gob" (or "leveldb://leveldb" for a relative path).
 */
 package leveldb
@@ -2,8 +2,12 @@ package leveldb
 
 import (
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	leveldbutil "github.com/syndtr/goleveldb/leveldb/util"
+	"net/url"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -29,13 +33,17 @@ func (s Store) Set(k string, v any) error {
 		return err
 	}
 
-	var writeOptions *opt.WriteOptions
-	if s.writeSync {
-		writeOptions = &opt.WriteOptions{
-			Sync: true,
-		}
+	return s.db.Put([]byte(k), data, s.writeOptions())
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
 	}
-	return s.db.Put([]byte(k), data, writeOptions)
+
+	return s.db.Put([]byte(k), data, s.writeOptions())
 }
 
 // Get retrieves the stored value for the given key.
@@ -60,6 +68,68 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	return true, s.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err = s.db.Get([]byte(k), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
+
+// SetNX stores the given value for the given key, but only if the key doesn't exist yet.
+// The check and the write happen in a LevelDB transaction, which blocks other writes in the meantime.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	tr, err := s.db.OpenTransaction()
+	if err != nil {
+		return false, err
+	}
+	exists, err := tr.Has([]byte(k), nil)
+	if err != nil || exists {
+		tr.Discard()
+		return false, err
+	}
+	if err := tr.Put([]byte(k), data, s.writeOptions()); err != nil {
+		tr.Discard()
+		return false, err
+	}
+	if err := tr.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (s Store) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	return s.db.Has([]byte(k), nil)
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
@@ -68,13 +138,230 @@ func (s Store) Delete(k string) error {
 		return err
 	}
 
-	var writeOptions *opt.WriteOptions
-	if s.writeSync {
-		writeOptions = &opt.WriteOptions{
-			Sync: true,
+	return s.db.Delete([]byte(k), s.writeOptions())
+}
+
+// SetMulti stores all given key-value pairs atomically with a single LevelDB batch.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, s.codec.Marshal)
+	if err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for k, v := range data {
+		batch.Put([]byte(k), v)
+	}
+	return s.db.Write(batch, s.writeOptions())
+}
+
+// GetMulti retrieves the stored values for the given keys from a consistent snapshot of the DB.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be "".
+func (s Store) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	result := make(map[string]any, len(keys))
+	for _, k := range keys {
+		data, err := snapshot.Get([]byte(k), nil)
+		if err == leveldb.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		v := newValue()
+		if err := s.codec.Unmarshal(data, v); err != nil {
+			return nil, err
 		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys atomically with a single LevelDB batch.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for _, k := range keys {
+		batch.Delete([]byte(k))
+	}
+	return s.db.Write(batch, s.writeOptions())
+}
+
+// writeOptions returns the write options according to the WriteSync option.
+func (s Store) writeOptions() *opt.WriteOptions {
+	if !s.writeSync {
+		return nil
+	}
+	return &opt.WriteOptions{
+		Sync: true,
 	}
-	return s.db.Delete([]byte(k), writeOptions)
+}
+
+// Keys calls fn for each key in the store, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The iteration works on an implicit snapshot of the DB,
+// so changes that fn makes to the store aren't reflected in the iteration.
+func (s Store) Keys(fn func(k string) bool) error {
+	iter := s.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if !fn(string(iter.Key())) {
+			break
+		}
+	}
+	return iter.Error()
+}
+
+// Iter returns an iterator over the keys in the store, in byte-sorted order.
+// The iterator works on an implicit snapshot of the DB,
+// so it doesn't see changes that are made after its creation.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	return keyIterator{s.db.NewIterator(nil, nil)}, nil
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The scan works on an implicit snapshot of the DB,
+// so changes that fn makes to the store aren't reflected in it.
+func (s Store) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	iter := s.db.NewIterator(leveldbutil.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		// The value is only valid until the next call of Next(), which is the case while fn runs.
+		data := iter.Value()
+		decode := func(v any) error {
+			return s.codec.Unmarshal(data, v)
+		}
+		if !fn(string(iter.Key()), decode) {
+			break
+		}
+	}
+	return iter.Error()
+}
+
+// keyIterator is a gokv.KeyIterator based on a LevelDB iterator.
+type keyIterator struct {
+	iter iterator.Iterator
+}
+
+func (i keyIterator) Next() bool {
+	return i.iter.Next()
+}
+
+func (i keyIterator) Key() string {
+	return string(i.iter.Key())
+}
+
+func (i keyIterator) Err() error {
+	return i.iter.Error()
+}
+
+func (i keyIterator) Close() error {
+	i.iter.Release()
+	return i.iter.Error()
+}
+
+// Update executes the given function within a LevelDB transaction.
+// If the function returns nil, the transaction is committed, otherwise it's discarded.
+// Only one transaction is allowed at a time, and other writes are blocked in the meantime.
+func (s Store) Update(fn func(tx gokv.Tx) error) error {
+	tr, err := s.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+	err = fn(storeTx{
+		r:         tr,
+		tr:        tr,
+		writeOpts: s.writeOptions(),
+		codec:     s.codec,
+	})
+	if err != nil {
+		tr.Discard()
+		return err
+	}
+	return tr.Commit()
+}
+
+// View executes the given function on a snapshot of the DB.
+func (s Store) View(fn func(tx gokv.Tx) error) error {
+	snapshot, err := s.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+	return fn(storeTx{
+		r:     snapshot,
+		codec: s.codec,
+	})
+}
+
+// storeTx is a gokv.Tx for a LevelDB transaction or snapshot.
+type storeTx struct {
+	// Either the transaction or the snapshot
+	r interface {
+		Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	}
+	// nil for snapshots, which are read-only
+	tr        *leveldb.Transaction
+	writeOpts *opt.WriteOptions
+	codec     encoding.Codec
+}
+
+func (t storeTx) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if t.tr == nil {
+		return gokv.ErrReadOnlyTx
+	}
+
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.tr.Put([]byte(k), data, t.writeOpts)
+}
+
+func (t storeTx) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := t.r.Get([]byte(k), nil)
+	if err == leveldb.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, t.codec.Unmarshal(data, v)
+}
+
+func (t storeTx) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	if t.tr == nil {
+		return gokv.ErrReadOnlyTx
+	}
+
+	return t.tr.Delete([]byte(k), t.writeOpts)
 }
 
 // Close closes the store.
@@ -134,3 +421,26 @@ func NewStore(options Options) (Store, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("leveldb", open)
+}
+
+// open creates a store from a URL like "leveldb:///tmp/leveldb?write_sync=true&codec=gob",
+// see util.URLPath() for relative paths.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Path:      util.URLPath(u),
+		WriteSync: q.Bool("write_sync"),
+		Codec:     q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(options)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
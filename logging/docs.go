@@ -0,0 +1,10 @@
+/*
+Package logging contains an implementation of the `gokv.Store` interface that logs the operations
+of any other store with a `log/slog` logger.
+
+The level can be configured per operation, operations that take longer than a threshold can be logged
+at a higher level, and keys can be logged as they are, hashed or redacted.
+Failed operations are logged with the error and the Go type of the value,
+which helps finding values that can't be marshalled.
+*/
+package logging
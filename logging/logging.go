@@ -0,0 +1,250 @@
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/util"
+)
+
+// Names of the operations, which are the keys of Options.Levels and the values of the "operation" attribute.
+const (
+	OpSet    = "Set"
+	OpGet    = "Get"
+	OpDelete = "Delete"
+	OpClose  = "Close"
+)
+
+// KeyMode defines how keys are logged.
+type KeyMode int
+
+const (
+	// KeyPlain logs the keys as they are.
+	KeyPlain KeyMode = iota
+	// KeyHashed logs a hex encoded SHA-256 hash of the keys,
+	// so log entries of the same key can be correlated without revealing it.
+	KeyHashed
+	// KeyRedacted logs "[redacted]" instead of the keys.
+	KeyRedacted
+)
+
+// Store is a gokv.Store that logs the operations of the wrapped store with a slog.Logger.
+//
+// Each operation is logged with the message "gokv operation" at the level that's configured for it
+// and with the attributes "operation", "key" (see KeyMode), "duration" and, for Get(), "found".
+// Operations that take longer than the SlowThreshold are logged with the message "gokv slow operation"
+// at the SlowLevel instead. Failed operations are logged with the message "gokv operation failed"
+// at the error level, with the additional attributes "error" and, for Set() and Get(), "type",
+// which is the Go type of the value. With a Codec, values that can't be marshalled
+// are logged with the message "gokv marshalling failed" at the error level.
+type Store struct {
+	store         gokv.Store
+	logger        *slog.Logger
+	levels        map[string]slog.Level
+	slowThreshold time.Duration
+	slowLevel     slog.Level
+	keyMode       KeyMode
+	codec         encoding.Codec
+}
+
+// Set stores the given value for the given key and logs the operation.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	// With a codec the value is marshalled here, so marshalling errors can be told apart from errors of the store.
+	if s.codec != nil {
+		if _, err := s.codec.Marshal(v); err != nil {
+			s.logger.LogAttrs(context.Background(), slog.LevelError, "gokv marshalling failed",
+				slog.String("operation", OpSet), s.keyAttr(k), slog.String("type", fmt.Sprintf("%T", v)), slog.Any("error", err))
+			return err
+		}
+	}
+
+	start := time.Now()
+	err := s.store.Set(k, v)
+	s.log(OpSet, k, v, time.Since(start), err)
+	return err
+}
+
+// Get retrieves the stored value for the given key and logs the operation.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	found, err = s.store.Get(k, v)
+	s.log(OpGet, k, v, time.Since(start), err, slog.Bool("found", found))
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// Delete deletes the stored value for the given key and logs the operation.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := s.store.Delete(k)
+	s.log(OpDelete, k, nil, time.Since(start), err)
+	return err
+}
+
+// Close closes the wrapped store and logs the operation.
+func (s Store) Close() error {
+	start := time.Now()
+	err := s.store.Close()
+	s.log(OpClose, "", nil, time.Since(start), err)
+	return err
+}
+
+// log logs the operation, depending on its error and duration.
+// The key is only logged if it's not "" and the type of the value if it's not nil.
+func (s Store) log(operation, k string, v any, duration time.Duration, err error, attrs ...slog.Attr) {
+	msg := "gokv operation"
+	level, ok := s.levels[operation]
+	if !ok {
+		level = DefaultLevel
+	}
+	if err != nil {
+		msg = "gokv operation failed"
+		level = slog.LevelError
+	} else if s.slowThreshold > 0 && duration > s.slowThreshold {
+		msg = "gokv slow operation"
+		level = s.slowLevel
+	}
+
+	ctx := context.Background()
+	// Don't compute the attributes (like the hash of the key) if they're not logged anyway
+	if !s.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{slog.String("operation", operation)}, attrs...)
+	if k != "" {
+		attrs = append(attrs, s.keyAttr(k))
+	}
+	attrs = append(attrs, slog.Duration("duration", duration))
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+		if v != nil {
+			attrs = append(attrs, slog.String("type", fmt.Sprintf("%T", v)))
+		}
+	}
+	s.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (s Store) keyAttr(k string) slog.Attr {
+	switch s.keyMode {
+	case KeyHashed:
+		hash := sha256.Sum256([]byte(k))
+		return slog.String("key", hex.EncodeToString(hash[:]))
+	case KeyRedacted:
+		return slog.String("key", "[redacted]")
+	default:
+		return slog.String("key", k)
+	}
+}
+
+// DefaultLevel is the level of the operations that have no level in Options.Levels.
+const DefaultLevel = slog.LevelDebug
+
+// Options are the options for the logging store.
+type Options struct {
+	// The store whose operations are logged.
+	// Required.
+	Store gokv.Store
+	// The logger. Use Logger.With() for attributes that should be added to all log entries,
+	// like the name of the store.
+	// Optional (slog.Default() by default).
+	Logger *slog.Logger
+	// The levels of the successful operations, with the Op constants as keys.
+	// Failed operations are always logged at the error level.
+	// Optional (DefaultLevel for the operations that aren't in the map).
+	Levels map[string]slog.Level
+	// Operations that take longer are logged at the SlowLevel instead of their regular level.
+	// Optional (0 by default, which means slow operations aren't logged differently).
+	SlowThreshold time.Duration
+	// The level of operations that take longer than the SlowThreshold.
+	// Optional (slog.LevelWarn by default).
+	SlowLevel *slog.Level
+	// How keys are logged.
+	// Optional (KeyPlain by default).
+	KeyMode KeyMode
+	// The codec that the wrapped store uses, so that errors of marshalling values can be logged
+	// separately from errors of the store, with the Go type of the value.
+	// The values are marshalled with it an additional time before calling Set().
+	// Optional (nil by default, which means that marshalling errors are logged like other errors of Set()).
+	Codec encoding.Codec
+}
+
+var defaultSlowLevel = slog.LevelWarn
+
+// DefaultOptions is an Options object with default values.
+// Logger: slog.Default(), SlowThreshold: 0, SlowLevel: slog.LevelWarn, KeyMode: KeyPlain, Codec: nil
+var DefaultOptions = Options{
+	SlowLevel: &defaultSlowLevel,
+	KeyMode:   KeyPlain,
+	// No need to set Store because it's required and doesn't have a default,
+	// and no need to set the other fields because their zero values are fine.
+	// The default Logger is looked up in NewStore(), because it can change.
+}
+
+// NewStore creates a new logging store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+	if options.KeyMode < KeyPlain || options.KeyMode > KeyRedacted {
+		return result, fmt.Errorf("invalid KeyMode %d", options.KeyMode)
+	}
+	for operation := range options.Levels {
+		switch operation {
+		case OpSet, OpGet, OpDelete, OpClose:
+		default:
+			return result, fmt.Errorf("invalid operation %q in the Levels", operation)
+		}
+	}
+
+	// Set default values
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+	if options.SlowLevel == nil {
+		options.SlowLevel = DefaultOptions.SlowLevel
+	}
+
+	result.store = options.Store
+	result.logger = options.Logger
+	result.levels = make(map[string]slog.Level, len(options.Levels))
+	for operation, level := range options.Levels {
+		result.levels[operation] = level
+	}
+	result.slowThreshold = options.SlowThreshold
+	result.slowLevel = *options.SlowLevel
+	result.keyMode = options.KeyMode
+	result.codec = options.Codec
+
+	return result, nil
+}
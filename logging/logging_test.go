@@ -0,0 +1,353 @@
+package logging_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/logging"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, logging.Options{Codec: encoding.JSON}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, logging.Options{Codec: encoding.Gob}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestStore(store, t)
+	})
+
+	// Test without a codec
+	t.Run("NoCodec", func(t *testing.T) {
+		store, _ := createStore(t, logging.Options{}, gomap.NewStore(gomap.DefaultOptions))
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, logging.Options{Codec: encoding.JSON}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, logging.Options{Codec: encoding.Gob}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _ := createStore(t, logging.Options{Codec: encoding.JSON}, gomap.NewStore(gomap.DefaultOptions))
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestLogging tests if the operations are logged with the expected levels, messages and attributes.
+func TestLogging(t *testing.T) {
+	store, handler := createStore(t, logging.Options{}, gomap.NewStore(gomap.DefaultOptions))
+
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("bar", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, handler, []entry{
+		{slog.LevelDebug, "gokv operation", map[string]any{"operation": "Set", "key": "foo"}},
+		{slog.LevelDebug, "gokv operation", map[string]any{"operation": "Get", "key": "foo", "found": true}},
+		{slog.LevelDebug, "gokv operation", map[string]any{"operation": "Get", "key": "bar", "found": false}},
+		{slog.LevelDebug, "gokv operation", map[string]any{"operation": "Delete", "key": "foo"}},
+		{slog.LevelDebug, "gokv operation", map[string]any{"operation": "Close"}},
+	})
+	for _, e := range handler.entries {
+		if _, ok := e.attrs["duration"].(time.Duration); !ok {
+			t.Errorf("Expected a duration attribute, but the attributes were %v", e.attrs)
+		}
+	}
+}
+
+// TestLevels tests if the operations are logged at their configured level.
+func TestLevels(t *testing.T) {
+	store, handler := createStore(t, logging.Options{
+		Levels: map[string]slog.Level{logging.OpSet: slog.LevelInfo, logging.OpDelete: slog.LevelWarn},
+	}, gomap.NewStore(gomap.DefaultOptions))
+	handler.level = slog.LevelInfo
+
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Get is logged at the DefaultLevel, which isn't enabled
+	checkEntries(t, handler, []entry{
+		{slog.LevelInfo, "gokv operation", map[string]any{"operation": "Set"}},
+		{slog.LevelWarn, "gokv operation", map[string]any{"operation": "Delete"}},
+	})
+}
+
+// TestSlowThreshold tests if operations that take longer than the SlowThreshold are logged at the SlowLevel.
+func TestSlowThreshold(t *testing.T) {
+	errorLevel := slog.LevelError
+	for _, options := range []logging.Options{
+		{SlowThreshold: 50 * time.Millisecond},
+		{SlowThreshold: 50 * time.Millisecond, SlowLevel: &errorLevel},
+	} {
+		store, handler := createStore(t, options, slowStore{gomap.NewStore(gomap.DefaultOptions)})
+
+		err := store.Delete("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedLevel := slog.LevelWarn
+		if options.SlowLevel != nil {
+			expectedLevel = *options.SlowLevel
+		}
+		checkEntries(t, handler, []entry{
+			{slog.LevelDebug, "gokv operation", map[string]any{"operation": "Delete"}},
+			{expectedLevel, "gokv slow operation", map[string]any{"operation": "Set"}},
+		})
+	}
+}
+
+// TestKeyMode tests if the keys are logged according to the KeyMode.
+func TestKeyMode(t *testing.T) {
+	hash := sha256.Sum256([]byte("foo"))
+	expectedKeys := map[logging.KeyMode]string{
+		logging.KeyPlain:    "foo",
+		logging.KeyHashed:   hex.EncodeToString(hash[:]),
+		logging.KeyRedacted: "[redacted]",
+	}
+	for keyMode, expectedKey := range expectedKeys {
+		store, handler := createStore(t, logging.Options{KeyMode: keyMode}, gomap.NewStore(gomap.DefaultOptions))
+		err := store.Delete("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkEntries(t, handler, []entry{
+			{slog.LevelDebug, "gokv operation", map[string]any{"key": expectedKey}},
+		})
+	}
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store, handler := createStore(t, logging.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	// Invalid arguments don't reach the wrapped store, so they're not logged
+	checkEntries(t, handler, nil)
+
+	// Test errors of the wrapped store, which are logged with the type of the value
+	store, handler = createStore(t, logging.Options{}, test.FailingStore{Err: errFailing})
+	err = store.Set("foo", 123)
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	_, err = store.Get("foo", new(string))
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	err = store.Close()
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	checkEntries(t, handler, []entry{
+		{slog.LevelError, "gokv operation failed", map[string]any{"operation": "Set", "type": "int", "error": errFailing}},
+		{slog.LevelError, "gokv operation failed", map[string]any{"operation": "Get", "type": "*string", "error": errFailing}},
+		{slog.LevelError, "gokv operation failed", map[string]any{"operation": "Close", "error": errFailing}},
+	})
+
+	// Test marshalling errors, which are only logged as such with a codec
+	for _, codec := range []encoding.Codec{nil, encoding.JSON} {
+		store, handler = createStore(t, logging.Options{Codec: codec}, gomap.NewStore(gomap.DefaultOptions))
+		err = store.Set("foo", make(chan int))
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+		expectedMsg := "gokv operation failed"
+		if codec != nil {
+			expectedMsg = "gokv marshalling failed"
+		}
+		checkEntries(t, handler, []entry{
+			{slog.LevelError, expectedMsg, map[string]any{"operation": "Set", "type": "chan int"}},
+		})
+	}
+
+	// Test invalid options
+	invalidOptions := []logging.Options{
+		{},
+		{Store: gomap.NewStore(gomap.DefaultOptions), KeyMode: 3},
+		{Store: gomap.NewStore(gomap.DefaultOptions), Levels: map[string]slog.Level{"Foo": slog.LevelInfo}},
+	}
+	for _, options := range invalidOptions {
+		_, err = logging.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store, _ := createStore(t, logging.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store, _ := createStore(t, logging.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store, _ := createStore(t, logging.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// createStore creates a logging store for the wrapped store, with a logger whose handler
+// records the log entries at all levels. The handler is returned as well.
+func createStore(t *testing.T, options logging.Options, wrapped gokv.Store) (logging.Store, *recordingHandler) {
+	handler := &recordingHandler{level: slog.LevelDebug}
+	options.Store = wrapped
+	options.Logger = slog.New(handler)
+	store, err := logging.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, handler
+}
+
+type entry struct {
+	level slog.Level
+	msg   string
+	attrs map[string]any
+}
+
+// recordingHandler is a slog.Handler that records the log entries.
+type recordingHandler struct {
+	level   slog.Level
+	entries []entry
+	lock    sync.Mutex
+}
+
+func (h *recordingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *recordingHandler) Handle(_ context.Context, r slog.Record) error {
+	e := entry{level: r.Level, msg: r.Message, attrs: make(map[string]any)}
+	r.Attrs(func(a slog.Attr) bool {
+		e.attrs[a.Key] = a.Value.Any()
+		return true
+	})
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	panic("not implemented")
+}
+
+func (h *recordingHandler) WithGroup(name string) slog.Handler {
+	panic("not implemented")
+}
+
+// checkEntries checks the levels and messages of the recorded entries,
+// and if they contain the expected attributes, among others.
+func checkEntries(t *testing.T, handler *recordingHandler, expected []entry) {
+	t.Helper()
+	handler.lock.Lock()
+	defer handler.lock.Unlock()
+	if len(handler.entries) != len(expected) {
+		t.Fatalf("Expected %v log entries, but was %v: %+v", len(expected), len(handler.entries), handler.entries)
+	}
+	for i, e := range expected {
+		actual := handler.entries[i]
+		if actual.level != e.level || actual.msg != e.msg {
+			t.Errorf("Expected the entry %q at level %v, but was %q at level %v", e.msg, e.level, actual.msg, actual.level)
+		}
+		for key, value := range e.attrs {
+			if actual.attrs[key] != value {
+				t.Errorf("Expected %v for the attribute %q of the entry %q, but was %v", value, key, actual.msg, actual.attrs[key])
+			}
+		}
+	}
+}
+
+// slowStore is a gokv.Store whose Set() method takes 100ms.
+type slowStore struct {
+	gokv.Store
+}
+
+func (s slowStore) Set(k string, v any) error {
+	time.Sleep(100 * time.Millisecond)
+	return s.Store.Set(k, v)
+}
+
+var errFailing = errors.New("the store is failing")
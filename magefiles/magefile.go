@@ -57,10 +57,12 @@ func Test(module string) error {
 				return err
 			}
 		}
-		return nil
+		return testTool("cmd/gokv")
 	}
 
 	switch module {
+	case "cmd/gokv":
+		return testTool(module)
 	case "encoding", "sql", "test", "util":
 		return errors.New("module " + module + " doesn't have any tests")
 	case "examples":
@@ -1,4 +1,7 @@
 /*
 Package memcached contains an implementation of the `gokv.Store` interface for Memcached.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"memcached://host1:11211,host2:11211?timeout=200ms&max_idle_conns=100&codec=gob".
 */
 package memcached
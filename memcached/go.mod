@@ -4,6 +4,7 @@ go 1.20
 
 require (
 	github.com/bradfitz/gomemcache v0.0.0-20250403215159-8d39553ac7cf
+	github.com/philippgille/gokv v0.7.0
 	github.com/philippgille/gokv/encoding v0.7.0
 	github.com/philippgille/gokv/test v0.7.0
 	github.com/philippgille/gokv/util v0.7.0
@@ -11,5 +12,4 @@ require (
 
 require (
 	github.com/go-test/deep v1.1.0 // indirect
-	github.com/philippgille/gokv v0.7.0 // indirect
 )
@@ -1,16 +1,23 @@
 package memcached
 
 import (
+	"errors"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 var defaultTimeout = 200 * time.Millisecond
 
+// Memcached interprets expiration times of more than 30 days as absolute Unix timestamps.
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
 // Client is a gokv.Store implementation for Memcached.
 type Client struct {
 	c     *memcache.Client
@@ -38,12 +45,61 @@ func (c Client) Set(k string, v any) error {
 	}
 	err = c.c.Set(&item)
 	if err != nil {
+		return classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	item := memcache.Item{
+		Key:   k,
+		Value: data,
+	}
+	err := c.c.Set(&item)
+	if err != nil {
+		return classifyError(err)
+	}
+
 	return nil
 }
 
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// Memcached only supports seconds, so the TTL is rounded up to the next full second.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (c Client) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	expiration := util.TTLSeconds(ttl)
+	if expiration > maxRelativeExpiration {
+		expiration = time.Now().Add(ttl).Unix()
+	}
+	item := memcache.Item{
+		Key:        k,
+		Value:      data,
+		Expiration: int32(expiration),
+	}
+	return classifyError(c.c.Set(&item))
+}
+
 // Get retrieves the stored value for the given key.
 // The key must not be longer than 250 bytes (this is a restriction of Memcached).
 // You need to pass a pointer to the value, so in case of a struct
@@ -61,27 +117,190 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	if err == memcache.ErrCacheMiss {
 		return false, nil
 	} else if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 	data := item.Value
 
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	item, err := c.c.Get(k)
+	// If no value was found return false
+	if err == memcache.ErrCacheMiss {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, classifyError(err)
+	}
+	return item.Value, true, nil
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version, which is Memcached's CAS ID of the item.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// If no value is found it returns (0, false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+
+	item, err := c.c.Get(k)
+	// If no value was found return false
+	if err == memcache.ErrCacheMiss {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, classifyError(err)
+	}
+
+	return item.CasID, true, c.codec.Unmarshal(item.Value, v)
+}
+
+// SetIfVersion stores the given value for the given key with Memcached's "cas" command,
+// but only if the item's CAS ID is the given version.
+// Use the version 0 to store the value only if the key doesn't exist yet (Memcached's "add" command).
+// It returns false (and no error) if the version didn't match.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	item := memcache.Item{
+		Key:   k,
+		Value: data,
+		CasID: version,
+	}
+	if version == 0 {
+		err = c.c.Add(&item)
+	} else {
+		err = c.c.CompareAndSwap(&item)
+	}
+	// ErrNotStored means that the item already exists (add), ErrCASConflict that it was modified (cas)
+	// and ErrCacheMiss that it doesn't exist anymore (cas)
+	if err == memcache.ErrNotStored || err == memcache.ErrCASConflict || err == memcache.ErrCacheMiss {
+		return false, nil
+	} else if err != nil {
+		return false, classifyError(err)
+	}
+	return true, nil
+}
+
+// SetNX stores the given value for the given key with Memcached's "add" command,
+// but only if the key doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+// The key must not be longer than 250 bytes (this is a restriction of Memcached).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	return c.SetIfVersion(k, v, 0)
+}
+
 // Delete deletes the stored value for the given key.
 // The key must not be longer than 250 bytes (this is a restriction of Memcached).
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	_, err := c.DeleteExisting(k)
+	return err
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (c Client) DeleteExisting(k string) (existed bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return false, err
 	}
 
-	err := c.c.Delete(k)
+	err = c.c.Delete(k)
 	if err == memcache.ErrCacheMiss {
-		return nil
+		return false, nil
+	} else if err != nil {
+		return false, classifyError(err)
 	}
-	return err
+	return true, nil
+}
+
+// SetMulti stores all given key-value pairs.
+// Memcached doesn't have a command for storing multiple items at once,
+// so one request is sent per key-value pair, but all values are marshalled before the first one is sent.
+// No key must be longer than 250 bytes (this is a restriction of Memcached).
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, c.codec.Marshal)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range data {
+		item := memcache.Item{
+			Key:   k,
+			Value: v,
+		}
+		if err := c.c.Set(&item); err != nil {
+			return classifyError(err)
+		}
+	}
+	return nil
+}
+
+// GetMulti retrieves the stored values for the given keys with a single request per Memcached server.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be longer than 250 bytes (this is a restriction of Memcached).
+// No key must be "".
+func (c Client) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+
+	items, err := c.c.GetMulti(keys)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	result := make(map[string]any, len(items))
+	for k, item := range items {
+		v := newValue()
+		if err := c.codec.Unmarshal(item.Value, v); err != nil {
+			return nil, err
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// Memcached doesn't have a command for deleting multiple items at once,
+// so one request is sent per key.
+// No key must be longer than 250 bytes (this is a restriction of Memcached).
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		err := c.c.Delete(k)
+		if err != nil && err != memcache.ErrCacheMiss {
+			return classifyError(err)
+		}
+	}
+	return nil
 }
 
 // Close closes the client.
@@ -149,3 +368,47 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("memcached", open)
+}
+
+// open creates a client from a URL like "memcached://host1:11211,host2:11211?timeout=200ms&max_idle_conns=100&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		MaxIdleConns: q.Int("max_idle_conns"),
+		Codec:        q.Codec(),
+	}
+	if u.Host != "" {
+		options.Addresses = strings.Split(u.Host, ",")
+	}
+	if q.Has("timeout") {
+		timeout := q.Duration("timeout")
+		options.Timeout = &timeout
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the Memcached client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	var connectTimeoutErr *memcache.ConnectTimeoutError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &connectTimeoutErr):
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case errors.Is(err, memcache.ErrNoServers), errors.Is(err, memcache.ErrServerError):
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	case errors.Is(err, memcache.ErrCASConflict):
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return util.ClassifyError(err)
+}
@@ -123,6 +123,59 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestBatchStore tests if multiple key-value pairs can be set, retrieved and deleted at once.
+func TestBatchStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestBatchStore(client, t)
+}
+
+// TestExpiringStore tests if key-value pairs that are stored with a TTL expire.
+func TestExpiringStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestExpiringStore(client, t)
+}
+
+// TestVersionedStore tests if values are only stored when the given version is current.
+func TestVersionedStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestVersionedStore(client, t)
+}
+
+// TestCreateOnlyStore tests if values are only stored when the key doesn't exist yet.
+func TestCreateOnlyStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestCreateOnlyStore(client, t)
+}
+
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestRawStore(client, t)
+}
+
+// TestDeleter tests if deleting reports whether the key-value pair existed.
+func TestDeleter(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestDeleter(client, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestSentinelErrors(client, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	client := createClient(t, encoding.JSON)
+	test.TestTypedStore(client, t)
+}
+
+// TestOpen tests if the client can be created with gokv.Open().
+func TestOpen(t *testing.T) {
+	test.TestOpen("memcached://localhost:11211?timeout=2s&codec=gob", t)
+}
+
 // TestClose tests if the close method returns any errors.
 func TestClose(t *testing.T) {
 	client := createClient(t, encoding.JSON)
@@ -3,5 +3,8 @@ Package mongodb contains an implementation of the `gokv.Store` interface for Mon
 
 Note: If you use a sharded cluster, you must use "_id" as the shard key!
 You should also use hashed sharding as opposed to ranged sharding to enable more evenly distributed data no matter how your key looks like.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"mongodb://localhost/?database=gokv&collection=item&codec=gob" (other query parameters are left in the connection string, "mongodb+srv://" works as well).
 */
 package mongodb
@@ -2,19 +2,27 @@ package mongodb
 
 import (
 	"context"
+	"errors"
+	"net/url"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 var setOpt = options.Replace().SetUpsert(true)
 
+// watchOpt makes change streams include the current document for update events.
+var watchOpt = options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
 // item is the document that's stored in the MongoDB collection.
 // mongo (un-)marshalls it to/from BSON automatically, when reading from / writing to MongoDB.
 // In a previous version where we used github.com/globalsign/mgo, we had to do this
@@ -59,6 +67,11 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to MongoDB.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -76,11 +89,33 @@ func (c Client) Set(k string, v any) error {
 		K: k,
 		V: data,
 	}
-	_, err = c.c.ReplaceOne(context.Background(), bson.D{{Key: "_id", Value: k}}, item, setOpt)
+	_, err = c.c.ReplaceOne(ctx, bson.D{{Key: "_id", Value: k}}, item, setOpt)
 	if err != nil {
+		return classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	item := item{
+		// K needs to be specified, otherwise an update operation (on an existing document)
+		// would lead to the "_id" being overwritten by "",
+		// which 1) we don't want of course and 2) leads to an error anyway.
+		K: k,
+		V: data,
+	}
+	_, err := c.c.ReplaceOne(context.Background(), bson.D{{Key: "_id", Value: k}}, item, setOpt)
+	if err != nil {
+		return classifyError(err)
+	}
+
 	return nil
 }
 
@@ -91,43 +126,224 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to MongoDB.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
 	item := new(item)
-	err = c.c.FindOne(context.Background(), bson.D{{Key: "_id", Value: k}}).Decode(item)
+	err = c.c.FindOne(ctx, bson.D{{Key: "_id", Value: k}}).Decode(item)
 	// If no value was found return false
 	if err == mongo.ErrNoDocuments {
 		return false, nil
 	} else if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 	data := item.V
 
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	item := new(item)
+	err = c.c.FindOne(context.Background(), bson.D{{Key: "_id", Value: k}}).Decode(item)
+	// If no value was found return false
+	if err == mongo.ErrNoDocuments {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, classifyError(err)
+	}
+	return item.V, true, nil
+}
+
+// SetNX stores the given value for the given key by inserting a new document,
+// which only succeeds if no document with the key as "_id" exists yet.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	item := item{
+		K: k,
+		V: data,
+	}
+	_, err = c.c.InsertOne(context.Background(), item)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, classifyError(err)
+	}
+	return true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to MongoDB.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
-	_, err := c.c.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: k}})
+	_, err := c.c.DeleteOne(ctx, bson.D{{Key: "_id", Value: k}})
 	// No need to check for mongo.ErrNoDocuments, because DeleteOne() doesn't return
 	// any error if no document was deleted. This differs from a previous version
 	// where we used mgo.
-	return err
+	return classifyError(err)
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (c Client) DeleteExisting(k string) (existed bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	res, err := c.c.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: k}})
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// changeEvent is the part of a change stream event that's relevant for Watch() and WatchPrefix().
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		K string `bson:"_id"`
+	} `bson:"documentKey"`
+	// Only set for insert, replace and update events.
+	// For update events it's nil if the document was deleted in the meantime.
+	FullDocument *item               `bson:"fullDocument"`
+	ClusterTime  primitive.Timestamp `bson:"clusterTime"`
+}
+
+// Watch sends an event to the returned channel for each change of the given key.
+// Watching stops and the channel is closed when the context is canceled.
+//
+// Changes are sent via MongoDB change streams, which require a replica set or sharded cluster.
+// With a standalone server an error is returned.
+// The value is part of the event, except for updates made by other clients with update operators,
+// where the value is looked up by MongoDB and can be newer than the change.
+// Event.Revision is the cluster time of the change.
+func (c Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return c.watch(ctx, k)
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+// An empty prefix matches all keys.
+func (c Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watch(ctx, bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(prefix)}})
+}
+
+// watch opens a change stream for the documents whose "_id" matches the given filter.
+func (c Client) watch(ctx context.Context, filter any) (<-chan gokv.Event, error) {
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{{Key: "documentKey._id", Value: filter}}}},
+	}
+	stream, err := c.c.Watch(ctx, pipeline, watchOpt)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer func() { _ = stream.Close(context.Background()) }()
+		for stream.Next(ctx) {
+			var ce changeEvent
+			if err := stream.Decode(&ce); err != nil {
+				select {
+				case events <- gokv.Event{Err: classifyError(err)}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			event, ok := c.toEvent(ce)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		// The stream also ends without error when it's invalidated, for example because the collection was dropped.
+		if err := stream.Err(); err != nil && ctx.Err() == nil {
+			select {
+			case events <- gokv.Event{Err: classifyError(err)}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// toEvent converts a change stream event to an event.
+// It returns false for operations that don't change a single document.
+func (c Client) toEvent(ce changeEvent) (gokv.Event, bool) {
+	event := gokv.Event{
+		Key:      ce.DocumentKey.K,
+		Revision: uint64(ce.ClusterTime.T)<<32 | uint64(ce.ClusterTime.I),
+	}
+	switch ce.OperationType {
+	case "insert", "replace", "update":
+		doc := ce.FullDocument
+		event.Type = gokv.EventSet
+		event.Decode = func(v any) error {
+			if doc == nil {
+				return gokv.ErrValueUnavailable
+			}
+			return c.codec.Unmarshal(doc.V, v)
+		}
+	case "delete":
+		event.Type = gokv.EventDelete
+	default:
+		return gokv.Event{}, false
+	}
+	return event, true
 }
 
 // Close closes the client.
 // It must be called to release any open resources.
 func (c Client) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but uses the given context for disconnecting from MongoDB.
+func (c Client) CloseContext(ctx context.Context) error {
 	c.cancel()
-	return c.client.Disconnect(context.Background())
+	return c.client.Disconnect(ctx)
 }
 
 // Options are the options for the MongoDB client.
@@ -182,7 +398,7 @@ func NewClient(opts Options) (Client, error) {
 	defer cancel()
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(opts.ConnectionString))
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	// The above `Connect` doesn't block for server discovery. But like with other
@@ -192,7 +408,7 @@ func NewClient(opts Options) (Client, error) {
 	defer pingCancel()
 	err = client.Ping(pingCtx, readpref.Primary())
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	c := client.Database(opts.DatabaseName).Collection(opts.CollectionName)
@@ -204,3 +420,51 @@ func NewClient(opts Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("mongodb", open)
+	gokv.Register("mongodb+srv", open)
+}
+
+// open creates a client from a MongoDB connection string with the additional query parameters
+// "database", "collection" and "codec", like "mongodb://localhost/?database=gokv&collection=item&codec=gob".
+// The other query parameters are left in the connection string.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		DatabaseName:   q.String("database"),
+		CollectionName: q.String("collection"),
+		Codec:          q.Codec(),
+	}
+	connURL := *u
+	connURL.RawQuery = q.Rest().Encode()
+	options.ConnectionString = connURL.String()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the MongoDB driver with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if mongo.IsTimeout(err) {
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	}
+	if mongo.IsNetworkError(err) || errors.Is(err, mongo.ErrClientDisconnected) {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	var serverErr mongo.ServerError
+	// 112 is the code of write conflicts
+	if errors.As(err, &serverErr) && (serverErr.HasErrorLabel("TransientTransactionError") || serverErr.HasErrorCode(112)) {
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return util.ClassifyError(err)
+}
@@ -1,4 +1,7 @@
 /*
 Package mysql contains an implementation of the `gokv.Store` interface for MySQL.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"mysql://root@localhost:3306/gokv?table=Item&max_open_connections=100&codec=gob" (other query parameters are left in the DataSourceName).
 */
 package mysql
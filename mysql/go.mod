@@ -4,14 +4,14 @@ go 1.21.0
 
 require (
 	github.com/go-sql-driver/mysql v1.9.3
+	github.com/philippgille/gokv v0.7.0
 	github.com/philippgille/gokv/encoding v0.7.0
 	github.com/philippgille/gokv/sql v0.7.0
 	github.com/philippgille/gokv/test v0.7.0
+	github.com/philippgille/gokv/util v0.7.0
 )
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/go-test/deep v1.1.0 // indirect
-	github.com/philippgille/gokv v0.7.0 // indirect
-	github.com/philippgille/gokv/util v0.7.0 // indirect
 )
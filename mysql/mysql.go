@@ -1,14 +1,20 @@
 package mysql
 
 import (
+	"context"
 	gosql "database/sql"
+	"errors"
+	"net/url"
+	"strings"
 
 	// Usually a blank import is enough as it calls the package's init() function and loads the driver,
 	// but we'll use the package's ParseDNS() function so we make this an actual import.
 	gosqldriver "github.com/go-sql-driver/mysql"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/sql"
+	"github.com/philippgille/gokv/util"
 )
 
 const (
@@ -38,6 +44,19 @@ func (c Client) Set(k string, v any) error {
 	return c.c.Set(k, v)
 }
 
+// SetContext is like Set, but uses the given context for executing the statement.
+// The length of the key must not exceed 255 characters.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
+	return c.c.SetContext(ctx, k, v)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	return c.c.SetRaw(k, data)
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -53,6 +72,27 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	return c.c.Get(k, v)
 }
 
+// GetContext is like Get, but uses the given context for executing the query.
+// The length of the key must not exceed 255 characters.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
+	return c.c.GetContext(ctx, k, v)
+}
+
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	return c.c.GetRaw(k)
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) Has(k string) (found bool, err error) {
+	return c.c.Has(k)
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The length of the key must not exceed 255 characters.
@@ -65,12 +105,105 @@ func (c Client) Delete(k string) error {
 	return c.c.Delete(k)
 }
 
+// DeleteContext is like Delete, but uses the given context for executing the statement.
+// The length of the key must not exceed 255 characters.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	return c.c.DeleteContext(ctx, k)
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The length of the key must not exceed 255 characters.
+// The key must not be "".
+func (c Client) DeleteExisting(k string) (existed bool, err error) {
+	return c.c.DeleteExisting(k)
+}
+
+// SetMulti stores all given key-value pairs in a single transaction.
+// The length of the keys must not exceed 255 characters.
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(kvs map[string]any) error {
+	return c.c.SetMulti(kvs)
+}
+
+// GetMulti retrieves the stored values for the given keys.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// The length of the keys must not exceed 255 characters.
+// No key must be "".
+func (c Client) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	return c.c.GetMulti(keys, newValue)
+}
+
+// DeleteMulti deletes the stored values for the given keys in a single transaction.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// The length of the keys must not exceed 255 characters.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	return c.c.DeleteMulti(keys)
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix,
+// ordered by key according to the table's collation.
+// The iteration stops when fn returns false.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	return c.c.ScanPrefix(prefix, fn)
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version from the version column.
+// If no value is found it returns (0, false, nil).
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	return c.c.GetVersioned(k, v)
+}
+
+// SetIfVersion stores the given value for the given key,
+// but only if the row's version matches the given version (see GetVersioned()).
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match.
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	return c.c.SetIfVersion(k, v, version)
+}
+
+// SetNX stores the given value for the given key,
+// but only if the key doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+// The length of the key must not exceed 255 characters.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	return c.c.SetNX(k, v)
+}
+
+// Update executes the given function within a database transaction.
+// If the function returns nil, the transaction is committed, otherwise it's rolled back.
+// The length of the keys that are used in the transaction must not exceed 255 characters.
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	return c.c.Update(fn)
+}
+
+// View executes the given function within a read-only database transaction.
+// The length of the keys that are used in the transaction must not exceed 255 characters.
+func (c Client) View(fn func(tx gokv.Tx) error) error {
+	return c.c.View(fn)
+}
+
 // Close closes the client.
 // It must be called to return all open connections to the connection pool and to release any open resources.
 func (c Client) Close() error {
 	return c.c.Close()
 }
 
+// CloseContext is like Close.
+// The context is passed on to the sql.Client, which ignores it, because database/sql doesn't support one for closing.
+func (c Client) CloseContext(ctx context.Context) error {
+	return c.c.CloseContext(ctx)
+}
+
 // Options are the options for the MySQL client.
 type Options struct {
 	// Connection string.
@@ -132,12 +265,12 @@ func NewClient(options Options) (Client, error) {
 
 	db, err := gosql.Open("mysql", options.DataSourceName)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	cfg, err := gosqldriver.ParseDSN(options.DataSourceName)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	err = db.Ping()
@@ -151,17 +284,17 @@ func NewClient(options Options) (Client, error) {
 					return result, nil
 				}
 			} else {
-				return result, err
+				return result, classifyError(err)
 			}
 		} else {
-			return result, err
+			return result, classifyError(err)
 		}
 	} else if cfg.DBName == "" {
 		// Ping() was successful, but in case the package user didn't include a database name
 		// in the DataSourceName, we must now attempt to create the database.
 		newDB, err := createDefaultDB(db, cfg)
 		if err != nil {
-			return result, err
+			return result, classifyError(err)
 		}
 		// Also, we must replace the current value that the db pointer points to by the new db,
 		// because calling "USE" on a database only works for the single connection that's used
@@ -183,34 +316,86 @@ func NewClient(options Options) (Client, error) {
 	// If yes, allow the user to define a key length via the options.
 	// Also: There's no hard character limit, but byte limit.
 	// So the 255 characters come from 255 utf8mb3 characters.
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k VARCHAR(" + keyLength + ") PRIMARY KEY, v BLOB NOT NULL)")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k VARCHAR(" + keyLength + ") PRIMARY KEY, v BLOB NOT NULL, ver BIGINT NOT NULL DEFAULT 1)")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	// Tables that were created by older versions of this package don't have the version column yet.
+	// MySQL doesn't support "ADD COLUMN IF NOT EXISTS", so we check the schema first.
+	var verColumns int
+	err = db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = 'ver'", options.TableName).Scan(&verColumns)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
+	}
+	if verColumns == 0 {
+		_, err = db.Exec("ALTER TABLE " + options.TableName + " ADD COLUMN ver BIGINT NOT NULL DEFAULT 1")
+		if err != nil {
+			return result, classifyError(err)
+		}
 	}
 
 	// Create prepared statements that will be reused for every Set()/Get() operation.
 	// Note: Prepared statements are handled differently from other programming languages in Go,
 	// see: http://go-database-sql.org/prepared.html.
 	// TODO: Prepared statements might prevent the use of other databases that are compatible with the MySQL protocol.
-	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES (?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v)")
+	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, ver) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v), ver = ver + 1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = ?")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	deleteStmt, err := db.Prepare("DELETE FROM " + options.TableName + " where k = ?")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
+	}
+	scanPrefixStmt, err := db.Prepare("SELECT k, v FROM " + options.TableName + " WHERE k LIKE ? ESCAPE '!' ORDER BY k")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	getVersionedStmt, err := db.Prepare("SELECT v, ver FROM " + options.TableName + " WHERE k = ?")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	// "INSERT IGNORE" would turn other errors into warnings as well.
+	// Updating the key to itself doesn't count as affected row (as long as the DSN doesn't contain "clientFoundRows=true").
+	createStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, ver) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE k = k")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	updateIfVersionStmt, err := db.Prepare("UPDATE " + options.TableName + " SET v = ?, ver = ver + 1 WHERE k = ? AND ver = ?")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	hasStmt, err := db.Prepare("SELECT 1 FROM " + options.TableName + " WHERE k = ?")
+	if err != nil {
+		return result, classifyError(err)
 	}
 
 	c := sql.Client{
-		C:          db,
-		UpsertStmt: upsertStmt,
-		GetStmt:    getStmt,
-		DeleteStmt: deleteStmt,
-		Codec:      options.Codec,
+		C:              db,
+		UpsertStmt:     upsertStmt,
+		GetStmt:        getStmt,
+		DeleteStmt:     deleteStmt,
+		ScanPrefixStmt: scanPrefixStmt,
+
+		GetVersionedStmt:    getVersionedStmt,
+		CreateStmt:          createStmt,
+		UpdateIfVersionStmt: updateIfVersionStmt,
+		HasStmt:             hasStmt,
+		// Multi-row statements depend on the number of rows, so they can't be prepared upfront.
+		UpsertMultiQuery: func(n int) string {
+			return "INSERT INTO " + options.TableName + " (k, v, ver) VALUES " + sql.PlaceholderTuples(n, 3, sql.QuestionMark) + " ON DUPLICATE KEY UPDATE v = VALUES(v), ver = ver + 1"
+		},
+		GetMultiQuery: func(n int) string {
+			return "SELECT k, v FROM " + options.TableName + " WHERE k IN (" + sql.PlaceholderList(n, sql.QuestionMark) + ")"
+		},
+		DeleteMultiQuery: func(n int) string {
+			return "DELETE FROM " + options.TableName + " WHERE k IN (" + sql.PlaceholderList(n, sql.QuestionMark) + ")"
+		},
+		ClassifyError: classifyError,
+		Codec:         options.Codec,
 	}
 
 	result.c = &c
@@ -218,6 +403,50 @@ func NewClient(options Options) (Client, error) {
 	return result, nil
 }
 
+func init() {
+	gokv.Register("mysql", open)
+}
+
+// open creates a client from a URL like "mysql://root@localhost:3306/gokv?table=Item&codec=gob",
+// which is converted to a DataSourceName like "root@tcp(localhost:3306)/gokv".
+// Query parameters other than "table", "max_open_connections" and "codec" are left in the DataSourceName.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		TableName:          q.String("table"),
+		MaxOpenConnections: q.Int("max_open_connections"),
+		Codec:              q.Codec(),
+	}
+	rest := q.Rest()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	// Keep the default DataSourceName for "mysql://"
+	if u.User != nil || u.Host != "" || u.Path != "" || len(rest) > 0 {
+		dsn := ""
+		if u.User != nil {
+			dsn = u.User.Username()
+			if password, ok := u.User.Password(); ok {
+				dsn += ":" + password
+			}
+			dsn += "@"
+		}
+		if u.Host != "" {
+			dsn += "tcp(" + u.Host + ")"
+		}
+		dsn += "/" + strings.TrimPrefix(u.Path, "/")
+		if len(rest) > 0 {
+			dsn += "?" + rest.Encode()
+		}
+		options.DataSourceName = dsn
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // createDB creates a DB when the DB name is given in the config.
 func createDB(cfg *gosqldriver.Config, db *gosql.DB) error {
 	// We can't use the existing db object, because that would lead to the same error again.
@@ -232,22 +461,22 @@ func createDB(cfg *gosqldriver.Config, db *gosql.DB) error {
 	// TODO: Maybe DO return an error?
 	defer func() { _ = tempDB.Close() }()
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	err = tempDB.Ping()
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	// No need to check if userProvidedDBname == "", because in that case the error wouldn't be 1049 (unknown database).
 	// In case the user doesn't have the permission to create a database, an error is returned.
 	err = sql.CreateDB(tempDB, userProvidedDBname)
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	// Now the initial ping should work.
 	err = db.Ping()
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 
 	return nil
@@ -259,18 +488,44 @@ func createDB(cfg *gosqldriver.Config, db *gosql.DB) error {
 func createDefaultDB(db *gosql.DB, cfg *gosqldriver.Config) (*gosql.DB, error) {
 	err := sql.CreateDB(db, defaultDBname)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	cfg.DBName = defaultDBname
 	dsnWithDBname := cfg.FormatDSN()
 	newDB, err := gosql.Open("mysql", dsnWithDBname)
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 	err = newDB.Ping()
 	if err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
 	return newDB, nil
 }
+
+// classifyError wraps errors of the MySQL driver with the matching gokv error, like gokv.ErrConflict.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, gosqldriver.ErrInvalidConn) {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	var driverErr *gosqldriver.MySQLError
+	if errors.As(err, &driverErr) {
+		switch driverErr.Number {
+		// Lock wait timeout exceeded and maximum statement execution time exceeded
+		case 1205, 3024:
+			return gokv.WrapError(gokv.ErrTimeout, err)
+		// Deadlock found when trying to get lock
+		case 1213:
+			return gokv.WrapError(gokv.ErrConflict, err)
+		// Too many connections and server shutdown in progress
+		case 1040, 1053:
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
@@ -0,0 +1,10 @@
+/*
+Package namespace contains an implementation of the `gokv.Store` interface that prefixes the keys of any other store
+with a namespace, like "app1:" for the key "app1:foo".
+
+This allows multiple applications or tenants to share one backend without their keys colliding,
+also for backends without a namespace concept of their own, like Redis, Memcached, LevelDB or BadgerDB.
+Namespaces can be nested with Store.Namespace(), and iterating over the keys or scanning them by prefix
+only returns the keys of the namespace, with the prefix stripped.
+*/
+package namespace
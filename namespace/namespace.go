@@ -0,0 +1,293 @@
+package namespace
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
+
+// Store is a gokv.Store that prefixes all keys with a namespace before passing them to the wrapped store,
+// so that multiple applications or tenants can share one backend without their keys colliding.
+// The prefix is the namespace followed by the separator, for example "app1:" for the key "app1:foo".
+//
+// Keys() and Iter() only return the keys of the namespace and ScanPrefix() only scans it,
+// with the prefix stripped from the keys, if the wrapped store implements gokv.Iterable or gokv.PrefixScanner.
+// Otherwise they return an error.
+type Store struct {
+	store     gokv.Store
+	prefix    string
+	separator string
+}
+
+// Set stores the given value for the given key in the namespace.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	return s.store.Set(s.prefix+k, v)
+}
+
+// Get retrieves the stored value for the given key in the namespace.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+	return s.store.Get(s.prefix+k, v)
+}
+
+// Delete deletes the stored value for the given key in the namespace.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	return s.store.Delete(s.prefix + k)
+}
+
+// SetMulti stores all given key-value pairs in the namespace.
+// It uses the bulk operation of the wrapped store if it implements gokv.BatchStore, see gokv.AsBatchStore().
+// No key must be "" and no value must be nil.
+func (s Store) SetMulti(kvs map[string]any) error {
+	prefixed := make(map[string]any, len(kvs))
+	for k, v := range kvs {
+		if err := util.CheckKeyAndValue(k, v); err != nil {
+			return err
+		}
+		prefixed[s.prefix+k] = v
+	}
+	return gokv.AsBatchStore(s.store).SetMulti(prefixed)
+}
+
+// GetMulti retrieves the values for the given keys in the namespace.
+// Keys that aren't found are missing from the returned map.
+// It uses the bulk operation of the wrapped store if it implements gokv.BatchStore, see gokv.AsBatchStore().
+// No key must be "".
+func (s Store) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	prefixedKeys, err := s.prefixKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	prefixed, err := gokv.AsBatchStore(s.store).GetMulti(prefixedKeys, newValue)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(prefixed))
+	for k, v := range prefixed {
+		result[strings.TrimPrefix(k, s.prefix)] = v
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys in the namespace.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// It uses the bulk operation of the wrapped store if it implements gokv.BatchStore, see gokv.AsBatchStore().
+// No key must be "".
+func (s Store) DeleteMulti(keys []string) error {
+	prefixedKeys, err := s.prefixKeys(keys)
+	if err != nil {
+		return err
+	}
+	return gokv.AsBatchStore(s.store).DeleteMulti(prefixedKeys)
+}
+
+func (s Store) prefixKeys(keys []string) ([]string, error) {
+	prefixed := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if err := util.CheckKey(k); err != nil {
+			return nil, err
+		}
+		prefixed = append(prefixed, s.prefix+k)
+	}
+	return prefixed, nil
+}
+
+// Keys calls fn for each key in the namespace, without the namespace's prefix.
+// The iteration stops when fn returns false.
+// It uses ScanPrefix() of the wrapped store if it implements gokv.PrefixScanner,
+// otherwise it iterates over all keys of the wrapped store if it implements gokv.Iterable.
+// It returns gokv.ErrNotSupported if the wrapped store implements neither.
+func (s Store) Keys(fn func(k string) bool) error {
+	if scanner, ok := s.store.(gokv.PrefixScanner); ok {
+		return scanner.ScanPrefix(s.prefix, func(k string, _ func(v any) error) bool {
+			return fn(strings.TrimPrefix(k, s.prefix))
+		})
+	}
+	if iterable, ok := s.store.(gokv.Iterable); ok {
+		return iterable.Keys(func(k string) bool {
+			if !strings.HasPrefix(k, s.prefix) {
+				return true
+			}
+			return fn(strings.TrimPrefix(k, s.prefix))
+		})
+	}
+	return gokv.WrapError(gokv.ErrNotSupported, errors.New("keys aren't supported, because the wrapped store implements neither gokv.PrefixScanner nor gokv.Iterable"))
+}
+
+// Iter returns a cursor-style iterator over the keys in the namespace, without the namespace's prefix.
+// The iterator must be closed when it's not needed anymore.
+// It returns gokv.ErrNotSupported if the wrapped store doesn't implement gokv.Iterable.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	iterable, ok := s.store.(gokv.Iterable)
+	if !ok {
+		return nil, gokv.WrapError(gokv.ErrNotSupported, errors.New("iterators aren't supported, because the wrapped store doesn't implement gokv.Iterable"))
+	}
+	iter, err := iterable.Iter()
+	if err != nil {
+		return nil, err
+	}
+	return &keyIterator{
+		KeyIterator: iter,
+		prefix:      s.prefix,
+	}, nil
+}
+
+// ScanPrefix calls fn for each key-value pair in the namespace whose key starts with the given prefix.
+// The key is passed without the namespace's prefix, but including the given prefix.
+// The value can be unmarshalled by calling decode with a pointer to an object of the correct type,
+// like the one you'd pass to Get(). decode is only valid during the call of fn.
+// The iteration stops when fn returns false.
+// An empty prefix matches all keys in the namespace.
+// It uses ScanPrefix() of the wrapped store if it implements gokv.PrefixScanner,
+// otherwise it iterates over all keys of the wrapped store and retrieves the values with Get()
+// if it implements gokv.Iterable.
+// It returns gokv.ErrNotSupported if the wrapped store implements neither.
+func (s Store) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	fullPrefix := s.prefix + prefix
+	if scanner, ok := s.store.(gokv.PrefixScanner); ok {
+		return scanner.ScanPrefix(fullPrefix, func(k string, decode func(v any) error) bool {
+			return fn(strings.TrimPrefix(k, s.prefix), decode)
+		})
+	}
+	iterable, ok := s.store.(gokv.Iterable)
+	if !ok {
+		return gokv.WrapError(gokv.ErrNotSupported, errors.New("prefix scans aren't supported, because the wrapped store implements neither gokv.PrefixScanner nor gokv.Iterable"))
+	}
+
+	// Collect the keys first, because some stores (like bbolt) don't allow other operations during the iteration
+	var keys []string
+	err := iterable.Keys(func(k string) bool {
+		if strings.HasPrefix(k, fullPrefix) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		k := k
+		decode := func(v any) error {
+			found, err := s.store.Get(k, v)
+			if err == nil && !found {
+				return errors.New("the key-value pair was deleted during the scan")
+			}
+			return err
+		}
+		if !fn(strings.TrimPrefix(k, s.prefix), decode) {
+			break
+		}
+	}
+	return nil
+}
+
+// Namespace returns a store for a namespace that's nested in this one.
+// Its keys are prefixed with the prefix of this store, the given namespace and the separator,
+// like "app1:tenant1:foo" for the key "foo" in the namespace "tenant1" of the namespace "app1".
+// The namespace must not be "".
+func (s Store) Namespace(namespace string) (Store, error) {
+	if namespace == "" {
+		return Store{}, errors.New("the namespace must not be empty")
+	}
+	return Store{
+		store:     s.store,
+		prefix:    s.prefix + namespace + s.separator,
+		separator: s.separator,
+	}, nil
+}
+
+// Prefix returns the prefix of the keys in the wrapped store, which is the namespace followed by the separator.
+func (s Store) Prefix() string {
+	return s.prefix
+}
+
+// Close closes the wrapped store.
+// When multiple namespaces share the wrapped store, close it only once,
+// for example by closing the wrapped store directly instead of the namespaces.
+func (s Store) Close() error {
+	return s.store.Close()
+}
+
+// keyIterator is a gokv.KeyIterator that skips the keys of other namespaces and strips the prefix.
+type keyIterator struct {
+	gokv.KeyIterator
+	prefix string
+	key    string
+}
+
+func (i *keyIterator) Next() bool {
+	for i.KeyIterator.Next() {
+		k := i.KeyIterator.Key()
+		if strings.HasPrefix(k, i.prefix) {
+			i.key = strings.TrimPrefix(k, i.prefix)
+			return true
+		}
+	}
+	i.key = ""
+	return false
+}
+
+func (i *keyIterator) Key() string {
+	return i.key
+}
+
+// Options are the options for the namespace store.
+type Options struct {
+	// The store whose keys are prefixed.
+	// Required.
+	Store gokv.Store
+	// The namespace, like the name of an application or tenant.
+	// Required.
+	Namespace string
+	// The separator between the namespace and the key.
+	// Optional (":" by default).
+	Separator string
+}
+
+// DefaultOptions is an Options object with default values.
+// Separator: ":"
+var DefaultOptions = Options{
+	Separator: ":",
+	// No need to set Store or Namespace because they're required and don't have a default.
+}
+
+// NewStore creates a new namespace store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+	if options.Namespace == "" {
+		return result, errors.New("the Namespace in the options must not be empty")
+	}
+
+	// Set default values
+	if options.Separator == "" {
+		options.Separator = DefaultOptions.Separator
+	}
+
+	result.store = options.Store
+	result.separator = options.Separator
+	result.prefix = options.Namespace + options.Separator
+
+	return result, nil
+}
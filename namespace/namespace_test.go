@@ -0,0 +1,293 @@
+package namespace_test
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/namespace"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, encoding.Gob)
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, encoding.Gob)
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key, which must not be turned into the valid key "app:"
+	store := createStore(t, encoding.JSON)
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Namespace("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test invalid options
+	invalidOptions := []namespace.Options{
+		{Namespace: "app"},
+		{Store: gomap.NewStore(gomap.DefaultOptions)},
+	}
+	for _, options := range invalidOptions {
+		_, err = namespace.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestIsolation tests if namespaces that share a store don't see each other's keys,
+// and if nested namespaces are prefixed with the prefixes of all their parents.
+func TestIsolation(t *testing.T) {
+	wrapped := prefixScanner{gomap.NewStore(gomap.DefaultOptions)}
+	app1, err := namespace.NewStore(namespace.Options{Store: wrapped, Namespace: "app1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app2, err := namespace.NewStore(namespace.Options{Store: wrapped, Namespace: "app2", Separator: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant, err := app1.Namespace("tenant1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant.Prefix() != "app1:tenant1:" {
+		t.Errorf("Expected the prefix %q, but was %q", "app1:tenant1:", tenant.Prefix())
+	}
+
+	for _, store := range []namespace.Store{app1, app2, tenant} {
+		if err := store.Set("foo", store.Prefix()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, store := range []namespace.Store{app1, app2, tenant} {
+		checkValue(t, store, "foo", store.Prefix())
+	}
+	checkKeys(t, wrapped, "", []string{"app1:foo", "app1:tenant1:foo", "app2/foo"})
+	// The keys of the nested namespace are part of the parent namespace, like files in a subdirectory
+	checkKeys(t, app1, "", []string{"foo", "tenant1:foo"})
+	checkKeys(t, app2, "", []string{"foo"})
+	checkKeys(t, tenant, "", []string{"foo"})
+
+	err = app1.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, app1, "foo", "")
+	checkValue(t, app2, "foo", "app2/")
+	checkValue(t, tenant, "foo", "app1:tenant1:")
+}
+
+// TestIterable tests if the keys of the namespace can be iterated over.
+func TestIterable(t *testing.T) {
+	t.Run("PrefixScanner", func(t *testing.T) {
+		store := createStoreFor(t, prefixScanner{gomap.NewStore(gomap.DefaultOptions)})
+		test.TestIterable(store, t)
+	})
+	t.Run("Iterable", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestIterable(store, t)
+	})
+}
+
+// TestPrefixScanner tests if the key-value pairs of the namespace can be scanned by prefix.
+func TestPrefixScanner(t *testing.T) {
+	t.Run("PrefixScanner", func(t *testing.T) {
+		store := createStoreFor(t, prefixScanner{gomap.NewStore(gomap.DefaultOptions)})
+		test.TestPrefixScanner(store, t)
+	})
+	t.Run("Iterable", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestPrefixScanner(store, t)
+	})
+}
+
+// TestIterationNotSupported tests if iterating fails if the wrapped store doesn't support it.
+func TestIterationNotSupported(t *testing.T) {
+	store := createStoreFor(t, plainStore{gomap.NewStore(gomap.DefaultOptions)})
+	err := store.Keys(func(k string) bool { return true })
+	if !errors.Is(err, gokv.ErrNotSupported) {
+		t.Errorf("Expected gokv.ErrNotSupported, but was: %v", err)
+	}
+	_, err = store.Iter()
+	if !errors.Is(err, gokv.ErrNotSupported) {
+		t.Errorf("Expected gokv.ErrNotSupported, but was: %v", err)
+	}
+	err = store.ScanPrefix("", func(k string, decode func(v any) error) bool { return true })
+	if !errors.Is(err, gokv.ErrNotSupported) {
+		t.Errorf("Expected gokv.ErrNotSupported, but was: %v", err)
+	}
+}
+
+// TestBatchStore tests if setting, getting and deleting multiple key-value pairs at once works properly.
+func TestBatchStore(t *testing.T) {
+	t.Run("BatchStore", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestBatchStore(store, t)
+	})
+	t.Run("Store", func(t *testing.T) {
+		store := createStoreFor(t, plainStore{gomap.NewStore(gomap.DefaultOptions)})
+		test.TestBatchStore(store, t)
+	})
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// createStore creates a namespace store with the namespace "app" for a gomap store.
+func createStore(t *testing.T, codec encoding.Codec) namespace.Store {
+	return createStoreFor(t, gomap.NewStore(gomap.Options{Codec: codec}))
+}
+
+func createStoreFor(t *testing.T, wrapped gokv.Store) namespace.Store {
+	options := namespace.DefaultOptions
+	options.Store = wrapped
+	options.Namespace = "app"
+	store, err := namespace.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// checkValue checks the string value for the given key, with "" meaning that it must not be found.
+func checkValue(t *testing.T, store gokv.Store, k, expected string) {
+	t.Helper()
+	actual := ""
+	found, err := store.Get(k, &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected == "" && found {
+		t.Errorf("Expected no value for the key %q, but was %q", k, actual)
+	} else if expected != "" && actual != expected {
+		t.Errorf("Expected %q for the key %q, but was %q (found: %v)", expected, k, actual, found)
+	}
+}
+
+// checkKeys checks the sorted keys with the given prefix.
+func checkKeys(t *testing.T, store gokv.PrefixScanner, prefix string, expected []string) {
+	t.Helper()
+	var actual []string
+	err := store.ScanPrefix(prefix, func(k string, _ func(v any) error) bool {
+		actual = append(actual, k)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(actual)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected the keys %v, but was %v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("Expected the keys %v, but was %v", expected, actual)
+			break
+		}
+	}
+}
+
+// plainStore only implements gokv.Store, hiding the optional interfaces of the wrapped store.
+type plainStore struct {
+	gokv.Store
+}
+
+// prefixScanner implements gokv.PrefixScanner for a gomap store, which only implements gokv.Iterable.
+type prefixScanner struct {
+	gomap.Store
+}
+
+func (s prefixScanner) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	var keys []string
+	err := s.Keys(func(k string) bool {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		k := k
+		decode := func(v any) error {
+			_, err := s.Get(k, v)
+			return err
+		}
+		if !fn(k, decode) {
+			break
+		}
+	}
+	return nil
+}
@@ -1,4 +1,7 @@
 /*
 Package noop contains an implementation of the `gokv.Store` interface that does nothing.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"noop://".
 */
 package noop
@@ -1,6 +1,11 @@
 package noop
 
-import "github.com/philippgille/gokv/util"
+import (
+	"net/url"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
 
 // Store is a gokv.Store implementation that does nothing except validate the arguments if applicable.
 type Store struct{}
@@ -23,6 +28,24 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	return false, nil
 }
 
+// SetRaw pretends it stores the data. Always return nil error unless the key or data are invalid.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetRaw pretends it fetches the data. Always return not found and nil error unless the key is invalid.
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	return nil, false, nil
+}
+
 // Delete pretends it deletes the key. Always return nil error unless the key is invalid.
 func (s Store) Delete(k string) error {
 	if err := util.CheckKey(k); err != nil {
@@ -41,3 +64,15 @@ func (s Store) Close() error {
 func NewStore() Store {
 	return Store{}
 }
+
+func init() {
+	gokv.Register("noop", open)
+}
+
+// open creates a store from the URL "noop://".
+func open(u *url.URL) (gokv.Store, error) {
+	if err := util.NewURLQuery(u).Err(); err != nil {
+		return nil, err
+	}
+	return NewStore(), nil
+}
@@ -1,6 +1,7 @@
 package noop_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/philippgille/gokv"
@@ -34,6 +35,53 @@ func TestNop(t *testing.T) {
 	}
 }
 
+func TestRawNop(t *testing.T) {
+	t.Parallel()
+
+	var s gokv.RawStore = noop.NewStore()
+
+	if err := s.SetRaw("foo", []byte("bar")); err != nil {
+		t.Error(err)
+	}
+
+	data, found, err := s.GetRaw("foo")
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+	if data != nil {
+		t.Errorf("Expected nil data, but was: %v", data)
+	}
+
+	if err := s.SetRaw("", []byte("bar")); err == nil {
+		t.Error("An error was expected for an empty key")
+	}
+	if err := s.SetRaw("foo", nil); err == nil {
+		t.Error("An error was expected for nil data")
+	}
+	if _, _, err := s.GetRaw(""); err == nil {
+		t.Error("An error was expected for an empty key")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	s, err := gokv.Open("noop://")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(noop.Store); !ok {
+		t.Errorf("Expected a noop.Store, but was: %T", s)
+	}
+
+	if _, err := gokv.Open("noop://?codec=gob"); err == nil {
+		t.Error("An error should have been returned for an unknown query parameter")
+	}
+}
+
 func TestInputValidation(t *testing.T) {
 	t.Parallel()
 
@@ -41,18 +89,18 @@ func TestInputValidation(t *testing.T) {
 
 	{
 		err := s.Set("", 1)
-		assertEqualError(t, err, errInvalidKey)
+		assertError(t, err, gokv.ErrKeyEmpty)
 	}
 
 	{
 		err := s.Set("foo", nil)
-		assertEqualError(t, err, errInvalidValue)
+		assertError(t, err, gokv.ErrValueNil)
 	}
 
 	{
 		var v int
 		found, err := s.Get("", &v)
-		assertEqualError(t, err, errInvalidKey)
+		assertError(t, err, gokv.ErrKeyEmpty)
 		if found {
 			t.Error("A value was found, but no value was expected")
 		}
@@ -60,7 +108,7 @@ func TestInputValidation(t *testing.T) {
 
 	{
 		found, err := s.Get("foo", nil)
-		assertEqualError(t, err, errInvalidValue)
+		assertError(t, err, gokv.ErrValueNil)
 		if found {
 			t.Error("A value was found, but no value was expected")
 		}
@@ -68,24 +116,16 @@ func TestInputValidation(t *testing.T) {
 
 	{
 		err := s.Delete("")
-		assertEqualError(t, err, errInvalidKey)
+		assertError(t, err, gokv.ErrKeyEmpty)
 	}
 }
 
-func assertEqualError(t *testing.T, err error, expectedErrMsg string) {
+func assertError(t *testing.T, err error, expected error) {
 	t.Helper()
 
 	if err == nil {
 		t.Error("expect error, got nil")
-	} else if err.Error() != expectedErrMsg {
+	} else if !errors.Is(err, expected) {
 		t.Error(err)
 	}
 }
-
-// TODO: We updated error capitalization in the utils package after v0.7.0, but
-// as long as utils doesn't have a v0.8.0 release, we can't import it yet and have
-// to match on the old error messages.
-var (
-	errInvalidKey   = "The passed key is an empty string, which is invalid"
-	errInvalidValue = "The passed value is nil, which is not allowed"
-)
@@ -0,0 +1,79 @@
+package gokv
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Opener creates a store from a URL, whose scheme is the one the Opener was registered for.
+// It's implemented by the implementation packages, which map the URL's host, path and query parameters
+// to the fields of their Options struct.
+type Opener func(u *url.URL) (Store, error)
+
+var (
+	openersLock sync.RWMutex
+	openers     = make(map[string]Opener)
+)
+
+// Register makes an Opener available for the given URL scheme, so that stores can be created with Open().
+// The implementation packages call it in their init() function,
+// so you only need to import them, for example with a blank import like `_ "github.com/philippgille/gokv/redis"`.
+// Schemes are case-insensitive, like in URLs.
+// Register panics if the scheme is empty, if the opener is nil or if an opener is already registered for the scheme.
+func Register(scheme string, opener Opener) {
+	if scheme == "" {
+		panic("gokv: Register scheme is empty")
+	}
+	if opener == nil {
+		panic("gokv: Register opener is nil")
+	}
+	scheme = strings.ToLower(scheme)
+
+	openersLock.Lock()
+	defer openersLock.Unlock()
+	if _, ok := openers[scheme]; ok {
+		panic("gokv: Register called twice for scheme " + scheme)
+	}
+	openers[scheme] = opener
+}
+
+// Open creates a store from the given URL, with the Opener that's registered for the URL's scheme.
+// For example "redis://localhost:6379/0?codec=gob" or "bbolt:///tmp/gokv.db?bucket=Default".
+// See the documentation of the implementation packages for the supported URLs and query parameters.
+// The "codec" query parameter selects a codec that's registered in the encoding package by name,
+// like "json" (the default) or "gob".
+//
+// You must call the Close() method on the store when you're done working with it.
+func Open(rawURL string) (Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" {
+		return nil, errors.New("the URL must have a scheme, like \"redis://\"")
+	}
+
+	openersLock.RLock()
+	opener, ok := openers[u.Scheme]
+	openersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no store is registered for the URL scheme %q (forgotten import?)", u.Scheme)
+	}
+	return opener(u)
+}
+
+// Schemes returns a sorted list of the URL schemes for which an Opener is registered.
+func Schemes() []string {
+	openersLock.RLock()
+	defer openersLock.RUnlock()
+	schemes := make([]string, 0, len(openers))
+	for scheme := range openers {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
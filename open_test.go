@@ -0,0 +1,76 @@
+package gokv_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/philippgille/gokv"
+)
+
+// TestOpen tests if Open() passes the parsed URL to the Opener that's registered for its scheme.
+func TestOpen(t *testing.T) {
+	var got *url.URL
+	gokv.Register("Gokv-Open-Test", func(u *url.URL) (gokv.Store, error) {
+		got = u
+		return nil, nil
+	})
+
+	// The scheme is case-insensitive
+	_, err := gokv.Open("GOKV-OPEN-TEST://host/path?codec=gob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("The opener wasn't called")
+	}
+	if got.Host != "host" || got.Path != "/path" || got.Query().Get("codec") != "gob" {
+		t.Errorf("The opener was called with an unexpected URL: %v", got)
+	}
+
+	found := false
+	for _, scheme := range gokv.Schemes() {
+		if scheme == "gokv-open-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("The registered scheme is missing from the schemes: %v", gokv.Schemes())
+	}
+}
+
+// TestOpenErrors tests if Open() returns an error for invalid URLs and unregistered schemes.
+func TestOpenErrors(t *testing.T) {
+	for _, rawURL := range []string{"", "/tmp/gokv.db", "gokv-unregistered://host", "://host"} {
+		if _, err := gokv.Open(rawURL); err == nil {
+			t.Errorf("An error should have been returned for the URL %q", rawURL)
+		}
+	}
+}
+
+// TestRegisterPanics tests if Register() panics for invalid arguments and for schemes that are registered twice.
+func TestRegisterPanics(t *testing.T) {
+	opener := func(u *url.URL) (gokv.Store, error) {
+		return nil, nil
+	}
+	gokv.Register("gokv-register-test", opener)
+
+	testCases := []struct {
+		name   string
+		scheme string
+		opener gokv.Opener
+	}{
+		{"empty scheme", "", opener},
+		{"nil opener", "gokv-register-nil-test", nil},
+		{"duplicate scheme", "GOKV-REGISTER-TEST", opener},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Register should have panicked")
+				}
+			}()
+			gokv.Register(tc.scheme, tc.opener)
+		})
+	}
+}
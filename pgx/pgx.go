@@ -4,10 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/sql"
 	"github.com/philippgille/gokv/util"
 )
 
@@ -34,9 +38,19 @@ type Client struct {
 
 	// pgx prepares statements for us, so we don't need to do it manually
 	// https://github.com/jackc/pgx/issues/791#issuecomment-660486444
-	upsertStmt string
-	getStmt    string
-	deleteStmt string
+	upsertStmt     string
+	getStmt        string
+	deleteStmt     string
+	scanPrefixStmt string
+	// The multi statements take arrays as parameters, so they work for any number of key-value pairs
+	upsertMultiStmt string
+	getMultiStmt    string
+	deleteMultiStmt string
+	// The version column is incremented by every write
+	getVersionedStmt    string
+	createStmt          string
+	updateIfVersionStmt string
+	hasStmt             string
 }
 
 // NewClient creates a new PostgreSQL client using pgx.
@@ -57,19 +71,44 @@ func NewClient(options Options) (*Client, error) {
 	}
 
 	client := &Client{
-		pool:       options.Pool,
-		codec:      options.Codec,
-		tableName:  options.TableName,
-		upsertStmt: fmt.Sprintf("INSERT INTO %s (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v", options.TableName),
-		getStmt:    fmt.Sprintf("SELECT v FROM %s WHERE k=$1", options.TableName),
-		deleteStmt: fmt.Sprintf("DELETE FROM %s WHERE k=$1", options.TableName),
+		pool:            options.Pool,
+		codec:           options.Codec,
+		tableName:       options.TableName,
+		upsertStmt:      fmt.Sprintf("INSERT INTO %[1]s (k, v, ver) VALUES ($1, $2, $3) ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v, ver = %[1]s.ver + 1", options.TableName),
+		getStmt:         fmt.Sprintf("SELECT v FROM %s WHERE k=$1", options.TableName),
+		deleteStmt:      fmt.Sprintf("DELETE FROM %s WHERE k=$1", options.TableName),
+		scanPrefixStmt:  fmt.Sprintf("SELECT k, v FROM %s WHERE k LIKE $1 ESCAPE '!' ORDER BY k", options.TableName),
+		upsertMultiStmt: fmt.Sprintf("INSERT INTO %[1]s (k, v, ver) SELECT * FROM unnest($1::text[], $2::bytea[], $3::bigint[]) ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v, ver = %[1]s.ver + 1", options.TableName),
+		getMultiStmt:    fmt.Sprintf("SELECT k, v FROM %s WHERE k = ANY($1)", options.TableName),
+		deleteMultiStmt: fmt.Sprintf("DELETE FROM %s WHERE k = ANY($1)", options.TableName),
+
+		getVersionedStmt:    fmt.Sprintf("SELECT v, ver FROM %s WHERE k=$1", options.TableName),
+		createStmt:          fmt.Sprintf("INSERT INTO %s (k, v, ver) VALUES ($1, $2, $3) ON CONFLICT (k) DO NOTHING", options.TableName),
+		updateIfVersionStmt: fmt.Sprintf("UPDATE %s SET v = $1, ver = ver + 1 WHERE k=$2 AND ver=$3", options.TableName),
+		hasStmt:             fmt.Sprintf("SELECT 1 FROM %s WHERE k=$1", options.TableName),
 	}
 
 	// Create table if it doesn't exist yet
-	_, err := client.pool.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS `+client.tableName+` (k TEXT PRIMARY KEY, v BYTEA NOT NULL)`)
+	_, err := client.pool.Exec(context.Background(), `CREATE TABLE IF NOT EXISTS `+client.tableName+` (k TEXT PRIMARY KEY, v BYTEA NOT NULL, ver BIGINT NOT NULL DEFAULT 1)`)
 	if err != nil {
 		_ = client.Close()
-		return nil, err
+		return nil, classifyError(err)
+	}
+	// Tables that were created by older versions of this package don't have the version column yet.
+	// ALTER TABLE takes an exclusive lock on the table, so we check the schema first
+	// (unquoted table names are stored in lower case).
+	var verColumns int
+	err = client.pool.QueryRow(context.Background(), `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = lower($1) AND column_name = 'ver'`, client.tableName).Scan(&verColumns)
+	if err != nil {
+		_ = client.Close()
+		return nil, classifyError(err)
+	}
+	if verColumns == 0 {
+		_, err = client.pool.Exec(context.Background(), `ALTER TABLE `+client.tableName+` ADD COLUMN IF NOT EXISTS ver BIGINT NOT NULL DEFAULT 1`)
+		if err != nil {
+			_ = client.Close()
+			return nil, classifyError(err)
+		}
 	}
 
 	return client, nil
@@ -77,6 +116,11 @@ func NewClient(options Options) (*Client, error) {
 
 // Set stores the given value for the given key.
 func (c *Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key, using the given context for the query.
+func (c *Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -86,36 +130,421 @@ func (c *Client) Set(k string, v any) error {
 		return err
 	}
 
-	_, err = c.pool.Exec(context.Background(), c.upsertStmt, k, data)
-	return err
+	_, err = c.pool.Exec(ctx, c.upsertStmt, k, data, sql.NewVersion())
+	return classifyError(err)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c *Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	_, err := c.pool.Exec(context.Background(), c.upsertStmt, k, data, sql.NewVersion())
+	return classifyError(err)
 }
 
 // Get retrieves the stored value for the given key.
 func (c *Client) Get(k string, v any) (bool, error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key, using the given context for the query.
+func (c *Client) GetContext(ctx context.Context, k string, v any) (bool, error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
 	var data []byte
-	err := c.pool.QueryRow(context.Background(), c.getStmt, k).Scan(&data)
+	err := c.pool.QueryRow(ctx, c.getStmt, k).Scan(&data)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return false, nil
 		}
-		return false, err
+		return false, classifyError(err)
 	}
 
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c *Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	err = c.pool.QueryRow(context.Background(), c.getStmt, k).Scan(&data)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, classifyError(err)
+	}
+
+	return data, true, nil
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (c *Client) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	err = c.pool.QueryRow(context.Background(), c.hasStmt, k).Scan(nil)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, classifyError(err)
+	}
+	return true, nil
+}
+
 // Delete deletes the stored value for the given key.
 func (c *Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key, using the given context for the query.
+func (c *Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
-	_, err := c.pool.Exec(context.Background(), c.deleteStmt, k)
-	return err
+	_, err := c.pool.Exec(ctx, c.deleteStmt, k)
+	return classifyError(err)
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (c *Client) DeleteExisting(k string) (existed bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	tag, err := c.pool.Exec(context.Background(), c.deleteStmt, k)
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version from the version column.
+// If no value is found it returns (0, false, nil).
+func (c *Client) GetVersioned(k string, v any) (uint64, bool, error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+
+	var data []byte
+	var version int64
+	err := c.pool.QueryRow(context.Background(), c.getVersionedStmt, k).Scan(&data, &version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, classifyError(err)
+	}
+
+	return uint64(version), true, c.codec.Unmarshal(data, v)
+}
+
+// SetIfVersion stores the given value for the given key,
+// but only if the row's version matches the given version (see GetVersioned()).
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match.
+func (c *Client) SetIfVersion(k string, v any, version uint64) (bool, error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	var tag pgconn.CommandTag
+	if version == 0 {
+		tag, err = c.pool.Exec(context.Background(), c.createStmt, k, data, sql.NewVersion())
+	} else {
+		tag, err = c.pool.Exec(context.Background(), c.updateIfVersionStmt, data, k, int64(version))
+	}
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetNX stores the given value for the given key with "INSERT ... ON CONFLICT DO NOTHING",
+// so the value is only stored if the key doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+func (c *Client) SetNX(k string, v any) (bool, error) {
+	return c.SetIfVersion(k, v, 0)
+}
+
+// SetMulti stores all given key-value pairs with a single statement.
+// No key must be "" and no value must be nil.
+func (c *Client) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, c.codec.Marshal)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(data))
+	values := make([][]byte, 0, len(data))
+	versions := make([]int64, 0, len(data))
+	for k, v := range data {
+		keys = append(keys, k)
+		values = append(values, v)
+		versions = append(versions, sql.NewVersion())
+	}
+	_, err = c.pool.Exec(context.Background(), c.upsertMultiStmt, keys, values, versions)
+	return classifyError(err)
+}
+
+// GetMulti retrieves the stored values for the given keys with a single query.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be "".
+func (c *Client) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.pool.Query(context.Background(), c.getMultiStmt, keys)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer rows.Close()
+
+	result := make(map[string]any, len(keys))
+	for rows.Next() {
+		var k string
+		var data []byte
+		if err := rows.Scan(&k, &data); err != nil {
+			return nil, classifyError(err)
+		}
+		v := newValue()
+		if err := c.codec.Unmarshal(data, v); err != nil {
+			return nil, err
+		}
+		result[k] = v
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys with a single statement.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c *Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+
+	_, err := c.pool.Exec(context.Background(), c.deleteMultiStmt, keys)
+	return classifyError(err)
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, ordered by key.
+// The iteration stops when fn returns false.
+func (c *Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	rows, err := c.pool.Query(context.Background(), c.scanPrefixStmt, sql.LikePrefixPattern(prefix))
+	if err != nil {
+		return classifyError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k string
+		var data []byte
+		if err := rows.Scan(&k, &data); err != nil {
+			return classifyError(err)
+		}
+		decode := func(v any) error {
+			return c.codec.Unmarshal(data, v)
+		}
+		if !fn(k, decode) {
+			break
+		}
+	}
+	return classifyError(rows.Err())
+}
+
+// Update executes the given function within a database transaction.
+// If the function returns nil, the transaction is committed, otherwise it's rolled back.
+// The isolation level is the database's default.
+func (c *Client) Update(fn func(tx gokv.Tx) error) error {
+	return c.runTx(pgx.TxOptions{}, fn)
+}
+
+// View executes the given function within a read-only database transaction.
+func (c *Client) View(fn func(tx gokv.Tx) error) error {
+	return c.runTx(pgx.TxOptions{AccessMode: pgx.ReadOnly}, fn)
+}
+
+func (c *Client) runTx(opts pgx.TxOptions, fn func(tx gokv.Tx) error) error {
+	ctx := context.Background()
+	tx, err := c.pool.BeginTx(ctx, opts)
+	if err != nil {
+		return classifyError(err)
+	}
+	err = fn(&clientTx{
+		tx:       tx,
+		c:        c,
+		readOnly: opts.AccessMode == pgx.ReadOnly,
+	})
+	if err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	return classifyError(tx.Commit(ctx))
+}
+
+// clientTx is a gokv.Tx for a database transaction.
+type clientTx struct {
+	tx       pgx.Tx
+	c        *Client
+	readOnly bool
+}
+
+func (t *clientTx) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return gokv.ErrReadOnlyTx
+	}
+
+	data, err := t.c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = t.tx.Exec(context.Background(), t.c.upsertStmt, k, data, sql.NewVersion())
+	return classifyError(err)
+}
+
+func (t *clientTx) Get(k string, v any) (bool, error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	var data []byte
+	err := t.tx.QueryRow(context.Background(), t.c.getStmt, k).Scan(&data)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, classifyError(err)
+	}
+	return true, t.c.codec.Unmarshal(data, v)
+}
+
+func (t *clientTx) Delete(k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return gokv.ErrReadOnlyTx
+	}
+
+	_, err := t.tx.Exec(context.Background(), t.c.deleteStmt, k)
+	return classifyError(err)
+}
+
+// Watch sends an event to the returned channel for each change of the given key.
+// Watching stops and the channel is closed when the context is canceled.
+//
+// Changes are sent via PostgreSQL's LISTEN/NOTIFY, which occupies one connection of the pool while watching.
+// The first call creates a trigger on the table, which from then on sends a notification for each change,
+// also when nobody is watching.
+// Notifications only contain the key, so Event.Decode() retrieves the current value from the table.
+// Changes of keys that are longer than about 8000 bytes are skipped.
+func (c *Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return c.watch(ctx, func(key string) bool {
+		return key == k
+	})
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+// An empty prefix matches all keys.
+func (c *Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watch(ctx, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+func (c *Client) watch(ctx context.Context, match func(k string) bool) (<-chan gokv.Event, error) {
+	channel := sql.NotifyChannel(c.tableName)
+
+	_, err := c.pool.Exec(ctx, sql.NotifyFunctionQuery)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	_, err = c.pool.Exec(ctx, sql.NotifyTriggerQuery(c.tableName))
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+	if err != nil {
+		conn.Release()
+		return nil, classifyError(err)
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer func() {
+			// The connection is returned to the pool, so it must not keep listening.
+			// If the wait was interrupted, the connection is closed anyway and the pool discards it.
+			_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+			conn.Release()
+		}()
+		for {
+			n, err := conn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					select {
+					case events <- gokv.Event{Err: classifyError(err)}:
+					case <-ctx.Done():
+					}
+				}
+				return
+			}
+			event, ok := sql.NotificationEvent(n.Payload, c.Get)
+			if !ok || !match(event.Key) {
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
 }
 
 // Close closes the connection pool.
@@ -123,3 +552,39 @@ func (c *Client) Close() error {
 	c.pool.Close()
 	return nil
 }
+
+// CloseContext closes the connection pool.
+// The context is ignored, because pgxpool doesn't support one for closing.
+func (c *Client) CloseContext(_ context.Context) error {
+	return c.Close()
+}
+
+// classifyError wraps errors of pgx with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if pgconn.Timeout(err) {
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		// serialization_failure and deadlock_detected
+		case pgErr.Code == "40001", pgErr.Code == "40P01":
+			return gokv.WrapError(gokv.ErrConflict, err)
+		// query_canceled, which is also returned when the statement_timeout is exceeded
+		case pgErr.Code == "57014":
+			return gokv.WrapError(gokv.ErrTimeout, err)
+		// Connection exceptions, too_many_connections, admin_shutdown and cannot_connect_now
+		case strings.HasPrefix(pgErr.Code, "08"), pgErr.Code == "53300", pgErr.Code == "57P01", pgErr.Code == "57P03":
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
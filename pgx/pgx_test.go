@@ -40,6 +40,66 @@ func TestClient(t *testing.T) {
 				defer func() { _ = client.Close() }()
 				test.TestConcurrentInteractions(t, 10, client)
 			})
+			t.Run("context", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestContextStore(client, t)
+			})
+			t.Run("prefix scanner", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestPrefixScanner(client, t)
+			})
+			t.Run("batch", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestBatchStore(client, t)
+			})
+			t.Run("versioned", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestVersionedStore(client, t)
+			})
+			t.Run("create only", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestCreateOnlyStore(client, t)
+			})
+			t.Run("transactional", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestTransactional(client, t)
+			})
+			t.Run("raw", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestRawStore(client, t)
+			})
+			t.Run("deleter", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestDeleter(client, t)
+			})
+			t.Run("existence checker", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestExistenceChecker(client, t)
+			})
+			t.Run("watcher", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestWatcher(client, t)
+			})
+			t.Run("sentinel errors", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestSentinelErrors(client, t)
+			})
+			t.Run("typed", func(t *testing.T) {
+				client := createClient(t, tc)
+				defer func() { _ = client.Close() }()
+				test.TestTypedStore(client, t)
+			})
 		})
 	}
 }
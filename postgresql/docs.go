@@ -1,4 +1,7 @@
 /*
 Package postgresql contains an implementation of the `gokv.Store` interface for PostgreSQL.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"postgres://postgres@localhost/gokv?sslmode=disable&table=Item&max_open_connections=100&codec=gob" (other query parameters are left in the connection URL, "postgresql://" works as well).
 */
 package postgresql
@@ -4,13 +4,13 @@ go 1.20
 
 require (
 	github.com/lib/pq v1.10.9
+	github.com/philippgille/gokv v0.7.0
 	github.com/philippgille/gokv/encoding v0.7.0
 	github.com/philippgille/gokv/sql v0.7.0
 	github.com/philippgille/gokv/test v0.7.0
+	github.com/philippgille/gokv/util v0.7.0
 )
 
 require (
 	github.com/go-test/deep v1.1.0 // indirect
-	github.com/philippgille/gokv v0.7.0 // indirect
-	github.com/philippgille/gokv/util v0.7.0 // indirect
 )
@@ -1,14 +1,21 @@
 package postgresql
 
 import (
+	"context"
 	gosql "database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
 
 	// Usually a blank import is enough as it calls the package's init() function and loads the driver,
-	// but we'll use the package's ParseDNS() function so we make this an actual import.
-	_ "github.com/lib/pq"
+	// but we use the package's Listener for Watch(), so we make this an actual import.
+	"github.com/lib/pq"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/sql"
+	"github.com/philippgille/gokv/util"
 )
 
 const defaultDBname = "gokv"
@@ -16,6 +23,8 @@ const defaultDBname = "gokv"
 // Client is a gokv.Store implementation for PostgreSQL.
 type Client struct {
 	*sql.Client
+	connectionURL string
+	tableName     string
 }
 
 // Options are the options for the PostgreSQL client.
@@ -79,12 +88,12 @@ func NewClient(options Options) (Client, error) {
 
 	db, err := gosql.Open("postgres", options.ConnectionURL)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	err = db.Ping()
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	// Limit number of concurrent connections. Typical max connections on a PostgreSQL server is 100.
@@ -92,37 +101,223 @@ func NewClient(options Options) (Client, error) {
 	db.SetMaxOpenConns(options.MaxOpenConnections)
 
 	// Create table if it doesn't exist yet.
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k TEXT PRIMARY KEY, v BYTEA NOT NULL)")
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS " + options.TableName + " (k TEXT PRIMARY KEY, v BYTEA NOT NULL, ver BIGINT NOT NULL DEFAULT 1)")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
+	}
+	// Tables that were created by older versions of this package don't have the version column yet.
+	// ALTER TABLE takes an exclusive lock on the table, so we check the schema first
+	// (unquoted table names are stored in lower case).
+	var verColumns int
+	err = db.QueryRow("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = lower($1) AND column_name = 'ver'", options.TableName).Scan(&verColumns)
+	if err != nil {
+		return result, classifyError(err)
+	}
+	if verColumns == 0 {
+		_, err = db.Exec("ALTER TABLE " + options.TableName + " ADD COLUMN IF NOT EXISTS ver BIGINT NOT NULL DEFAULT 1")
+		if err != nil {
+			return result, classifyError(err)
+		}
 	}
 
 	// Create prepared statements that will be reused for every Set()/Get() operation.
 	// Note: Prepared statements are handled differently from other programming languages in Go,
 	// see: http://go-database-sql.org/prepared.html.
 	// TODO: Prepared statements might prevent the use of other databases that are compatible with the PostgreSQL protocol.
-	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v) VALUES ($1, $2) ON CONFLICT (k) DO UPDATE SET v = $2")
+	upsertStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, ver) VALUES ($1, $2, $3) ON CONFLICT (k) DO UPDATE SET v = $2, ver = " + options.TableName + ".ver + 1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	getStmt, err := db.Prepare("SELECT v FROM " + options.TableName + " WHERE k = $1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	deleteStmt, err := db.Prepare("DELETE FROM " + options.TableName + " where k = $1")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
+	}
+	scanPrefixStmt, err := db.Prepare("SELECT k, v FROM " + options.TableName + " WHERE k LIKE $1 ESCAPE '!' ORDER BY k")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	getVersionedStmt, err := db.Prepare("SELECT v, ver FROM " + options.TableName + " WHERE k = $1")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	createStmt, err := db.Prepare("INSERT INTO " + options.TableName + " (k, v, ver) VALUES ($1, $2, $3) ON CONFLICT (k) DO NOTHING")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	updateIfVersionStmt, err := db.Prepare("UPDATE " + options.TableName + " SET v = $1, ver = ver + 1 WHERE k = $2 AND ver = $3")
+	if err != nil {
+		return result, classifyError(err)
+	}
+	hasStmt, err := db.Prepare("SELECT 1 FROM " + options.TableName + " WHERE k = $1")
+	if err != nil {
+		return result, classifyError(err)
 	}
 
 	c := sql.Client{
-		C:          db,
-		UpsertStmt: upsertStmt,
-		GetStmt:    getStmt,
-		DeleteStmt: deleteStmt,
-		Codec:      options.Codec,
+		C:              db,
+		UpsertStmt:     upsertStmt,
+		GetStmt:        getStmt,
+		DeleteStmt:     deleteStmt,
+		ScanPrefixStmt: scanPrefixStmt,
+
+		GetVersionedStmt:    getVersionedStmt,
+		CreateStmt:          createStmt,
+		UpdateIfVersionStmt: updateIfVersionStmt,
+		HasStmt:             hasStmt,
+		// Multi-row statements depend on the number of rows, so they can't be prepared upfront.
+		UpsertMultiQuery: func(n int) string {
+			return "INSERT INTO " + options.TableName + " (k, v, ver) VALUES " + sql.PlaceholderTuples(n, 3, sql.DollarNumber) + " ON CONFLICT (k) DO UPDATE SET v = EXCLUDED.v, ver = " + options.TableName + ".ver + 1"
+		},
+		GetMultiQuery: func(n int) string {
+			return "SELECT k, v FROM " + options.TableName + " WHERE k IN (" + sql.PlaceholderList(n, sql.DollarNumber) + ")"
+		},
+		DeleteMultiQuery: func(n int) string {
+			return "DELETE FROM " + options.TableName + " WHERE k IN (" + sql.PlaceholderList(n, sql.DollarNumber) + ")"
+		},
+		ClassifyError: classifyError,
+		Codec:         options.Codec,
 	}
 
 	result.Client = &c
+	result.connectionURL = options.ConnectionURL
+	result.tableName = options.TableName
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("postgres", open)
+	gokv.Register("postgresql", open)
+}
+
+// open creates a client from a connection URL with the additional query parameters
+// "table", "max_open_connections" and "codec",
+// like "postgres://postgres@localhost/gokv?sslmode=disable&table=Item&codec=gob".
+// The other query parameters are left in the connection URL.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		TableName:          q.String("table"),
+		MaxOpenConnections: q.Int("max_open_connections"),
+		Codec:              q.Codec(),
+	}
+	connURL := *u
+	connURL.RawQuery = q.Rest().Encode()
+	options.ConnectionURL = connURL.String()
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// Watch sends an event to the returned channel for each change of the given key.
+// Watching stops and the channel is closed when the context is canceled.
+//
+// Changes are sent via PostgreSQL's LISTEN/NOTIFY.
+// The first call creates a trigger on the table, which from then on sends a notification for each change,
+// also when nobody is watching.
+// Notifications only contain the key, so Event.Decode() retrieves the current value from the table.
+// Changes of keys that are longer than about 8000 bytes are skipped.
+// Changes that happen while the listening connection is reconnecting are missed.
+func (c Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return c.watch(ctx, func(key string) bool {
+		return key == k
+	})
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+// An empty prefix matches all keys.
+func (c Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watch(ctx, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+func (c Client) watch(ctx context.Context, match func(k string) bool) (<-chan gokv.Event, error) {
+	channel := sql.NotifyChannel(c.tableName)
+
+	_, err := c.C.ExecContext(ctx, sql.NotifyFunctionQuery)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	_, err = c.C.ExecContext(ctx, sql.NotifyTriggerQuery(c.tableName))
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	// The listener uses its own connection and reconnects automatically.
+	listener := pq.NewListener(c.connectionURL, 10*time.Millisecond, time.Minute, nil)
+	// Listen() blocks until the server acknowledged the LISTEN command,
+	// so changes that happen after Watch() returned are sent.
+	err = listener.Listen(channel)
+	if err != nil {
+		_ = listener.Close()
+		return nil, classifyError(err)
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer func() { _ = listener.Close() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case n, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				// A nil notification is sent after reconnecting.
+				if n == nil {
+					continue
+				}
+				event, ok := sql.NotificationEvent(n.Extra, c.Get)
+				if !ok || !match(event.Key) {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// classifyError wraps errors of the PostgreSQL driver with the matching gokv error, like gokv.ErrConflict.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		// serialization_failure and deadlock_detected
+		case pqErr.Code == "40001", pqErr.Code == "40P01":
+			return gokv.WrapError(gokv.ErrConflict, err)
+		// query_canceled, which is also returned when the statement_timeout is exceeded
+		case pqErr.Code == "57014":
+			return gokv.WrapError(gokv.ErrTimeout, err)
+		// Connection exceptions, too_many_connections, admin_shutdown and cannot_connect_now
+		case pqErr.Code.Class() == "08", pqErr.Code == "53300", pqErr.Code == "57P01", pqErr.Code == "57P03":
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
@@ -0,0 +1,17 @@
+package gokv
+
+// PrefixScanner is implemented by stores that can efficiently look up
+// all key-value pairs whose key starts with a given prefix.
+// This is useful for hierarchical keys like "tenant/123/user/456".
+type PrefixScanner interface {
+	// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix.
+	// The key is passed as is, including the prefix.
+	// The value can be unmarshalled by calling decode with a pointer to an object of the correct type,
+	// like the one you'd pass to Get(). It uses the same codec as the store.
+	// decode is only valid during the call of fn.
+	// The iteration stops when fn returns false.
+	// An empty prefix matches all keys.
+	//
+	// The order of the key-value pairs depends on the implementation.
+	ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error
+}
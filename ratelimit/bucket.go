@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket that's refilled with rate tokens per second, up to burst tokens.
+// The tokens can become negative, which means that the following calls have to wait until the debt is paid off.
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	lock   sync.Mutex
+}
+
+func newBucket(rate float64, burst int) *bucket {
+	return &bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// advance refills the bucket with the tokens for the time since the last call.
+// The lock must be held.
+func (b *bucket) advance(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// reserve takes n tokens and returns how long the caller has to wait until they're available.
+func (b *bucket) reserve(n float64) time.Duration {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// tryTake takes n tokens if they're available right now and returns whether it did.
+// More tokens than the burst are taken when the bucket is full, so that large values aren't rejected forever.
+func (b *bucket) tryTake(n float64) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	if b.tokens < math.Min(n, b.burst) {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// charge takes n tokens without waiting, for costs that are only known after the call.
+func (b *bucket) charge(n float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	b.tokens -= n
+}
+
+// giveBack returns n tokens of a reservation that wasn't used.
+func (b *bucket) giveBack(n float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.advance(time.Now())
+	b.tokens = math.Min(b.burst, b.tokens+n)
+}
@@ -0,0 +1,12 @@
+/*
+Package ratelimit contains an implementation of the `gokv.Store` interface that limits the rate of the calls
+to any other store on the client side.
+
+This keeps bursty jobs within the provisioned capacity of a backend, like the ReadCapacityUnits and WriteCapacityUnits
+of a DynamoDB table or the reserved capacity of an Alibaba Cloud Table Store instance,
+instead of letting them run into its throttling errors.
+Reads and writes are limited by separate token buckets, and with a codec the calls are weighted by the size
+of the values, like the capacity units of those backends. Calls that exceed the rate limit either wait
+until it allows them (ModeBlock) or fail with ErrRateLimited (ModeFailFast).
+*/
+package ratelimit
@@ -0,0 +1,291 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/util"
+)
+
+// Mode defines what happens when a call exceeds the rate limit.
+type Mode int
+
+const (
+	// ModeBlock lets calls wait until the rate limit allows them or their context is done.
+	ModeBlock Mode = iota
+	// ModeFailFast lets calls fail with ErrRateLimited right away.
+	ModeFailFast
+)
+
+// ErrRateLimited is returned in ModeFailFast when a call exceeds the rate limit.
+// It's classified as gokv.ErrUnavailable, like the throttling errors of the implementations,
+// so gokv.IsUnavailable() returns true for it.
+var ErrRateLimited = gokv.WrapError(gokv.ErrUnavailable, errors.New("the rate limit is exceeded"))
+
+// Store is a gokv.Store that limits the rate of the calls to the wrapped store on the client side,
+// with separate token buckets for reads (Get()) and writes (Set() and Delete()).
+// This keeps bursty jobs within the provisioned capacity of a backend like DynamoDB or Alibaba Cloud Table Store,
+// instead of letting them run into its throttling errors.
+//
+// Each call takes one unit from its bucket. With a Codec the calls are weighted by the size of the marshalled value,
+// like the capacity units of DynamoDB: a Set() takes one unit per started WriteUnitSize bytes,
+// and a Get() takes one unit per started ReadUnitSize bytes. As the size of a read value is only known
+// after reading it, Get() waits for one unit and takes the rest afterwards, which delays the following reads.
+//
+// It implements gokv.ContextStore. The context is passed on to the wrapped store if it implements gokv.ContextStore,
+// and in ModeBlock waiting for the rate limit stops as soon as the context is done.
+// Close() isn't rate limited.
+type Store struct {
+	store         gokv.ContextStore
+	reads         *bucket
+	writes        *bucket
+	mode          Mode
+	codec         encoding.Codec
+	readUnitSize  int
+	writeUnitSize int
+}
+
+// Set stores the given value for the given key, when the write rate limit allows it.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	return s.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key, when the write rate limit allows it.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetContext(ctx context.Context, k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	// With a codec the value is marshalled here, so its size can be weighted.
+	n := 1
+	if s.codec != nil {
+		data, err := s.codec.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n = units(len(data), s.writeUnitSize)
+	}
+
+	if err := s.wait(ctx, s.writes, n); err != nil {
+		return err
+	}
+	return s.store.SetContext(ctx, k, v)
+}
+
+// Get retrieves the stored value for the given key, when the read rate limit allows it.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	return s.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key, when the read rate limit allows it.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	if err := s.wait(ctx, s.reads, 1); err != nil {
+		return false, err
+	}
+
+	found, err = s.store.GetContext(ctx, k, v)
+	if err != nil || !found {
+		return false, err
+	}
+	if s.codec != nil {
+		// The wrapped store doesn't return the data, so the value is marshalled again
+		if data, err := s.codec.Marshal(v); err == nil {
+			s.charge(s.reads, len(data))
+		}
+	}
+	return true, nil
+}
+
+// Delete deletes the stored value for the given key, when the write rate limit allows it.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	return s.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key, when the write rate limit allows it.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	if err := s.wait(ctx, s.writes, 1); err != nil {
+		return err
+	}
+	return s.store.DeleteContext(ctx, k)
+}
+
+// Close closes the wrapped store, without rate limiting.
+func (s Store) Close() error {
+	return s.store.Close()
+}
+
+// CloseContext closes the wrapped store, without rate limiting.
+func (s Store) CloseContext(ctx context.Context) error {
+	return s.store.CloseContext(ctx)
+}
+
+// wait takes n units from the bucket, waiting until they're available in ModeBlock.
+// A nil bucket means that there's no rate limit.
+func (s Store) wait(ctx context.Context, b *bucket, n int) error {
+	if b == nil {
+		return nil
+	}
+	if s.mode == ModeFailFast {
+		if !b.tryTake(float64(n)) {
+			return ErrRateLimited
+		}
+		return nil
+	}
+
+	delay := b.reserve(float64(n))
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		b.giveBack(float64(n))
+		return fmt.Errorf("waiting for the rate limit was stopped: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// charge takes the units of a read value of the given size, except for the one that was already taken.
+func (s Store) charge(b *bucket, size int) {
+	if b == nil {
+		return
+	}
+	if n := units(size, s.readUnitSize) - 1; n > 0 {
+		b.charge(float64(n))
+	}
+}
+
+// units returns the number of started units of the given size, but at least 1.
+func units(size, unitSize int) int {
+	if size <= unitSize {
+		return 1
+	}
+	return (size + unitSize - 1) / unitSize
+}
+
+// Options are the options for the ratelimit store.
+type Options struct {
+	// The store whose calls are rate limited, for example a dynamodb.Client.
+	// Required.
+	Store gokv.Store
+	// Number of read units per second, for example the ReadCapacityUnits of a DynamoDB table.
+	// Optional (0 by default, which means that reads aren't rate limited),
+	// but ReadRate and WriteRate must not both be 0.
+	ReadRate float64
+	// Maximum number of read units that can be used at once after a period of fewer reads.
+	// Optional (ReadRate rounded up by default).
+	ReadBurst int
+	// Number of write units per second, for example the WriteCapacityUnits of a DynamoDB table.
+	// Optional (0 by default, which means that writes aren't rate limited),
+	// but ReadRate and WriteRate must not both be 0.
+	WriteRate float64
+	// Maximum number of write units that can be used at once after a period of fewer writes.
+	// Optional (WriteRate rounded up by default).
+	WriteBurst int
+	// What happens when a call exceeds the rate limit.
+	// Optional (ModeBlock by default).
+	Mode Mode
+	// The codec that the wrapped store uses, for weighting the calls by the size of the values.
+	// The values are marshalled with it an additional time, only for measuring their size.
+	// Optional (nil by default, which means that each call takes one unit).
+	Codec encoding.Codec
+	// Size in bytes of a read unit, when weighting the calls with the Codec.
+	// Optional (4096 by default, the size of a DynamoDB read capacity unit).
+	ReadUnitSize int
+	// Size in bytes of a write unit, when weighting the calls with the Codec.
+	// Optional (1024 by default, the size of a DynamoDB write capacity unit).
+	// Alibaba Cloud Table Store uses 4096 bytes for both read and write capacity units.
+	WriteUnitSize int
+}
+
+// DefaultOptions is an Options object with default values.
+// ReadRate: 0, WriteRate: 0, Mode: ModeBlock, Codec: nil, ReadUnitSize: 4096, WriteUnitSize: 1024
+var DefaultOptions = Options{
+	Mode:          ModeBlock,
+	ReadUnitSize:  4096,
+	WriteUnitSize: 1024,
+	// No need to set Store because it's required and doesn't have a default,
+	// no need to set the rates because at least one of them is required,
+	// and the default bursts depend on the rates.
+}
+
+// NewStore creates a new ratelimit store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+	if !isValidRate(options.ReadRate) || !isValidRate(options.WriteRate) {
+		return result, fmt.Errorf("the ReadRate and WriteRate in the options must be finite and not negative, but were %v and %v", options.ReadRate, options.WriteRate)
+	}
+	if options.ReadRate == 0 && options.WriteRate == 0 {
+		return result, errors.New("the ReadRate and WriteRate in the options must not both be 0")
+	}
+	if options.ReadBurst < 0 || options.WriteBurst < 0 || options.ReadUnitSize < 0 || options.WriteUnitSize < 0 {
+		return result, errors.New("the ReadBurst, WriteBurst, ReadUnitSize and WriteUnitSize in the options must not be negative")
+	}
+	if options.Mode < ModeBlock || options.Mode > ModeFailFast {
+		return result, fmt.Errorf("invalid Mode %d", options.Mode)
+	}
+
+	// Set default values
+	if options.ReadBurst == 0 {
+		options.ReadBurst = int(math.Ceil(options.ReadRate))
+	}
+	if options.WriteBurst == 0 {
+		options.WriteBurst = int(math.Ceil(options.WriteRate))
+	}
+	if options.ReadUnitSize == 0 {
+		options.ReadUnitSize = DefaultOptions.ReadUnitSize
+	}
+	if options.WriteUnitSize == 0 {
+		options.WriteUnitSize = DefaultOptions.WriteUnitSize
+	}
+
+	result.store = gokv.AsContextStore(options.Store)
+	if options.ReadRate > 0 {
+		result.reads = newBucket(options.ReadRate, options.ReadBurst)
+	}
+	if options.WriteRate > 0 {
+		result.writes = newBucket(options.WriteRate, options.WriteBurst)
+	}
+	result.mode = options.Mode
+	result.codec = options.Codec
+	result.readUnitSize = options.ReadUnitSize
+	result.writeUnitSize = options.WriteUnitSize
+
+	return result, nil
+}
+
+func isValidRate(rate float64) bool {
+	return rate >= 0 && !math.IsInf(rate, 1)
+}
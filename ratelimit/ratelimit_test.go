@@ -0,0 +1,324 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/ratelimit"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, encoding.Gob)
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, encoding.JSON)
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, encoding.Gob)
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestFailFast tests if calls that exceed the rate limit fail with ErrRateLimited in ModeFailFast,
+// and if reads and writes are limited separately.
+func TestFailFast(t *testing.T) {
+	store, err := ratelimit.NewStore(ratelimit.Options{
+		Store:      gomap.NewStore(gomap.DefaultOptions),
+		ReadRate:   1,
+		ReadBurst:  3,
+		WriteRate:  1,
+		WriteBurst: 2,
+		Mode:       ratelimit.ModeFailFast,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = store.Delete("foo")
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, but was: %v", err)
+	}
+	if !gokv.IsUnavailable(err) {
+		t.Error("Expected ErrRateLimited to be classified as gokv.ErrUnavailable")
+	}
+
+	// The writes didn't use up the reads
+	for i := 0; i < 3; i++ {
+		_, err = store.Get("foo", new(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err = store.Get("foo", new(string))
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, but was: %v", err)
+	}
+}
+
+// TestBlock tests if calls that exceed the rate limit wait in ModeBlock,
+// and if reads aren't limited when only the WriteRate is set.
+func TestBlock(t *testing.T) {
+	store, err := ratelimit.NewStore(ratelimit.Options{
+		Store:     gomap.NewStore(gomap.DefaultOptions),
+		WriteRate: 20,
+		// One call every 50ms
+		WriteBurst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		err = store.Set("foo", "bar")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("Expected the writes to take at least 150ms, but they took %v", elapsed)
+	}
+
+	start = time.Now()
+	for i := 0; i < 100; i++ {
+		_, err = store.Get("foo", new(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected the reads not to be limited, but they took %v", elapsed)
+	}
+}
+
+// TestContext tests if waiting for the rate limit stops when the context is done,
+// and if the units of the stopped call are given back.
+func TestContext(t *testing.T) {
+	store, err := ratelimit.NewStore(ratelimit.Options{
+		Store:      gomap.NewStore(gomap.DefaultOptions),
+		WriteRate:  5,
+		WriteBurst: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = store.DeleteContext(ctx, "foo")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, but was: %v", err)
+	}
+
+	// Without giving back the units this would wait for 400ms
+	start := time.Now()
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Expected the call to wait for about 200ms, but it took %v", elapsed)
+	}
+}
+
+// TestValueSizes tests if the calls are weighted by the size of the values with a codec.
+func TestValueSizes(t *testing.T) {
+	wrapped := gomap.NewStore(gomap.DefaultOptions)
+	store, err := ratelimit.NewStore(ratelimit.Options{
+		Store:      wrapped,
+		ReadRate:   1,
+		ReadBurst:  3,
+		WriteRate:  1,
+		WriteBurst: 4,
+		Mode:       ratelimit.ModeFailFast,
+		Codec:      encoding.JSON,
+		// A 30 byte value takes 3 units
+		ReadUnitSize:  10,
+		WriteUnitSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 28 characters plus quotes
+	value := strings.Repeat("a", 28)
+	err = store.Set("foo", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("foo", value)
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, but was: %v", err)
+	}
+	// Small values take one unit
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set("bar", value)
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, but was: %v", err)
+	}
+
+	// Reading the value takes the remaining units afterwards
+	err = wrapped.Set("foo", value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := ""
+	found, err := store.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual != value {
+		t.Errorf("Expected %q, but was %q (found: %v)", value, actual, found)
+	}
+	_, err = store.Get("foo", &actual)
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, but was: %v", err)
+	}
+}
+
+// TestLargeValues tests if values that take more units than the burst don't fail forever in ModeFailFast.
+func TestLargeValues(t *testing.T) {
+	store, err := ratelimit.NewStore(ratelimit.Options{
+		Store:         gomap.NewStore(gomap.DefaultOptions),
+		WriteRate:     1,
+		WriteBurst:    2,
+		Mode:          ratelimit.ModeFailFast,
+		Codec:         encoding.JSON,
+		WriteUnitSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Set("foo", strings.Repeat("a", 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Delete("foo")
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, but was: %v", err)
+	}
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store := createStore(t, encoding.JSON)
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+
+	// Test invalid options
+	wrapped := gomap.NewStore(gomap.DefaultOptions)
+	invalidOptions := []ratelimit.Options{
+		{ReadRate: 1},
+		{Store: wrapped},
+		{Store: wrapped, ReadRate: -1},
+		{Store: wrapped, WriteRate: math.Inf(1)},
+		{Store: wrapped, ReadRate: math.NaN()},
+		{Store: wrapped, ReadRate: 1, ReadBurst: -1},
+		{Store: wrapped, ReadRate: 1, WriteUnitSize: -1},
+		{Store: wrapped, ReadRate: 1, Mode: 2},
+	}
+	for _, options := range invalidOptions {
+		_, err = ratelimit.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store := createStore(t, encoding.JSON)
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// createStore creates a store with rate limits that are high enough for the generic tests.
+func createStore(t *testing.T, codec encoding.Codec) ratelimit.Store {
+	options := ratelimit.Options{
+		Store:     gomap.NewStore(gomap.Options{Codec: codec}),
+		ReadRate:  1000000,
+		WriteRate: 1000000,
+		Codec:     codec,
+	}
+	store, err := ratelimit.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
@@ -0,0 +1,20 @@
+package gokv
+
+// RawStore is a Store that can store and retrieve the bytes of values directly, bypassing the codec.
+// This is useful when the values are already serialized, for example when proxying, migrating or checksumming,
+// where marshalling them again would be wasteful or even wrong
+// (encoding.JSON for example marshals a []byte to a base64 encoded string).
+//
+// The data is what Set() stores after marshalling a value with the store's codec,
+// so a value that's stored with Set() can be retrieved with GetRaw() and vice versa,
+// as long as the data is in the format of the codec.
+type RawStore interface {
+	Store
+	// SetRaw stores the given data for the given key, without marshalling it.
+	// The key must not be "" and the data must not be nil.
+	SetRaw(k string, data []byte) error
+	// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+	// If no value is found it returns (nil, false, nil).
+	// The key must not be "".
+	GetRaw(k string) (data []byte, found bool, err error)
+}
@@ -1,4 +1,7 @@
 /*
 Package redis contains an implementation of the `gokv.Store` interface for Redis.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"redis://:password@localhost:6379/0?timeout=2s&codec=gob".
 */
 package redis
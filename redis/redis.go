@@ -2,10 +2,17 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -23,6 +30,12 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to Redis.
+// The configured timeout still applies.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -36,16 +49,51 @@ func (c Client) Set(k string, v any) error {
 		return err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	tctx, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 
 	err = c.c.Set(tctx, k, string(data), 0).Err()
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	return nil
 }
 
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	return classifyError(c.c.Set(tctx, k, string(data), 0).Err())
+}
+
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (c Client) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	return classifyError(c.c.Set(tctx, k, string(data), ttl).Err())
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -53,11 +101,17 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to Redis.
+// The configured timeout still applies.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	tctx, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 
 	dataString, err := c.c.Get(tctx, k).Result()
@@ -65,25 +119,418 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 		if err == redis.Nil {
 			return false, nil
 		}
-		return false, err
+		return false, classifyError(err)
 	}
 
 	return true, c.codec.Unmarshal([]byte(dataString), v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	dataString, err := c.c.Get(tctx, k).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, false, nil
+		}
+		return nil, false, classifyError(err)
+	}
+
+	return []byte(dataString), true, nil
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version.
+// Redis doesn't have version numbers, so the version is a hash of the stored value.
+// If no value is found it returns (0, false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	dataString, err := c.c.Get(tctx, k).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, false, nil
+		}
+		return 0, false, classifyError(err)
+	}
+
+	return hashVersion(dataString), true, c.codec.Unmarshal([]byte(dataString), v)
+}
+
+// SetIfVersion stores the given value for the given key in a transaction (WATCH/MULTI/EXEC),
+// but only if the hash of the currently stored value is the given version (see GetVersioned()).
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match
+// or if the key was changed by another client during the transaction.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	err = c.c.Watch(tctx, func(tx *redis.Tx) error {
+		var currentVersion uint64
+		dataString, err := tx.Get(tctx, k).Result()
+		if err == nil {
+			currentVersion = hashVersion(dataString)
+		} else if err != redis.Nil {
+			return err
+		}
+		if currentVersion != version {
+			return nil
+		}
+		_, err = tx.TxPipelined(tctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(tctx, k, string(data), 0)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		swapped = true
+		return nil
+	}, k)
+	if err == redis.TxFailedErr {
+		return false, nil
+	} else if err != nil {
+		return false, classifyError(err)
+	}
+	return swapped, nil
+}
+
+// hashVersion returns the version for the given stored value,
+// which is its 64-bit FNV-1a hash. 0 is reserved for non-existing keys.
+func hashVersion(dataString string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(dataString))
+	version := h.Sum64()
+	if version == 0 {
+		version = 1
+	}
+	return version
+}
+
+// SetNX stores the given value for the given key with Redis' SETNX command,
+// but only if the key doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	created, err = c.c.SetNX(tctx, k, string(data), 0).Result()
+	return created, classifyError(err)
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (c Client) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	// EXISTS returns the number of existing keys
+	n, err := c.c.Exists(tctx, k).Result()
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return n > 0, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to Redis.
+// The configured timeout still applies.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
 
-	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	tctx, cancel := context.WithTimeout(ctx, c.timeOut)
 	defer cancel()
 
 	_, err := c.c.Del(tctx, k).Result()
-	return err
+	return classifyError(err)
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (c Client) DeleteExisting(k string) (existed bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	// DEL returns the number of deleted keys
+	n, err := c.c.Del(tctx, k).Result()
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return n > 0, nil
+}
+
+// SetMulti stores all given key-value pairs with a single MSET command.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, c.codec.Marshal)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	pairs := make([]any, 0, 2*len(data))
+	for k, v := range data {
+		pairs = append(pairs, k, string(v))
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	return classifyError(c.c.MSet(tctx, pairs...).Err())
+}
+
+// GetMulti retrieves the stored values for the given keys with a single MGET command.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be "".
+func (c Client) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	vals, err := c.c.MGet(tctx, keys...).Result()
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for i, val := range vals {
+		// The value is nil if the key doesn't exist
+		dataString, ok := val.(string)
+		if !ok {
+			continue
+		}
+		v := newValue()
+		if err := c.codec.Unmarshal([]byte(dataString), v); err != nil {
+			return nil, err
+		}
+		result[keys[i]] = v
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys with a single DEL command.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+	defer cancel()
+
+	return classifyError(c.c.Del(tctx, keys...).Err())
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix.
+// The iteration stops when fn returns false.
+// The keys are looked up with SCAN, so the order is random and key-value pairs
+// that are added or deleted during the scan may or may not be passed to fn.
+// The configured timeout applies to each request to Redis, not to the whole scan.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	match := escapeGlob(prefix) + "*"
+	// SCAN can return a key multiple times
+	seen := make(map[string]struct{})
+	var cursor uint64
+	for {
+		tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+		keys, nextCursor, err := c.c.Scan(tctx, cursor, match, 100).Result()
+		cancel()
+		if err != nil {
+			return classifyError(err)
+		}
+
+		var newKeys []string
+		for _, k := range keys {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				newKeys = append(newKeys, k)
+			}
+		}
+		if len(newKeys) > 0 {
+			tctx, cancel := context.WithTimeout(context.Background(), c.timeOut)
+			vals, err := c.c.MGet(tctx, newKeys...).Result()
+			cancel()
+			if err != nil {
+				return classifyError(err)
+			}
+			for i, val := range vals {
+				// The value is nil if the key was deleted in the meantime
+				dataString, ok := val.(string)
+				if !ok {
+					continue
+				}
+				decode := func(v any) error {
+					return c.codec.Unmarshal([]byte(dataString), v)
+				}
+				if !fn(newKeys[i], decode) {
+					return nil
+				}
+			}
+		}
+
+		if nextCursor == 0 {
+			return nil
+		}
+		cursor = nextCursor
+	}
+}
+
+// Watch sends an event to the returned channel for each change of the given key,
+// using Redis' keyspace notifications.
+// They must be enabled in the server configuration, for example with
+// "CONFIG SET notify-keyspace-events Kg$xe" (or "KA" for all events).
+// Keyspace notifications don't contain the value, so it's retrieved when the event's Decode function is called.
+// The events' Revision is always 0.
+// Watching stops and the channel is closed when the context is canceled.
+// The key must not be "".
+func (c Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return c.watch(ctx, escapeGlob(k))
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+func (c Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return c.watch(ctx, escapeGlob(prefix)+"*")
+}
+
+// watch subscribes to the keyspace notifications for the keys that match the given pattern.
+func (c Client) watch(ctx context.Context, pattern string) (<-chan gokv.Event, error) {
+	channelPrefix := "__keyspace@" + strconv.Itoa(c.c.Options().DB) + "__:"
+	pubSub := c.c.PSubscribe(ctx, channelPrefix+pattern)
+	// Wait for the confirmation of the subscription, so that no change after this method returns is missed
+	if _, err := pubSub.Receive(ctx); err != nil {
+		_ = pubSub.Close()
+		return nil, classifyError(err)
+	}
+
+	events := make(chan gokv.Event)
+	go func() {
+		defer close(events)
+		defer func() { _ = pubSub.Close() }()
+		msgs := pubSub.Channel()
+		for {
+			var msg *redis.Message
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok = <-msgs:
+				if !ok {
+					return
+				}
+			}
+
+			// The notification's payload is the name of the command or event
+			key := strings.TrimPrefix(msg.Channel, channelPrefix)
+			event := gokv.Event{
+				Key: key,
+			}
+			switch msg.Payload {
+			case "set", "rename_to", "restore", "copy_to":
+				event.Type = gokv.EventSet
+				event.Decode = func(v any) error {
+					found, err := c.Get(key, v)
+					if err == nil && !found {
+						return gokv.ErrValueUnavailable
+					}
+					return err
+				}
+			case "del", "expired", "evicted", "rename_from":
+				event.Type = gokv.EventDelete
+			default:
+				// For example "expire", which doesn't change the value
+				continue
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// escapeGlob escapes the characters that have a special meaning in Redis' glob-style patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '^', '-', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
 // Close closes the client.
@@ -92,6 +539,12 @@ func (c Client) Close() error {
 	return c.c.Close()
 }
 
+// CloseContext is like Close.
+// The context is ignored, because closing the connection pool doesn't block.
+func (c Client) CloseContext(_ context.Context) error {
+	return c.Close()
+}
+
 // Options are the options for the Redis client.
 type Options struct {
 	// Address of the Redis server, including the port.
@@ -148,7 +601,7 @@ func NewClient(options Options) (Client, error) {
 
 	err := client.Ping(tctx).Err()
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	result.c = client
@@ -157,3 +610,57 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("redis", open)
+}
+
+// open creates a client from a URL like "redis://:password@localhost:6379/0?timeout=2s&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		Address: u.Host,
+		Codec:   q.Codec(),
+	}
+	if password, ok := u.User.Password(); ok {
+		options.Password = password
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		var err error
+		if options.DB, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("invalid DB number %q in the URL path: %w", db, err)
+		}
+	}
+	if q.Has("timeout") {
+		timeout := q.Duration("timeout")
+		options.Timeout = &timeout
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the Redis client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, redis.TxFailedErr):
+		return gokv.WrapError(gokv.ErrConflict, err)
+	case errors.Is(err, redis.ErrPoolTimeout):
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case errors.Is(err, redis.ErrClosed), errors.Is(err, redis.ErrPoolExhausted):
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	// Errors that Redis returns while it's loading the dataset or while a cluster is reconfigured
+	if strings.HasPrefix(err.Error(), "LOADING ") || strings.HasPrefix(err.Error(), "MASTERDOWN ") ||
+		strings.HasPrefix(err.Error(), "CLUSTERDOWN ") || strings.HasPrefix(err.Error(), "TRYAGAIN ") {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	return util.ClassifyError(err)
+}
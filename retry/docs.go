@@ -0,0 +1,11 @@
+/*
+Package retry contains an implementation of the `gokv.Store` interface that retries the failed operations
+of any other store with an exponential backoff and jitter.
+
+This helps with transient errors, for example during leader elections of Consul or etcd,
+or when DynamoDB throttles requests. Which errors are retried is decided by a pluggable classifier.
+The default one (IsRetryable) retries the errors that the implementations classify as
+`gokv.ErrTimeout`, `gokv.ErrUnavailable` or `gokv.ErrConflict`, network errors and AWS throttling errors.
+Invalid arguments and errors of the codec are never retried.
+*/
+package retry
@@ -0,0 +1,294 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/util"
+)
+
+// Store is a gokv.Store that retries the failed operations of the wrapped store
+// with an exponential backoff and jitter, as long as the errors are retryable.
+//
+// It implements gokv.ContextStore. The context is passed on to the wrapped store if it implements gokv.ContextStore,
+// and the retries stop as soon as the context is done, even while waiting for the next attempt.
+// Close() isn't retried, because most stores can't be closed a second time.
+type Store struct {
+	store           gokv.ContextStore
+	maxAttempts     int
+	maxElapsedTime  time.Duration
+	initialInterval time.Duration
+	maxInterval     time.Duration
+	multiplier      float64
+	jitter          float64
+	retryable       func(err error) bool
+}
+
+// Set stores the given value for the given key, retrying failed attempts.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	return s.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key, retrying failed attempts until the context is done.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetContext(ctx context.Context, k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	return s.do(ctx, func() error {
+		return s.store.SetContext(ctx, k, v)
+	})
+}
+
+// Get retrieves the stored value for the given key, retrying failed attempts.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	return s.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key, retrying failed attempts until the context is done.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+	err = s.do(ctx, func() error {
+		var err error
+		found, err = s.store.GetContext(ctx, k, v)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// Delete deletes the stored value for the given key, retrying failed attempts.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	return s.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key, retrying failed attempts until the context is done.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+	return s.do(ctx, func() error {
+		return s.store.DeleteContext(ctx, k)
+	})
+}
+
+// Close closes the wrapped store, without retrying.
+func (s Store) Close() error {
+	return s.store.Close()
+}
+
+// CloseContext closes the wrapped store, without retrying.
+func (s Store) CloseContext(ctx context.Context) error {
+	return s.store.CloseContext(ctx)
+}
+
+// do calls fn until it succeeds, its error isn't retryable, the maximum number of attempts
+// or the maximum elapsed time is reached, or the context is done.
+// It returns the error of the last attempt.
+func (s Store) do(ctx context.Context, fn func() error) error {
+	start := time.Now()
+	interval := s.initialInterval
+	if interval > s.maxInterval {
+		interval = s.maxInterval
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if attempt >= s.maxAttempts || ctx.Err() != nil || !s.isRetryable(err) {
+			return err
+		}
+
+		delay := s.delay(interval)
+		if s.maxElapsedTime > 0 && time.Since(start)+delay > s.maxElapsedTime {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("retrying was stopped: %w (error of attempt %d: %w)", ctx.Err(), attempt, err)
+		case <-timer.C:
+		}
+
+		interval = time.Duration(float64(interval) * s.multiplier)
+		if interval > s.maxInterval {
+			interval = s.maxInterval
+		}
+	}
+}
+
+// delay returns the interval, randomized by the jitter factor.
+func (s Store) delay(interval time.Duration) time.Duration {
+	if s.jitter == 0 {
+		return interval
+	}
+	// Between interval*(1-jitter) and interval*(1+jitter)
+	delta := s.jitter * float64(interval)
+	return time.Duration(float64(interval) - delta + rand.Float64()*2*delta)
+}
+
+// isRetryable returns false for invalid arguments and codec errors, and the result of the classifier otherwise.
+func (s Store) isRetryable(err error) bool {
+	if errors.Is(err, gokv.ErrKeyEmpty) || errors.Is(err, gokv.ErrValueNil) || gokv.IsEncode(err) || gokv.IsDecode(err) {
+		return false
+	}
+	return s.retryable(err)
+}
+
+// Error codes of AWS services for throttling and temporary failures,
+// for stores that don't classify them (see gokv.ErrUnavailable).
+var awsRetryableCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"ThrottlingException":                    true,
+	"Throttling":                             true,
+	"RequestLimitExceeded":                   true,
+	"RequestThrottled":                       true,
+	"SlowDown":                               true,
+	"TooManyRequestsException":               true,
+	"InternalServerError":                    true,
+	"InternalError":                          true,
+	"ServiceUnavailable":                     true,
+	"RequestTimeout":                         true,
+	"TransactionConflictException":           true,
+}
+
+// IsRetryable is the default classifier of retryable errors.
+// It returns true for errors that are classified as gokv.ErrTimeout, gokv.ErrUnavailable or gokv.ErrConflict,
+// which the implementations use for network errors, timeouts, throttling (like DynamoDB's
+// ProvisionedThroughputExceededException) and transaction conflicts.
+// For stores that don't classify their errors it additionally detects network errors
+// (see util.ClassifyError()) and the error codes of AWS services for throttling and temporary failures.
+// Other errors, for example of marshalling a value, aren't retryable.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	err = util.ClassifyError(err)
+	if gokv.IsTimeout(err) || gokv.IsUnavailable(err) || gokv.IsConflict(err) {
+		return true
+	}
+	var codeErr interface{ Code() string }
+	if errors.As(err, &codeErr) {
+		return awsRetryableCodes[codeErr.Code()]
+	}
+	return false
+}
+
+// Options are the options for the retry store.
+type Options struct {
+	// The store whose failed operations are retried.
+	// Required.
+	Store gokv.Store
+	// Maximum number of attempts, including the first one.
+	// Optional (5 by default).
+	MaxAttempts int
+	// Maximum time from the start of the first attempt after which no further attempt is started.
+	// Optional (0 by default, which means that only MaxAttempts limits the retries).
+	MaxElapsedTime time.Duration
+	// Interval between the first and the second attempt.
+	// Optional (100 * time.Millisecond by default).
+	InitialInterval time.Duration
+	// Maximum interval between two attempts.
+	// Optional (10 * time.Second by default).
+	MaxInterval time.Duration
+	// Factor by which the interval grows after each attempt.
+	// Must not be less than 1.
+	// Optional (2 by default).
+	Multiplier float64
+	// Randomization factor of the intervals, between 0 and 1.
+	// An interval i is randomized to a value between i*(1-Jitter) and i*(1+Jitter),
+	// so that clients that failed at the same time don't retry at the same time.
+	// Optional (0.5 by default).
+	Jitter *float64
+	// Function that decides if a failed attempt is retried.
+	// Invalid arguments and gokv.ErrEncode and gokv.ErrDecode errors are never retried.
+	// Optional (IsRetryable by default).
+	Retryable func(err error) bool
+}
+
+var defaultJitter = 0.5
+
+// DefaultOptions is an Options object with default values.
+// MaxAttempts: 5, MaxElapsedTime: 0, InitialInterval: 100 * time.Millisecond, MaxInterval: 10 * time.Second,
+// Multiplier: 2, Jitter: 0.5, Retryable: IsRetryable
+var DefaultOptions = Options{
+	MaxAttempts:     5,
+	InitialInterval: 100 * time.Millisecond,
+	MaxInterval:     10 * time.Second,
+	Multiplier:      2,
+	Jitter:          &defaultJitter,
+	Retryable:       IsRetryable,
+	// No need to set Store because it's required and doesn't have a default,
+	// and no need to set MaxElapsedTime because its zero value is fine.
+}
+
+// NewStore creates a new retry store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+	if options.MaxAttempts < 0 || options.MaxElapsedTime < 0 || options.InitialInterval < 0 || options.MaxInterval < 0 {
+		return result, errors.New("the MaxAttempts, MaxElapsedTime, InitialInterval and MaxInterval in the options must not be negative")
+	}
+	if options.Multiplier != 0 && options.Multiplier < 1 {
+		return result, fmt.Errorf("the Multiplier in the options must not be less than 1, but was %v", options.Multiplier)
+	}
+	if options.Jitter != nil && (*options.Jitter < 0 || *options.Jitter > 1) {
+		return result, fmt.Errorf("the Jitter in the options must be between 0 and 1, but was %v", *options.Jitter)
+	}
+
+	// Set default values
+	if options.MaxAttempts == 0 {
+		options.MaxAttempts = DefaultOptions.MaxAttempts
+	}
+	if options.InitialInterval == 0 {
+		options.InitialInterval = DefaultOptions.InitialInterval
+	}
+	if options.MaxInterval == 0 {
+		options.MaxInterval = DefaultOptions.MaxInterval
+	}
+	if options.Multiplier == 0 {
+		options.Multiplier = DefaultOptions.Multiplier
+	}
+	if options.Jitter == nil {
+		options.Jitter = DefaultOptions.Jitter
+	}
+	if options.Retryable == nil {
+		options.Retryable = DefaultOptions.Retryable
+	}
+
+	result.store = gokv.AsContextStore(options.Store)
+	result.maxAttempts = options.MaxAttempts
+	result.maxElapsedTime = options.MaxElapsedTime
+	result.initialInterval = options.InitialInterval
+	result.maxInterval = options.MaxInterval
+	result.multiplier = options.Multiplier
+	result.jitter = *options.Jitter
+	result.retryable = options.Retryable
+
+	return result, nil
+}
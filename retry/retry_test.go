@@ -0,0 +1,378 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/retry"
+	"github.com/philippgille/gokv/test"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, retry.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, retry.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store := createStore(t, retry.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store := createStore(t, retry.Options{}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store := createStore(t, retry.Options{}, gomap.NewStore(gomap.DefaultOptions))
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestRetries tests if failed operations are retried until they succeed.
+func TestRetries(t *testing.T) {
+	wrapped := &flakyStore{Store: gomap.NewStore(gomap.DefaultOptions), err: errUnavailable}
+	store := createStore(t, retry.Options{InitialInterval: time.Millisecond}, wrapped)
+
+	wrapped.failures.Store(2)
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAttempts(t, wrapped, 3)
+
+	wrapped.failures.Store(4)
+	actual := ""
+	found, err := store.Get("foo", &actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || actual != "bar" {
+		t.Errorf("Expected %q, but was %q (found: %v)", "bar", actual, found)
+	}
+	checkAttempts(t, wrapped, 5)
+
+	wrapped.failures.Store(1)
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAttempts(t, wrapped, 2)
+}
+
+// TestMaxAttempts tests if the error of the last attempt is returned when the maximum number of attempts is reached.
+func TestMaxAttempts(t *testing.T) {
+	wrapped := &flakyStore{Store: gomap.NewStore(gomap.DefaultOptions), err: errUnavailable}
+	store := createStore(t, retry.Options{MaxAttempts: 3, InitialInterval: time.Millisecond}, wrapped)
+
+	wrapped.failures.Store(100)
+	err := store.Set("foo", "bar")
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	checkAttempts(t, wrapped, 3)
+
+	// Close isn't retried
+	err = store.Close()
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	checkAttempts(t, wrapped, 1)
+}
+
+// TestBackoff tests if the intervals between the attempts grow exponentially up to the MaxInterval,
+// and if no attempt is started after the MaxElapsedTime.
+func TestBackoff(t *testing.T) {
+	noJitter := 0.0
+	wrapped := &flakyStore{Store: gomap.NewStore(gomap.DefaultOptions), err: errUnavailable}
+	store := createStore(t, retry.Options{
+		MaxAttempts:     5,
+		InitialInterval: 20 * time.Millisecond,
+		MaxInterval:     50 * time.Millisecond,
+		Jitter:          &noJitter,
+	}, wrapped)
+
+	// 20ms + 40ms + 50ms + 50ms
+	wrapped.failures.Store(100)
+	start := time.Now()
+	err := store.Set("foo", "bar")
+	elapsed := time.Since(start)
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	checkAttempts(t, wrapped, 5)
+	if elapsed < 160*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected the attempts to take about 160ms, but they took %v", elapsed)
+	}
+
+	// The third attempt would start after 60ms
+	store = createStore(t, retry.Options{
+		MaxAttempts:     5,
+		MaxElapsedTime:  50 * time.Millisecond,
+		InitialInterval: 20 * time.Millisecond,
+		Jitter:          &noJitter,
+	}, wrapped)
+	err = store.Set("foo", "bar")
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	checkAttempts(t, wrapped, 2)
+}
+
+// TestContext tests if retrying stops when the context is done.
+func TestContext(t *testing.T) {
+	wrapped := &flakyStore{Store: gomap.NewStore(gomap.DefaultOptions), err: errUnavailable}
+	store := createStore(t, retry.Options{InitialInterval: time.Minute}, wrapped)
+
+	wrapped.failures.Store(100)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := store.SetContext(ctx, "foo", "bar")
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected the context error and the error of the wrapped store, but was: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected retrying to stop when the context is done, but it took %v", time.Since(start))
+	}
+	checkAttempts(t, wrapped, 1)
+
+	// A context that's done already isn't retried
+	_, err = store.GetContext(ctx, "foo", new(string))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error, but was: %v", err)
+	}
+}
+
+// TestNotRetryable tests if errors that aren't retryable are returned without retrying.
+func TestNotRetryable(t *testing.T) {
+	wrapped := &flakyStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	store := createStore(t, retry.Options{InitialInterval: time.Millisecond}, wrapped)
+
+	for _, err := range []error{
+		errors.New("some error"),
+		gokv.WrapError(gokv.ErrDecode, errors.New("invalid JSON")),
+	} {
+		wrapped.err = err
+		wrapped.failures.Store(1)
+		_, actual := store.Get("foo", new(string))
+		if actual != err {
+			t.Errorf("Expected the error of the wrapped store, but was: %v", actual)
+		}
+		checkAttempts(t, wrapped, 1)
+	}
+
+	// Codec errors
+	wrapped.failures.Store(0)
+	err := store.Set("foo", make(chan int))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	checkAttempts(t, wrapped, 1)
+
+	// Codec errors aren't retried even if the classifier says so
+	store = createStore(t, retry.Options{
+		InitialInterval: time.Millisecond,
+		Retryable:       func(err error) bool { return true },
+	}, wrapped)
+	err = store.Set("foo", make(chan int))
+	if !gokv.IsEncode(err) {
+		t.Errorf("Expected an encode error, but was: %v", err)
+	}
+	checkAttempts(t, wrapped, 1)
+	wrapped.err = gokv.WrapError(gokv.ErrDecode, errors.New("invalid JSON"))
+	wrapped.failures.Store(100)
+	_, err = store.Get("foo", new(string))
+	if !gokv.IsDecode(err) {
+		t.Errorf("Expected a decode error, but was: %v", err)
+	}
+	checkAttempts(t, wrapped, 1)
+}
+
+// TestIsRetryable tests the default classifier.
+func TestIsRetryable(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("some error"), false},
+		{errUnavailable, true},
+		{gokv.WrapError(gokv.ErrTimeout, errors.New("timeout")), true},
+		{gokv.WrapError(gokv.ErrConflict, errors.New("conflict")), true},
+		{gokv.WrapError(gokv.ErrDecode, errors.New("invalid JSON")), false},
+		{gokv.WrapError(gokv.ErrEncode, errors.New("unsupported type")), false},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{&net.DNSError{Err: "no such host", Name: "example.invalid"}, true},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{awsError{"ProvisionedThroughputExceededException"}, true},
+		{awsError{"ThrottlingException"}, true},
+		{awsError{"ValidationException"}, false},
+	}
+	for _, testCase := range testCases {
+		if actual := retry.IsRetryable(testCase.err); actual != testCase.expected {
+			t.Errorf("Expected %v for the error %q, but was %v", testCase.expected, testCase.err, actual)
+		}
+	}
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	wrapped := &flakyStore{Store: gomap.NewStore(gomap.DefaultOptions)}
+	store := createStore(t, retry.Options{}, wrapped)
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	checkAttempts(t, wrapped, 0)
+
+	// Test invalid options
+	invalidJitter := 1.5
+	invalidOptions := []retry.Options{
+		{},
+		{Store: wrapped, MaxAttempts: -1},
+		{Store: wrapped, InitialInterval: -time.Second},
+		{Store: wrapped, Multiplier: 0.5},
+		{Store: wrapped, Jitter: &invalidJitter},
+	}
+	for _, options := range invalidOptions {
+		_, err = retry.NewStore(options)
+		if err == nil {
+			t.Errorf("Expected an error for the options %+v", options)
+		}
+	}
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store := createStore(t, retry.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store := createStore(t, retry.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store := createStore(t, retry.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func createStore(t *testing.T, options retry.Options, wrapped gokv.Store) retry.Store {
+	options.Store = wrapped
+	store, err := retry.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// checkAttempts checks the number of calls of the flaky store since the last check.
+func checkAttempts(t *testing.T, store *flakyStore, expected int64) {
+	t.Helper()
+	if actual := store.calls.Swap(0); actual != expected {
+		t.Errorf("Expected %v attempts, but was %v", expected, actual)
+	}
+}
+
+var errUnavailable = gokv.WrapError(gokv.ErrUnavailable, errors.New("the leader is being elected"))
+
+// flakyStore is a gokv.Store whose calls fail with err as long as there are failures left.
+// It counts all calls.
+type flakyStore struct {
+	gokv.Store
+	err      error
+	failures atomic.Int64
+	calls    atomic.Int64
+}
+
+func (s *flakyStore) fail() error {
+	s.calls.Add(1)
+	if s.failures.Add(-1) >= 0 {
+		return s.err
+	}
+	return nil
+}
+
+func (s *flakyStore) Set(k string, v any) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.Store.Set(k, v)
+}
+
+func (s *flakyStore) Get(k string, v any) (found bool, err error) {
+	if err := s.fail(); err != nil {
+		return false, err
+	}
+	return s.Store.Get(k, v)
+}
+
+func (s *flakyStore) Delete(k string) error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.Store.Delete(k)
+}
+
+func (s *flakyStore) Close() error {
+	if err := s.fail(); err != nil {
+		return err
+	}
+	return s.Store.Close()
+}
+
+// awsError has the Code() method of the errors of the AWS SDK.
+type awsError struct {
+	code string
+}
+
+func (e awsError) Error() string {
+	return e.code + ": the request failed"
+}
+
+func (e awsError) Code() string {
+	return e.code
+}
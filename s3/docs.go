@@ -1,4 +1,7 @@
 /*
 Package s3 contains an implementation of the `gokv.Store` interface for Amazon S3 and other S3-compatible services.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"s3://accessKeyID:secretAccessKey@bucket?region=us-west-2&endpoint=http%3A%2F%2Flocalhost%3A9000&path_style=true&codec=gob" (the credentials are optional).
 */
 package s3
@@ -2,15 +2,20 @@ package s3
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
+	"net/http"
+	"net/url"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	awss3 "github.com/aws/aws-sdk-go/service/s3"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -26,6 +31,11 @@ type Client struct {
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for the request to S3.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -41,11 +51,31 @@ func (c Client) Set(k string, v any) error {
 		Bucket: &c.bucketName,
 		Key:    &k,
 	}
-	_, err = c.c.PutObject(&pubObjectInput)
+	_, err = c.c.PutObjectWithContext(ctx, &pubObjectInput)
 	if err != nil {
+		return classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	pubObjectInput := awss3.PutObjectInput{
+		Body:   bytes.NewReader(data),
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	_, err := c.c.PutObject(&pubObjectInput)
+	if err != nil {
+		return classifyError(err)
+	}
+
 	return nil
 }
 
@@ -56,39 +86,78 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for the request to S3.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
-	getObjectInput := awss3.GetObjectInput{
-		Bucket: &c.bucketName,
-		Key:    &k,
-	}
-	getObjectOutput, err := c.c.GetObject(&getObjectInput)
+	data, err := c.getData(ctx, k)
 	if err != nil {
-		aerr, ok := err.(awserr.Error)
-		if ok && aerr.Code() == awss3.ErrCodeNoSuchKey {
-			return false, nil
-		}
-		return false, err
+		return false, classifyError(err)
 	}
-	if getObjectOutput.Body == nil {
-		// Return false if there's no value
-		// TODO: Maybe return an error? Behaviour should be consistent across all implementations.
+	if data == nil {
 		return false, nil
 	}
-	data, err := io.ReadAll(getObjectOutput.Body)
+
+	return true, c.codec.Unmarshal(data, v)
+}
+
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	data, err = c.getData(context.Background(), k)
 	if err != nil {
-		return true, err
+		return nil, false, classifyError(err)
+	}
+	if data == nil {
+		return nil, false, nil
 	}
 
-	return true, c.codec.Unmarshal(data, v)
+	return data, true, nil
+}
+
+// Has returns true if an object is stored for the given key.
+// It only retrieves the object's metadata with a HEAD request.
+// The key must not be "".
+func (c Client) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	headObjectInput := awss3.HeadObjectInput{
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	_, err = c.c.HeadObject(&headObjectInput)
+	if err != nil {
+		// A HEAD response doesn't have a body, so there's no "NoSuchKey" error code, only the status code.
+		reqErr, ok := err.(awserr.RequestFailure)
+		if ok && reqErr.StatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, classifyError(err)
+	}
+	return true, nil
 }
 
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for the request to S3.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
@@ -97,8 +166,68 @@ func (c Client) Delete(k string) error {
 		Bucket: &c.bucketName,
 		Key:    &k,
 	}
-	_, err := c.c.DeleteObject(&deleteObjectInput)
-	return err
+	_, err := c.c.DeleteObjectWithContext(ctx, &deleteObjectInput)
+	return classifyError(err)
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The keys are listed page by page, and each value is retrieved with a separate request.
+// Key-value pairs that are deleted during the scan are skipped.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	listObjectsInput := awss3.ListObjectsV2Input{
+		Bucket: &c.bucketName,
+		Prefix: &prefix,
+	}
+	var innerErr error
+	err := c.c.ListObjectsV2Pages(&listObjectsInput, func(page *awss3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			k := aws.StringValue(object.Key)
+			var data []byte
+			data, innerErr = c.getData(context.Background(), k)
+			if innerErr != nil {
+				return false
+			}
+			if data == nil {
+				continue
+			}
+			decode := func(v any) error {
+				return c.codec.Unmarshal(data, v)
+			}
+			if !fn(k, decode) {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return classifyError(err)
+	}
+	return innerErr
+}
+
+// getData retrieves the raw value for the given key.
+// It returns nil if no value is found.
+func (c Client) getData(ctx context.Context, k string) ([]byte, error) {
+	getObjectInput := awss3.GetObjectInput{
+		Bucket: &c.bucketName,
+		Key:    &k,
+	}
+	getObjectOutput, err := c.c.GetObjectWithContext(ctx, &getObjectInput)
+	if err != nil {
+		aerr, ok := err.(awserr.Error)
+		if ok && aerr.Code() == awss3.ErrCodeNoSuchKey {
+			return nil, nil
+		}
+		return nil, classifyError(err)
+	}
+	if getObjectOutput.Body == nil {
+		// Return nil if there's no value
+		// TODO: Maybe return an error? Behaviour should be consistent across all implementations.
+		return nil, nil
+	}
+	defer getObjectOutput.Body.Close()
+	return io.ReadAll(getObjectOutput.Body)
 }
 
 // Close closes the client.
@@ -107,6 +236,12 @@ func (c Client) Close() error {
 	return nil
 }
 
+// CloseContext is like Close.
+// In the S3 implementation this doesn't have any effect.
+func (c Client) CloseContext(_ context.Context) error {
+	return nil
+}
+
 // Options are the options for the S3 client.
 type Options struct {
 	// Name of the S3 bucket.
@@ -216,7 +351,7 @@ func NewClient(options Options) (Client, error) {
 	sessionOpts.Config.MergeIn(config)
 	session, err := session.NewSessionWithOptions(sessionOpts)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 	svc := awss3.New(session)
 
@@ -227,7 +362,7 @@ func NewClient(options Options) (Client, error) {
 	origS3 := options.CustomEndpoint == ""
 	err = createBucket(origS3, svc, createBucketInput, options.BucketName)
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	result.c = svc
@@ -237,6 +372,35 @@ func NewClient(options Options) (Client, error) {
 	return result, nil
 }
 
+func init() {
+	gokv.Register("s3", open)
+}
+
+// open creates a client from a URL like "s3://accessKeyID:secretAccessKey@bucket?region=us-west-2&codec=gob".
+// The credentials are optional, the other query parameters are "endpoint" and "path_style".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		BucketName:             u.Host,
+		Region:                 q.String("region"),
+		CustomEndpoint:         q.String("endpoint"),
+		UsePathStyleAddressing: q.Bool("path_style"),
+		Codec:                  q.Codec(),
+	}
+	if u.User != nil {
+		options.AWSaccessKeyID = u.User.Username()
+		options.AWSsecretAccessKey, _ = u.User.Password()
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
 // createBucket creates the bucket if it doesn't exist yet.
 //
 // When using actual Amazon S3, and trying to create a bucket you already own,
@@ -252,13 +416,13 @@ func createBucket(origS3 bool, svc *awss3.S3, createBucketInput awss3.CreateBuck
 		if err != nil {
 			aerr, ok := err.(awserr.Error)
 			if !ok || aerr.Code() != awss3.ErrCodeBucketAlreadyOwnedByYou {
-				return err
+				return classifyError(err)
 			}
 		}
 	} else {
 		listBucketsOutput, err := svc.ListBuckets(&awss3.ListBucketsInput{})
 		if err != nil {
-			return err
+			return classifyError(err)
 		}
 		ownsBucket := false
 		if listBucketsOutput.Buckets != nil {
@@ -272,10 +436,39 @@ func createBucket(origS3 bool, svc *awss3.S3, createBucketInput awss3.CreateBuck
 		if !ownsBucket {
 			_, err = svc.CreateBucket(&createBucketInput)
 			if err != nil {
-				return err
+				return classifyError(err)
 			}
 		}
 	}
 
 	return nil
 }
+
+// classifyError wraps errors of the AWS SDK with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		return util.ClassifyError(err)
+	}
+	var reqErr awserr.RequestFailure
+	switch {
+	case awsErr.Code() == request.ErrCodeResponseTimeout, awsErr.Code() == "RequestTimeout":
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case request.IsErrorThrottle(awsErr), errors.As(err, &reqErr) && reqErr.StatusCode() >= 500:
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	case awsErr.Code() == "OperationAborted":
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	// The SDK wraps network errors, but its errors don't support errors.Is() and errors.As() for them
+	switch origErr := classifyError(awsErr.OrigErr()); {
+	case gokv.IsTimeout(origErr):
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	case gokv.IsUnavailable(origErr):
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	return err
+}
@@ -3,6 +3,7 @@ module github.com/philippgille/gokv/sql
 go 1.20
 
 require (
+	github.com/philippgille/gokv v0.7.0
 	github.com/philippgille/gokv/encoding v0.7.0
 	github.com/philippgille/gokv/util v0.7.0
 )
@@ -0,0 +1,96 @@
+package sql
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/philippgille/gokv"
+)
+
+// NotifyFunctionQuery creates the PostgreSQL trigger function that sends a notification for each changed row,
+// unless it already exists. The channel is passed as argument by the trigger (see NotifyTriggerQuery()).
+// Notifications with a payload of 8000 bytes or more are rejected by PostgreSQL, so they're skipped.
+const NotifyFunctionQuery = `DO $do$
+BEGIN
+	CREATE FUNCTION gokv_notify() RETURNS trigger AS $fn$
+	DECLARE
+		payload TEXT;
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			payload := json_build_object('op', TG_OP, 'k', OLD.k)::text;
+		ELSE
+			payload := json_build_object('op', TG_OP, 'k', NEW.k)::text;
+		END IF;
+		IF octet_length(payload) < 8000 THEN
+			PERFORM pg_notify(TG_ARGV[0], payload);
+		END IF;
+		RETURN NULL;
+	END;
+	$fn$ LANGUAGE plpgsql;
+EXCEPTION WHEN duplicate_function THEN
+	NULL;
+END
+$do$`
+
+// NotifyTriggerQuery returns the query that creates the PostgreSQL trigger on the given table,
+// which calls the function of NotifyFunctionQuery for each changed row, unless it already exists.
+// The notifications are sent on the channel of NotifyChannel().
+func NotifyTriggerQuery(tableName string) string {
+	channel := strings.ReplaceAll(NotifyChannel(tableName), "'", "''")
+	return `DO $do$
+BEGIN
+	CREATE TRIGGER gokv_notify AFTER INSERT OR UPDATE OR DELETE ON ` + tableName + ` FOR EACH ROW EXECUTE PROCEDURE gokv_notify('` + channel + `');
+EXCEPTION WHEN duplicate_object THEN
+	NULL;
+END
+$do$`
+}
+
+// NotifyChannel returns the name of the channel on which changes of the given table are notified.
+// Channel names are identifiers, which are limited to 63 bytes.
+func NotifyChannel(tableName string) string {
+	channel := "gokv_" + tableName
+	if len(channel) > 63 {
+		channel = channel[:63]
+	}
+	return channel
+}
+
+// NotificationEvent converts the payload of a notification of the trigger to an event.
+// The event's Decode() retrieves the current value with get, because notifications only contain the key.
+// It returns false if the payload can't be converted.
+func NotificationEvent(payload string, get func(k string, v any) (found bool, err error)) (gokv.Event, bool) {
+	var n struct {
+		Op string `json:"op"`
+		K  string `json:"k"`
+	}
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return gokv.Event{}, false
+	}
+
+	switch n.Op {
+	case "INSERT", "UPDATE":
+		k := n.K
+		return gokv.Event{
+			Type: gokv.EventSet,
+			Key:  k,
+			Decode: func(v any) error {
+				found, err := get(k, v)
+				if err != nil {
+					return err
+				}
+				if !found {
+					return gokv.ErrValueUnavailable
+				}
+				return nil
+			},
+		}, true
+	case "DELETE":
+		return gokv.Event{
+			Type: gokv.EventDelete,
+			Key:  n.K,
+		}, true
+	default:
+		return gokv.Event{}, false
+	}
+}
@@ -1,25 +1,88 @@
 package sql
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Client is a gokv.Store implementation for SQL databases.
 type Client struct {
-	C          *sql.DB
+	C *sql.DB
+	// UpsertStmt is the statement that Set() uses.
+	// Its parameters are the key, value and version (in this order), see NewVersion().
+	// It must insert a new row with the given version, or update the value and increment the version of an existing row.
 	UpsertStmt *sql.Stmt
 	GetStmt    *sql.Stmt
 	DeleteStmt *sql.Stmt
-	Codec      encoding.Codec
+	// ScanPrefixStmt is the query that ScanPrefix() uses.
+	// It must select the key and value columns (in this order) of all rows
+	// whose key matches the given LIKE pattern, with "!" as escape character.
+	// See LikePrefixPattern() for the pattern.
+	// Optional (ScanPrefix() returns gokv.ErrNotSupported if not set).
+	ScanPrefixStmt *sql.Stmt
+	// UpsertMultiQuery returns the query that SetMulti() uses for storing n key-value pairs.
+	// The query's parameters are the keys, values and versions of new rows in alternating order (k1, v1, ver1, k2, v2, ver2, ...),
+	// and it must handle them like the UpsertStmt.
+	// Optional (SetMulti() executes the UpsertStmt for each key-value pair in a transaction if not set).
+	UpsertMultiQuery func(n int) string
+	// GetMultiQuery returns the query that GetMulti() uses for retrieving the values of n keys.
+	// It must select the key and value columns (in this order), the query's parameters are the keys.
+	// Optional (GetMulti() executes the GetStmt for each key if not set).
+	GetMultiQuery func(n int) string
+	// DeleteMultiQuery returns the query that DeleteMulti() uses for deleting n key-value pairs.
+	// The query's parameters are the keys.
+	// Optional (DeleteMulti() executes the DeleteStmt for each key in a transaction if not set).
+	DeleteMultiQuery func(n int) string
+	// GetVersionedStmt is the query that GetVersioned() uses.
+	// It must select the value and version columns (in this order) of the row with the given key.
+	// The version column must be an integer column that's never 0 (like "ver BIGINT NOT NULL DEFAULT 1"),
+	// with the versions of new rows from NewVersion().
+	// Optional (GetVersioned() returns gokv.ErrNotSupported if not set).
+	GetVersionedStmt *sql.Stmt
+	// CreateStmt is the statement that SetIfVersion() uses for version 0.
+	// It must insert the given key, value and version (in this order), but only if the key doesn't exist yet,
+	// in which case it must not affect any rows.
+	// Optional (SetIfVersion() returns gokv.ErrNotSupported if not set).
+	CreateStmt *sql.Stmt
+	// UpdateIfVersionStmt is the statement that SetIfVersion() uses for versions other than 0.
+	// Its parameters are the value, key and version (in this order).
+	// It must update the value and increment the version of the row with the given key and version.
+	// Optional (SetIfVersion() returns gokv.ErrNotSupported if not set).
+	UpdateIfVersionStmt *sql.Stmt
+	// HasStmt is the query that Has() uses.
+	// It must select a row with any column (like "SELECT 1") if the given key exists.
+	// Optional (Has() executes the GetStmt if not set).
+	HasStmt *sql.Stmt
+	// ClassifyError wraps the errors of the database driver with the matching gokv error
+	// (like gokv.ErrConflict for serialization failures), see gokv.WrapError().
+	// Errors that it doesn't classify must be returned as they are.
+	// Optional (only errors that are independent of the driver, like closed connections, are classified if not set).
+	ClassifyError func(err error) error
+	Codec         encoding.Codec
 }
 
+// maxBatchSize is the maximum number of key-value pairs per multi-row statement.
+// The limit keeps the number of parameters below the limits of the databases (65535 for example in MySQL and PostgreSQL).
+const maxBatchSize = 1000
+
 // Set stores the given value for the given key.
 // Values are automatically marshalled to JSON or gob (depending on the configuration).
 // The key must not be "" and the value must not be nil.
 func (c Client) Set(k string, v any) error {
+	return c.SetContext(context.Background(), k, v)
+}
+
+// SetContext is like Set, but uses the given context for executing the statement.
+func (c Client) SetContext(ctx context.Context, k string, v any) error {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return err
 	}
@@ -30,11 +93,26 @@ func (c Client) Set(k string, v any) error {
 		return err
 	}
 
-	_, err = c.UpsertStmt.Exec(k, data)
+	_, err = c.UpsertStmt.ExecContext(ctx, k, data, NewVersion())
 	if err != nil {
+		return c.classifyError(err)
+	}
+
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
 		return err
 	}
 
+	_, err := c.UpsertStmt.Exec(k, data, NewVersion())
+	if err != nil {
+		return c.classifyError(err)
+	}
+
 	return nil
 }
 
@@ -45,34 +123,475 @@ func (c Client) Set(k string, v any) error {
 // If no value is found it returns (false, nil).
 // The key must not be "" and the pointer must not be nil.
 func (c Client) Get(k string, v any) (found bool, err error) {
+	return c.GetContext(context.Background(), k, v)
+}
+
+// GetContext is like Get, but uses the given context for executing the query.
+func (c Client) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
 	if err := util.CheckKeyAndValue(k, v); err != nil {
 		return false, err
 	}
 
 	// TODO: Consider using RawBytes.
 	dataPtr := new([]byte)
-	err = c.GetStmt.QueryRow(k).Scan(dataPtr)
+	err = c.GetStmt.QueryRowContext(ctx, k).Scan(dataPtr)
 	// If no value was found return false
 	if err == sql.ErrNoRows {
 		return false, nil
 	} else if err != nil {
-		return false, err
+		return false, c.classifyError(err)
 	}
 	data := *dataPtr
 
 	return true, c.Codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	dataPtr := new([]byte)
+	err = c.GetStmt.QueryRow(k).Scan(dataPtr)
+	// If no value was found return false
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, c.classifyError(err)
+	}
+	return *dataPtr, true, nil
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (c Client) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	stmt := c.HasStmt
+	if stmt == nil {
+		stmt = c.GetStmt
+	}
+	// The selected column isn't needed, so the row isn't scanned
+	rows, err := stmt.Query(k)
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return true, nil
+	}
+	return false, c.classifyError(rows.Err())
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (c Client) Delete(k string) error {
+	return c.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext is like Delete, but uses the given context for executing the statement.
+func (c Client) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	_, err := c.DeleteStmt.ExecContext(ctx, k)
+	return c.classifyError(err)
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (c Client) DeleteExisting(k string) (existed bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	res, err := c.DeleteStmt.Exec(k)
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	return n > 0, nil
+}
+
+// SetMulti stores all given key-value pairs in a single transaction.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// No key must be "" and no value must be nil.
+func (c Client) SetMulti(kvs map[string]any) error {
+	data, err := util.MarshalMulti(kvs, c.Codec.Marshal)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	tx, err := c.C.Begin()
+	if err != nil {
+		return c.classifyError(err)
+	}
+	if c.UpsertMultiQuery == nil {
+		stmt := tx.Stmt(c.UpsertStmt)
+		for k, v := range data {
+			if _, err := stmt.Exec(k, v, NewVersion()); err != nil {
+				_ = tx.Rollback()
+				return c.classifyError(err)
+			}
+		}
+		return c.classifyError(tx.Commit())
+	}
+
+	args := make([]any, 0, 3*len(data))
+	for k, v := range data {
+		args = append(args, k, v, NewVersion())
+	}
+	for start := 0; start < len(args); start += 3 * maxBatchSize {
+		end := start + 3*maxBatchSize
+		if end > len(args) {
+			end = len(args)
+		}
+		chunk := args[start:end]
+		if _, err := tx.Exec(c.UpsertMultiQuery(len(chunk)/3), chunk...); err != nil {
+			_ = tx.Rollback()
+			return c.classifyError(err)
+		}
+	}
+	return c.classifyError(tx.Commit())
+}
+
+// GetMulti retrieves the stored values for the given keys.
+// For each key that's found, newValue is called to create a pointer to unmarshal the value into.
+// Keys that aren't found are missing from the returned map.
+// No key must be "".
+func (c Client) GetMulti(keys []string, newValue func() any) (map[string]any, error) {
+	if err := util.CheckKeys(keys); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(keys))
+	if c.GetMultiQuery == nil {
+		for _, k := range keys {
+			v := newValue()
+			found, err := c.Get(k, v)
+			if err != nil {
+				return nil, c.classifyError(err)
+			}
+			if found {
+				result[k] = v
+			}
+		}
+		return result, nil
+	}
+
+	for start := 0; start < len(keys); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := c.getChunk(keys[start:end], newValue, result); err != nil {
+			return nil, c.classifyError(err)
+		}
+	}
+	return result, nil
+}
+
+// getChunk retrieves the values for the given keys with a single query and puts them into the result.
+func (c Client) getChunk(keys []string, newValue func() any, result map[string]any) error {
+	args := make([]any, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	rows, err := c.C.Query(c.GetMultiQuery(len(keys)), args...)
+	if err != nil {
+		return c.classifyError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k string
+		var data []byte
+		if err := rows.Scan(&k, &data); err != nil {
+			return c.classifyError(err)
+		}
+		v := newValue()
+		if err := c.Codec.Unmarshal(data, v); err != nil {
+			return err
+		}
+		result[k] = v
+	}
+	return c.classifyError(rows.Err())
+}
+
+// DeleteMulti deletes the stored values for the given keys in a single transaction.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// No key must be "".
+func (c Client) DeleteMulti(keys []string) error {
+	if err := util.CheckKeys(keys); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	tx, err := c.C.Begin()
+	if err != nil {
+		return c.classifyError(err)
+	}
+	if c.DeleteMultiQuery == nil {
+		stmt := tx.Stmt(c.DeleteStmt)
+		for _, k := range keys {
+			if _, err := stmt.Exec(k); err != nil {
+				_ = tx.Rollback()
+				return c.classifyError(err)
+			}
+		}
+		return c.classifyError(tx.Commit())
+	}
+
+	for start := 0; start < len(keys); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		args := make([]any, 0, end-start)
+		for _, k := range keys[start:end] {
+			args = append(args, k)
+		}
+		if _, err := tx.Exec(c.DeleteMultiQuery(len(args)), args...); err != nil {
+			_ = tx.Rollback()
+			return c.classifyError(err)
+		}
+	}
+	return c.classifyError(tx.Commit())
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version from the version column.
+// If no value is found it returns (0, false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+	if c.GetVersionedStmt == nil {
+		return 0, false, gokv.WrapError(gokv.ErrNotSupported, errors.New("versioned reads aren't supported, because the client doesn't have a GetVersionedStmt"))
+	}
+
+	var data []byte
+	var ver int64
+	err = c.GetVersionedStmt.QueryRow(k).Scan(&data, &ver)
+	// If no value was found return false
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, c.classifyError(err)
+	}
+
+	return uint64(ver), true, c.Codec.Unmarshal(data, v)
+}
+
+// SetIfVersion stores the given value for the given key,
+// but only if the row's version matches the given version (see GetVersioned()).
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+	if c.CreateStmt == nil || c.UpdateIfVersionStmt == nil {
+		return false, gokv.WrapError(gokv.ErrNotSupported, errors.New("versioned writes aren't supported, because the client doesn't have a CreateStmt and UpdateIfVersionStmt"))
+	}
+
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	var res sql.Result
+	if version == 0 {
+		res, err = c.CreateStmt.Exec(k, data, NewVersion())
+	} else {
+		res, err = c.UpdateIfVersionStmt.Exec(data, k, int64(version))
+	}
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	return n > 0, nil
+}
+
+// SetNX stores the given value for the given key with the CreateStmt,
+// but only if the key doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+	if c.CreateStmt == nil {
+		return false, gokv.WrapError(gokv.ErrNotSupported, errors.New("create-only writes aren't supported, because the client doesn't have a CreateStmt"))
+	}
+
+	data, err := c.Codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := c.CreateStmt.Exec(k, data, NewVersion())
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, c.classifyError(err)
+	}
+	return n > 0, nil
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix,
+// in the order that the ScanPrefixStmt defines.
+// The iteration stops when fn returns false.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	if c.ScanPrefixStmt == nil {
+		return gokv.WrapError(gokv.ErrNotSupported, errors.New("prefix scans aren't supported, because the client doesn't have a ScanPrefixStmt"))
+	}
+
+	rows, err := c.ScanPrefixStmt.Query(LikePrefixPattern(prefix))
+	if err != nil {
+		return c.classifyError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k string
+		var data []byte
+		if err := rows.Scan(&k, &data); err != nil {
+			return c.classifyError(err)
+		}
+		// Depending on the collation LIKE can be case-insensitive (for example in MySQL),
+		// but the prefix is meant to be case-sensitive.
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		decode := func(v any) error {
+			return c.Codec.Unmarshal(data, v)
+		}
+		if !fn(k, decode) {
+			break
+		}
+	}
+	return c.classifyError(rows.Err())
+}
+
+// Update executes the given function within a database transaction,
+// with the client's prepared statements bound to the transaction.
+// If the function returns nil, the transaction is committed, otherwise it's rolled back.
+// The isolation level is the database's default.
+func (c Client) Update(fn func(tx gokv.Tx) error) error {
+	return c.runTx(&sql.TxOptions{}, fn)
+}
+
+// View executes the given function within a read-only database transaction.
+func (c Client) View(fn func(tx gokv.Tx) error) error {
+	return c.runTx(&sql.TxOptions{ReadOnly: true}, fn)
+}
+
+func (c Client) runTx(opts *sql.TxOptions, fn func(tx gokv.Tx) error) error {
+	tx, err := c.C.BeginTx(context.Background(), opts)
+	if err != nil {
+		return c.classifyError(err)
+	}
+	err = fn(clientTx{
+		tx:       tx,
+		c:        c,
+		readOnly: opts.ReadOnly,
+	})
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return c.classifyError(tx.Commit())
+}
+
+// clientTx is a gokv.Tx for a database transaction.
+type clientTx struct {
+	tx       *sql.Tx
+	c        Client
+	readOnly bool
+}
+
+func (t clientTx) Set(k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if t.readOnly {
+		return gokv.ErrReadOnlyTx
+	}
+
+	data, err := t.c.Codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = t.tx.Stmt(t.c.UpsertStmt).Exec(k, data, NewVersion())
+	return t.c.classifyError(err)
+}
+
+func (t clientTx) Get(k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	var data []byte
+	err = t.tx.Stmt(t.c.GetStmt).QueryRow(k).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, t.c.classifyError(err)
+	}
+	return true, t.c.Codec.Unmarshal(data, v)
+}
+
+func (t clientTx) Delete(k string) error {
 	if err := util.CheckKey(k); err != nil {
 		return err
 	}
+	if t.readOnly {
+		return gokv.ErrReadOnlyTx
+	}
 
-	_, err := c.DeleteStmt.Exec(k)
-	return err
+	_, err := t.tx.Stmt(t.c.DeleteStmt).Exec(k)
+	return t.c.classifyError(err)
+}
+
+// classifyError wraps the given error with the matching gokv error,
+// using ClassifyError for the errors of the database driver.
+func (c Client) classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	if c.ClassifyError != nil {
+		err = c.ClassifyError(err)
+	}
+	return util.ClassifyError(err)
 }
 
 // Close closes the client.
@@ -81,6 +600,78 @@ func (c Client) Close() error {
 	return c.C.Close()
 }
 
+// CloseContext is like Close.
+// The context is ignored, because database/sql doesn't support one for closing.
+func (c Client) CloseContext(_ context.Context) error {
+	return c.Close()
+}
+
+// NewVersion returns a random version for a new row.
+// New rows don't start at version 1, because then a caller that still has the version of a deleted row
+// could overwrite a new row with the same key via SetIfVersion() (ABA problem).
+// The version is at least 2, so it differs from rows that were stored by older versions of this package (DEFAULT 1),
+// and it's below 2^62, so it can be incremented without overflowing a BIGINT column.
+func NewVersion() int64 {
+	return rand.Int63n(1<<62) + 2
+}
+
+// LikePrefixPattern returns a pattern for the LIKE operator that matches all strings
+// that start with the given prefix.
+// The wildcard characters "%" and "_" as well as the escape character "!" are escaped with "!",
+// so the pattern must be used with ESCAPE '!'.
+func LikePrefixPattern(prefix string) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return r.Replace(prefix) + "%"
+}
+
+// Placeholder returns the placeholder for the i-th parameter of a query, starting at 1.
+// Use QuestionMark or DollarNumber, depending on the database.
+type Placeholder func(i int) string
+
+// QuestionMark is the Placeholder that MySQL uses ("?").
+func QuestionMark(_ int) string {
+	return "?"
+}
+
+// DollarNumber is the Placeholder that PostgreSQL and CockroachDB use ("$1", "$2" etc.).
+func DollarNumber(i int) string {
+	return "$" + strconv.Itoa(i)
+}
+
+// PlaceholderTuples returns n comma-separated tuples with the given number of placeholders each,
+// for example "($1, $2), ($3, $4)" for n = 2, size = 2 and DollarNumber.
+// It can be used for the VALUES of multi-row INSERT statements.
+func PlaceholderTuples(n, size int, placeholder Placeholder) string {
+	var b strings.Builder
+	i := 1
+	for t := 0; t < n; t++ {
+		if t > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := 0; j < size; j++ {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(placeholder(i))
+			i++
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// PlaceholderList returns n comma-separated placeholders,
+// for example "$1, $2, $3" for n = 3 and DollarNumber.
+// It can be used for IN clauses.
+func PlaceholderList(n int, placeholder Placeholder) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = placeholder(i + 1)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
 // CreateDB creates a database with the given name.
 // Note 1: When the DataSourceName already contained a database name
 // but it doesn't exist yet (error 1049 occurred during Ping()),
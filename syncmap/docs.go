@@ -1,4 +1,7 @@
 /*
 Package syncmap contains an implementation of the `gokv.Store` interface for a Go `sync.Map`.
+
+Importing the package registers it for gokv.Open(), so a store can also be created from a URL like
+"syncmap://?cleanup_interval=1m&codec=gob".
 */
 package syncmap
@@ -1,16 +1,38 @@
 package syncmap
 
 import (
+	"context"
+	"net/url"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
 
 // Store is a gokv.Store implementation for a Go sync.Map.
 type Store struct {
-	m     *sync.Map
-	codec encoding.Codec
+	// Values are either []byte or *expiringEntry (for key-value pairs stored with a TTL).
+	m        *sync.Map
+	janitor  *util.Janitor
+	watchers *util.Broadcaster[gokv.Event]
+	// Incremented atomically with each change.
+	revision *uint64
+	codec    encoding.Codec
+}
+
+// expiringEntry is a value that was stored with a TTL.
+// It's stored as pointer, so it can be used with sync.Map.CompareAndDelete().
+type expiringEntry struct {
+	data   []byte
+	expiry time.Time
+}
+
+func (e *expiringEntry) expired(now time.Time) bool {
+	return !now.Before(e.expiry)
 }
 
 // Set stores the given value for the given key.
@@ -27,6 +49,48 @@ func (s Store) Set(k string, v any) error {
 	}
 
 	s.m.Store(k, data)
+	s.publish(gokv.EventSet, k, data)
+	return nil
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The data is copied, so the slice can be changed afterwards.
+// The key must not be "" and the data must not be nil.
+func (s Store) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	data = append([]byte(nil), data...)
+	s.m.Store(k, data)
+	s.publish(gokv.EventSet, k, data)
+	return nil
+}
+
+// SetWithTTL stores the given value for the given key, with the key-value pair expiring after the given TTL.
+// Expired key-value pairs aren't returned anymore and are deleted by a background goroutine,
+// which is started with the first call of this method and runs in the configured CleanupInterval.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "", the value must not be nil and the TTL must be positive.
+func (s Store) SetWithTTL(k string, v any, ttl time.Duration) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+	if err := util.CheckTTL(ttl); err != nil {
+		return err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.janitor.Start()
+	s.m.Store(k, &expiringEntry{
+		data:   data,
+		expiry: time.Now().Add(ttl),
+	})
+	s.publish(gokv.EventSet, k, data)
 	return nil
 }
 
@@ -45,29 +109,219 @@ func (s Store) Get(k string, v any) (found bool, err error) {
 	if !found {
 		return false, nil
 	}
-	// No need to check "ok" return value in type assertion,
-	// because we control the map and we only put slices of bytes in the map.
-	data := dataInterface.([]byte)
+	data, found := unwrap(dataInterface, time.Now())
+	if !found {
+		return false, nil
+	}
 
 	return true, s.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// The returned slice is a copy, so it can be changed.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (s Store) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	dataInterface, found := s.m.Load(k)
+	if !found {
+		return nil, false, nil
+	}
+	data, found = unwrap(dataInterface, time.Now())
+	if !found {
+		return nil, false, nil
+	}
+
+	return append([]byte(nil), data...), true, nil
+}
+
+// SetNX stores the given value for the given key, but only if the key doesn't exist yet (or has expired).
+// It returns false (and no error) if the key already exists.
+// Values are automatically marshalled to JSON or gob (depending on the configuration).
+// The key must not be "" and the value must not be nil.
+func (s Store) SetNX(k string, v any) (created bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := s.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		actual, loaded := s.m.LoadOrStore(k, data)
+		if !loaded {
+			s.publish(gokv.EventSet, k, data)
+			return true, nil
+		}
+		if _, found := unwrap(actual, time.Now()); found {
+			return false, nil
+		}
+		// Only expiring entries can be expired, and they're pointers, so they can be compared.
+		// If the swap fails, the key was written concurrently, so check again.
+		if s.m.CompareAndSwap(k, actual, data) {
+			s.publish(gokv.EventSet, k, data)
+			return true, nil
+		}
+	}
+}
+
+// Has returns true if a value is stored for the given key, without retrieving the value.
+// The key must not be "".
+func (s Store) Has(k string) (found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return false, err
+	}
+
+	dataInterface, found := s.m.Load(k)
+	if !found {
+		return false, nil
+	}
+	_, found = unwrap(dataInterface, time.Now())
+	return found, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
 func (s Store) Delete(k string) error {
+	_, err := s.DeleteExisting(k)
+	return err
+}
+
+// DeleteExisting deletes the stored value for the given key
+// and returns whether the key-value pair existed.
+// An expired key-value pair is deleted as well, but reported as non-existing.
+// Deleting a non-existing key-value pair does NOT lead to an error, but returns false.
+// The key must not be "".
+func (s Store) DeleteExisting(k string) (existed bool, err error) {
 	if err := util.CheckKey(k); err != nil {
-		return err
+		return false, err
 	}
 
-	s.m.Delete(k)
+	value, loaded := s.m.LoadAndDelete(k)
+	if !loaded {
+		return false, nil
+	}
+	s.publish(gokv.EventDelete, k, nil)
+	_, existed = unwrap(value, time.Now())
+	return existed, nil
+}
+
+// Keys calls fn for each key in the store, except for expired ones.
+// The iteration stops when fn returns false.
+// See sync.Map.Range() for the consistency guarantees while the store is written to concurrently.
+func (s Store) Keys(fn func(k string) bool) error {
+	now := time.Now()
+	s.m.Range(func(key, value any) bool {
+		if _, found := unwrap(value, now); !found {
+			return true
+		}
+		// No need to check "ok" return value in type assertion,
+		// because we control the map and we only put string keys in the map.
+		return fn(key.(string))
+	})
 	return nil
 }
 
+// Iter returns an iterator over a snapshot of the keys in the store, except for expired ones.
+func (s Store) Iter() (gokv.KeyIterator, error) {
+	var keys []string
+	_ = s.Keys(func(k string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return util.NewKeySliceIterator(keys), nil
+}
+
+// Watch sends an event to the returned channel for each change of the given key,
+// until the context is canceled or the store is closed.
+// Expired key-value pairs lead to an EventDelete when they're deleted by the background goroutine.
+// The events' Revision is a counter that's incremented with each change of the store.
+// Events are queued for each watcher, so slow receivers don't block writes.
+// When more than util.DefaultMaxQueueLen events are queued, because they aren't received,
+// they're dropped and watching stops with an event whose Err is gokv.ErrEventsDropped.
+// The order of the events for concurrent changes of the same key is undefined,
+// because sync.Map doesn't provide a lock that could be held while sending them.
+// The key must not be "".
+func (s Store) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	return s.watchers.Subscribe(ctx, func(key string) bool {
+		return key == k
+	}), nil
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+func (s Store) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	return s.watchers.Subscribe(ctx, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}), nil
+}
+
+// publish sends an event for the change to the watchers.
+func (s Store) publish(eventType gokv.EventType, k string, data []byte) {
+	revision := atomic.AddUint64(s.revision, 1)
+	if !s.watchers.HasSubscribers() {
+		return
+	}
+	event := gokv.Event{
+		Type:     eventType,
+		Key:      k,
+		Revision: revision,
+	}
+	if eventType == gokv.EventSet {
+		event.Decode = func(v any) error {
+			return s.codec.Unmarshal(data, v)
+		}
+	}
+	s.watchers.Publish(k, event)
+}
+
+// unwrap returns the data of a value from the map,
+// or false if the value was stored with a TTL that has passed at the given time.
+func unwrap(value any, now time.Time) ([]byte, bool) {
+	// No need to check "ok" return value in type assertion,
+	// because we control the map and we only put slices of bytes
+	// or expiring entries in the map.
+	if e, ok := value.(*expiringEntry); ok {
+		if e.expired(now) {
+			return nil, false
+		}
+		return e.data, true
+	}
+	return value.([]byte), true
+}
+
+// deleteExpired deletes all expired key-value pairs.
+// It's called periodically by the janitor.
+func (s Store) deleteExpired() {
+	now := time.Now()
+	s.m.Range(func(key, value any) bool {
+		if e, ok := value.(*expiringEntry); ok && e.expired(now) {
+			// Only delete the entry if it wasn't overwritten in the meantime
+			if s.m.CompareAndDelete(key, e) {
+				// No need to check "ok" return value in type assertion,
+				// because we control the map and we only put string keys in the map.
+				s.publish(gokv.EventDelete, key.(string), nil)
+			}
+		}
+		return true
+	})
+}
+
 // Close closes the store.
-// When called, the store's pointer to the internal Go map is set to nil,
-// leading to the map being free for garbage collection.
+// When called, the background goroutine that deletes expired key-value pairs is stopped
+// and the channels of all watchers are closed.
 func (s Store) Close() error {
+	s.janitor.Stop()
+	s.watchers.Close()
 	// TODO: Requires pointer receiver. We should change this for *all* store
 	// implementations and mark it as breaking change.
 	// Iterating and deleting individual keys works for the regular map implementation
@@ -79,15 +333,20 @@ func (s Store) Close() error {
 
 // Options are the options for the Go sync.Map store.
 type Options struct {
+	// Interval in which key-value pairs that were stored with SetWithTTL() and have expired are deleted.
+	// Expired key-value pairs are never returned, this is only about freeing their memory.
+	// Optional (1 minute by default).
+	CleanupInterval time.Duration
 	// Encoding format.
 	// Optional (encoding.JSON by default).
 	Codec encoding.Codec
 }
 
 // DefaultOptions is an Options object with default values.
-// Codec: encoding.JSON
+// CleanupInterval: 1 minute, Codec: encoding.JSON
 var DefaultOptions = Options{
-	Codec: encoding.JSON,
+	CleanupInterval: time.Minute,
+	Codec:           encoding.JSON,
 }
 
 // NewStore creates a new Go sync.Map store.
@@ -95,12 +354,36 @@ var DefaultOptions = Options{
 // You should call the Close() method on the store when you're done working with it.
 func NewStore(options Options) Store {
 	// Set default values
+	if options.CleanupInterval <= 0 {
+		options.CleanupInterval = DefaultOptions.CleanupInterval
+	}
 	if options.Codec == nil {
 		options.Codec = DefaultOptions.Codec
 	}
 
-	return Store{
-		m:     &sync.Map{},
-		codec: options.Codec,
+	s := Store{
+		m:        &sync.Map{},
+		watchers: util.NewBroadcaster(util.DefaultMaxQueueLen, gokv.Event{Err: gokv.ErrEventsDropped}),
+		revision: new(uint64),
+		codec:    options.Codec,
+	}
+	s.janitor = util.NewJanitor(options.CleanupInterval, s.deleteExpired)
+	return s
+}
+
+func init() {
+	gokv.Register("syncmap", open)
+}
+
+// open creates a store from a URL like "syncmap://?cleanup_interval=1m&codec=gob".
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		CleanupInterval: q.Duration("cleanup_interval"),
+		Codec:           q.Codec(),
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
 	}
+	return NewStore(options), nil
 }
@@ -4,10 +4,12 @@ import (
 	"crypto/md5"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 
 	"github.com/Azure/azure-sdk-for-go/storage"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -56,11 +58,29 @@ func (c Client) Set(k string, v any) error {
 	}
 	err = entity.InsertOrReplace(&entityOptions)
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	return nil
 }
 
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	partitionKey := c.partitionKeySupplier(k)
+	entity := c.c.GetEntityReference(partitionKey, k)
+	valMap := make(map[string]any)
+	valMap[valAttrName] = data
+	entity.Properties = valMap
+	entityOptions := storage.EntityOptions{
+		Timeout: opTimeout,
+	}
+	return entity.InsertOrReplace(&entityOptions)
+}
+
 // Get retrieves the stored value for the given key.
 // You need to pass a pointer to the value, so in case of a struct
 // the automatic unmarshalling can populate the fields of the object
@@ -82,14 +102,14 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	if err != nil {
 		storageErr, ok := err.(storage.AzureStorageServiceError)
 		if !ok {
-			return false, err
+			return false, classifyError(err)
 		}
 		// Handle AzureStorageServiceError.
 		// Return false if the key-value pair doesn't exist.
 		if storageErr.Code == "ResourceNotFound" {
 			return false, nil
 		}
-		return false, err
+		return false, classifyError(err)
 	}
 	retrievedVal := entity.Properties[valAttrName]
 	data, ok := retrievedVal.([]byte)
@@ -100,6 +120,42 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	partitionKey := c.partitionKeySupplier(k)
+	entity := c.c.GetEntityReference(partitionKey, k)
+	getEntityOptions := storage.GetEntityOptions{
+		Select: []string{valAttrName},
+	}
+	timeout := uint(opTimeout)
+	err = entity.Get(timeout, storage.FullMetadata, &getEntityOptions)
+	if err != nil {
+		storageErr, ok := err.(storage.AzureStorageServiceError)
+		if !ok {
+			return nil, false, classifyError(err)
+		}
+		// Handle AzureStorageServiceError.
+		// Return false if the key-value pair doesn't exist.
+		if storageErr.Code == "ResourceNotFound" {
+			return nil, false, nil
+		}
+		return nil, false, classifyError(err)
+	}
+	retrievedVal := entity.Properties[valAttrName]
+	data, ok := retrievedVal.([]byte)
+	if !ok {
+		return nil, true, fmt.Errorf("the value belonging to the key was expected to be a slice of bytes, but wasn't. Key: %v", k)
+	}
+
+	return data, true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
@@ -117,7 +173,7 @@ func (c Client) Delete(k string) error {
 	if err != nil {
 		storageErr, ok := err.(storage.AzureStorageServiceError)
 		if !ok {
-			return err
+			return classifyError(err)
 		}
 		// Handle AzureStorageServiceError.
 		// Return false if the key-value pair doesn't exist.
@@ -126,7 +182,7 @@ func (c Client) Delete(k string) error {
 		}
 	}
 
-	return err
+	return classifyError(err)
 }
 
 // Close closes the client.
@@ -222,17 +278,17 @@ func NewClient(options Options) (Client, error) {
 	if err != nil {
 		storageErr, ok := err.(storage.AzureStorageServiceError)
 		if !ok {
-			return result, err
+			return result, classifyError(err)
 		}
 		// Handle AzureStorageServiceError.
 		// If the table wasn't found, create it.
 		if storageErr.Code == "ResourceNotFound" {
 			err = table.Create(setupTimeout, storage.EmptyPayload, nil)
 			if err != nil {
-				return result, err
+				return result, classifyError(err)
 			}
 		} else {
-			return result, err
+			return result, classifyError(err)
 		}
 	}
 
@@ -304,3 +360,23 @@ func CreateSyntheticPartitionKeySupplier(partitionCount uint16) func(string) str
 		return strconv.FormatUint(uint64(rem), 10)
 	}
 }
+
+// classifyError wraps errors of the Azure Table Storage client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var storageErr storage.AzureStorageServiceError
+	if errors.As(err, &storageErr) {
+		switch {
+		case storageErr.Code == "OperationTimedOut":
+			return gokv.WrapError(gokv.ErrTimeout, err)
+		case storageErr.StatusCode == http.StatusTooManyRequests, storageErr.StatusCode >= 500:
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		case storageErr.StatusCode == http.StatusConflict, storageErr.StatusCode == http.StatusPreconditionFailed:
+			return gokv.WrapError(gokv.ErrConflict, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
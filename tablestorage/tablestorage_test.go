@@ -168,6 +168,36 @@ func TestNil(t *testing.T) {
 	t.Run("get with nil / nil value parameter", createTest(encoding.Gob))
 }
 
+// TestRawStore tests if data is stored and retrieved without the codec.
+func TestRawStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Table Storage could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	test.TestRawStore(client, t)
+}
+
+// TestSentinelErrors tests if invalid arguments and undecodable values lead to the errors that gokv defines.
+func TestSentinelErrors(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Table Storage could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	test.TestSentinelErrors(client, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	if !checkConnection() {
+		t.Skip("No connection to Table Storage could be established. Probably not running in a proper test environment.")
+	}
+
+	client := createClient(t, encoding.JSON)
+	test.TestTypedStore(client, t)
+}
+
 // TestClose tests if the close method returns any errors.
 //
 // Note: This test is only executed if the initial connection to Table Storage works.
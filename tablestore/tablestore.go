@@ -7,6 +7,7 @@ import (
 
 	"github.com/aliyun/aliyun-tablestore-go-sdk/tablestore"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -53,7 +54,36 @@ func (c Client) Set(k string, v any) error {
 		},
 	}
 	_, err = c.c.PutRow(&putRowRequest)
-	return err
+	return classifyError(err)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	putRowRequest := tablestore.PutRowRequest{
+		PutRowChange: &tablestore.PutRowChange{
+			Condition: &tablestore.RowCondition{
+				RowExistenceExpectation: tablestore.RowExistenceExpectation_IGNORE,
+			},
+			Columns: []tablestore.AttributeColumn{{
+				ColumnName: "v",
+				Value:      data,
+			}},
+			PrimaryKey: &tablestore.PrimaryKey{
+				PrimaryKeys: []*tablestore.PrimaryKeyColumn{{
+					ColumnName: keyAttrName,
+					Value:      k,
+				}},
+			},
+			TableName: c.tableName,
+		},
+	}
+	_, err := c.c.PutRow(&putRowRequest)
+	return classifyError(err)
 }
 
 // Get retrieves the stored value for the given key.
@@ -82,7 +112,7 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	}
 	getRowResponse, err := c.c.GetRow(&getRowRequest)
 	if err != nil {
-		return false, err
+		return false, classifyError(err)
 	}
 	// Return false if no value was found
 	if len(getRowResponse.Columns) == 0 {
@@ -103,6 +133,50 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	getRowRequest := tablestore.GetRowRequest{
+		SingleRowQueryCriteria: &tablestore.SingleRowQueryCriteria{
+			ColumnsToGet: []string{"v"},
+			MaxVersion:   1,
+			PrimaryKey: &tablestore.PrimaryKey{
+				PrimaryKeys: []*tablestore.PrimaryKeyColumn{{
+					ColumnName: keyAttrName,
+					Value:      k,
+				}},
+			},
+			TableName: c.tableName,
+		},
+	}
+	getRowResponse, err := c.c.GetRow(&getRowRequest)
+	if err != nil {
+		return nil, false, classifyError(err)
+	}
+	// Return false if no value was found
+	if len(getRowResponse.Columns) == 0 {
+		return nil, false, nil
+	} else if len(getRowResponse.Columns) > 1 {
+		return nil, false, errors.New("the returned GetRowResponse should only contain one column, but it contains more than one")
+	}
+	attributeColumn := getRowResponse.Columns[0]
+	if attributeColumn == nil {
+		return nil, true, errors.New("a key-value pair for the key was found, but it didn't contain a value")
+	}
+	dataIface := attributeColumn.Value
+	data, ok := dataIface.([]byte)
+	if !ok {
+		return nil, false, errors.New("the returned value was expected to be a slice of bytes, but it wasn't")
+	}
+
+	return data, true, nil
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
@@ -127,7 +201,7 @@ func (c Client) Delete(k string) error {
 	}
 	_, err := c.c.DeleteRow(&deleteRowRequest)
 
-	return err
+	return classifyError(err)
 }
 
 // Close closes the client.
@@ -237,7 +311,7 @@ func NewClient(options Options) (Client, error) {
 	}
 	_, err := client.CreateTable(&createtableRequest)
 	if err != nil && !strings.HasPrefix(err.Error(), "OTSObjectAlreadyExist") {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	result.c = client
@@ -246,3 +320,25 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+// classifyError wraps errors of the Table Store client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var otsErr *tablestore.OtsError
+	if errors.As(err, &otsErr) {
+		switch otsErr.Code {
+		case tablestore.STORAGE_TIMEOUT:
+			return gokv.WrapError(gokv.ErrTimeout, err)
+		case tablestore.NOT_ENOUGH_CAPACITY_UNIT, tablestore.TABLE_NOT_READY, tablestore.PARTITION_UNAVAILABLE,
+			tablestore.SERVER_BUSY, tablestore.STORAGE_SERVER_BUSY, tablestore.QUOTA_EXHAUSTED,
+			tablestore.SERVER_UNAVAILABLE, tablestore.INTERNAL_SERVER_ERROR:
+			return gokv.WrapError(gokv.ErrUnavailable, err)
+		case tablestore.ROW_OPERATION_CONFLICT:
+			return gokv.WrapError(gokv.ErrConflict, err)
+		}
+	}
+	return util.ClassifyError(err)
+}
@@ -0,0 +1,27 @@
+package test
+
+// FailingStore is a gokv.Store whose methods all return Err.
+// It's meant for testing stores that wrap other stores, like the ones for retries, metrics or logging.
+type FailingStore struct {
+	Err error
+}
+
+// Set returns Err.
+func (s FailingStore) Set(k string, v any) error {
+	return s.Err
+}
+
+// Get returns (false, Err).
+func (s FailingStore) Get(k string, v any) (found bool, err error) {
+	return false, s.Err
+}
+
+// Delete returns Err.
+func (s FailingStore) Delete(k string) error {
+	return s.Err
+}
+
+// Close returns Err.
+func (s FailingStore) Close() error {
+	return s.Err
+}
@@ -1,10 +1,17 @@
 package test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
 	"math/rand"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/go-test/deep"
 
@@ -87,6 +94,1237 @@ func TestStore(store gokv.Store, t *testing.T) {
 	}
 }
 
+// TestContextStore tests if the context-aware methods of the store work properly,
+// and that they respect a context that's already canceled.
+func TestContextStore(store gokv.ContextStore, t *testing.T) {
+	ctx := context.Background()
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Initially the key shouldn't exist
+	found, err := store.GetContext(ctx, key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+
+	// Store an object
+	val := Foo{
+		Bar: "baz",
+	}
+	err = store.SetContext(ctx, key, val)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Retrieve the object
+	actualPtr := new(Foo)
+	found, err = store.GetContext(ctx, key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	// A canceled context must lead to errors
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = store.SetContext(canceledCtx, key, val)
+	if err == nil {
+		t.Error("An error was expected when setting with a canceled context")
+	}
+	_, err = store.GetContext(canceledCtx, key, new(Foo))
+	if err == nil {
+		t.Error("An error was expected when getting with a canceled context")
+	}
+	err = store.DeleteContext(canceledCtx, key)
+	if err == nil {
+		t.Error("An error was expected when deleting with a canceled context")
+	}
+
+	// Delete
+	err = store.DeleteContext(ctx, key)
+	if err != nil {
+		t.Error(err)
+	}
+	// Key-value pair shouldn't exist anymore
+	found, err = store.GetContext(ctx, key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+}
+
+// TestIterable tests if the keys of the store can be iterated over,
+// with both the callback-based and the cursor-style API.
+// The store can contain other keys, only the ones that are set by this test are checked.
+func TestIterable(store gokv.Store, t *testing.T) {
+	iterable, ok := store.(gokv.Iterable)
+	if !ok {
+		t.Fatal("The store doesn't implement gokv.Iterable")
+	}
+
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	keys := []string{prefix + "a", prefix + "b", prefix + "c"}
+	for _, key := range keys {
+		err := store.Set(key, Foo{Bar: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// All keys must be passed to the callback
+	seen := make(map[string]int)
+	err := iterable.Keys(func(k string) bool {
+		seen[k]++
+		return true
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	for _, key := range keys {
+		if seen[key] != 1 {
+			t.Errorf("Expected key %v to be seen once, but was seen %v times", key, seen[key])
+		}
+	}
+
+	// Returning false must stop the iteration
+	calls := 0
+	err = iterable.Keys(func(k string) bool {
+		calls++
+		return false
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the callback to be called once, but was called %v times", calls)
+	}
+
+	// The cursor-style iterator must return the same keys
+	iter, err := iterable.Iter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen = make(map[string]int)
+	for iter.Next() {
+		seen[iter.Key()]++
+	}
+	if err := iter.Err(); err != nil {
+		t.Error(err)
+	}
+	if err := iter.Close(); err != nil {
+		t.Error(err)
+	}
+	for _, key := range keys {
+		if seen[key] != 1 {
+			t.Errorf("Expected key %v to be seen once, but was seen %v times", key, seen[key])
+		}
+	}
+	// A closed iterator must not return any more keys
+	if iter.Next() {
+		t.Error("Expected Next() to return false after Close()")
+	}
+
+	// Deleted keys must not show up anymore
+	err = store.Delete(keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = iterable.Keys(func(k string) bool {
+		if k == keys[0] {
+			t.Errorf("Expected deleted key %v not to be seen", k)
+		}
+		return true
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, key := range keys[1:] {
+		err = store.Delete(key)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestPrefixScanner tests if all key-value pairs whose key starts with a given prefix
+// can be retrieved, and that no other key-value pairs are included.
+// The prefix contains an underscore, which is a wildcard in SQL's LIKE operator,
+// to make sure that implementations escape it.
+func TestPrefixScanner(store gokv.Store, t *testing.T) {
+	scanner, ok := store.(gokv.PrefixScanner)
+	if !ok {
+		t.Fatal("The store doesn't implement gokv.PrefixScanner")
+	}
+
+	base := strconv.FormatInt(rand.Int63(), 10)
+	prefix := base + "_"
+	expected := map[string]Foo{
+		prefix + "a": {Bar: "a"},
+		prefix + "b": {Bar: "b"},
+		prefix + "c": {Bar: "c"},
+	}
+	// Keys that share part of the prefix or match the prefix with an unescaped wildcard
+	others := []string{base, base + "x", base + "xa"}
+	for k, v := range expected {
+		err := store.Set(k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, k := range others {
+		err := store.Set(k, Foo{Bar: k})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the key-value pairs with the prefix must be passed to the callback
+	actual := make(map[string]Foo)
+	err := scanner.ScanPrefix(prefix, func(k string, decode func(v any) error) bool {
+		if _, ok := actual[k]; ok {
+			t.Errorf("Key %v was passed more than once", k)
+		}
+		val := Foo{}
+		if err := decode(&val); err != nil {
+			t.Error(err)
+		}
+		actual[k] = val
+		return true
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := deep.Equal(actual, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	// Returning false must stop the iteration
+	calls := 0
+	err = scanner.ScanPrefix(prefix, func(k string, decode func(v any) error) bool {
+		calls++
+		return false
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the callback to be called once, but was called %v times", calls)
+	}
+
+	// A prefix that doesn't match anything
+	err = scanner.ScanPrefix(base+"y", func(k string, decode func(v any) error) bool {
+		t.Errorf("Expected no key to be passed, but got %v", k)
+		return true
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	for k := range expected {
+		err = store.Delete(k)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	for _, k := range others {
+		err = store.Delete(k)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestBatchStore tests if setting, getting and deleting multiple key-value pairs at once works properly.
+func TestBatchStore(store gokv.BatchStore, t *testing.T) {
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	expected := map[string]any{
+		prefix + "a": &Foo{Bar: "a"},
+		prefix + "b": &Foo{Bar: "b"},
+		prefix + "c": &Foo{Bar: "c"},
+	}
+	keys := []string{prefix + "a", prefix + "b", prefix + "c", prefix + "missing"}
+	newFoo := func() any { return new(Foo) }
+
+	// Initially no key should exist
+	actual, err := store.GetMulti(keys, newFoo)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(actual) != 0 {
+		t.Errorf("Expected no values, but got %v", actual)
+	}
+
+	// Store the objects
+	err = store.SetMulti(expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Retrieve the objects. The missing key must not be part of the result.
+	actual, err = store.GetMulti(keys, newFoo)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := deep.Equal(actual, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	// The key-value pairs must also be accessible individually
+	actualPtr := new(Foo)
+	found, err := store.Get(prefix+"b", actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != *(expected[prefix+"b"].(*Foo)) {
+		t.Errorf("Expected: %v, but was: %v", expected[prefix+"b"], *actualPtr)
+	}
+
+	// An invalid key or value must lead to an error
+	err = store.SetMulti(map[string]any{"": Foo{}})
+	if err == nil {
+		t.Error("An error was expected when setting an empty key")
+	}
+	err = store.SetMulti(map[string]any{prefix + "nil": nil})
+	if err == nil {
+		t.Error("An error was expected when setting a nil value")
+	}
+	_, err = store.GetMulti([]string{""}, newFoo)
+	if err == nil {
+		t.Error("An error was expected when getting an empty key")
+	}
+	err = store.DeleteMulti([]string{""})
+	if err == nil {
+		t.Error("An error was expected when deleting an empty key")
+	}
+
+	// Delete, including the missing key, which must NOT lead to an error
+	err = store.DeleteMulti(keys)
+	if err != nil {
+		t.Error(err)
+	}
+	actual, err = store.GetMulti(keys, newFoo)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(actual) != 0 {
+		t.Errorf("Expected no values, but got %v", actual)
+	}
+}
+
+// TestExpiringStore tests if key-value pairs that are stored with a TTL expire,
+// and that storing them again without TTL removes the TTL.
+// The TTL is 2 seconds, because some backends only support seconds,
+// so the test takes a couple of seconds.
+func TestExpiringStore(store gokv.ExpiringStore, t *testing.T) {
+	ttl := 2 * time.Second
+	key := strconv.FormatInt(rand.Int63(), 10)
+	persistentKey := key + "-persistent"
+	val := Foo{
+		Bar: "baz",
+	}
+
+	// An invalid TTL must lead to an error
+	err := store.SetWithTTL(key, val, 0)
+	if err == nil {
+		t.Error("An error was expected when setting a TTL of 0")
+	}
+
+	err = store.SetWithTTL(key, val, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Storing a key with Set() after SetWithTTL() must remove the TTL
+	err = store.SetWithTTL(persistentKey, val, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set(persistentKey, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Before the TTL has passed the value must be found
+	actualPtr := new(Foo)
+	found, err := store.Get(key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	// Some backends delete expired key-value pairs in intervals, so give them some leeway
+	time.Sleep(ttl + 2*time.Second)
+
+	found, err = store.Get(key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found after the TTL has passed, but no value was expected")
+	}
+	found, err = store.Get(persistentKey, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found for the key that was stored again without TTL, but should have been")
+	}
+
+	err = store.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+	err = store.Delete(persistentKey)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestVersionedStore tests if values are only stored with SetIfVersion()
+// when the given version matches the current version of the key-value pair.
+func TestVersionedStore(store gokv.VersionedStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+	val := Foo{
+		Bar: "baz",
+	}
+	val2 := Foo{
+		Bar: "qux",
+	}
+
+	// A missing key must have version 0
+	version, found, err := store.GetVersioned(key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found || version != 0 {
+		t.Errorf("Expected no value with version 0, but found was %v with version %v", found, version)
+	}
+
+	// A version other than 0 must not match a missing key
+	swapped, err := store.SetIfVersion(key, val, 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if swapped {
+		t.Error("A value was stored for a missing key with a version other than 0")
+	}
+
+	// Version 0 must only match the missing key
+	swapped, err = store.SetIfVersion(key, val, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !swapped {
+		t.Fatal("No value was stored for a missing key with version 0")
+	}
+	swapped, err = store.SetIfVersion(key, val2, 0)
+	if err != nil {
+		t.Error(err)
+	}
+	if swapped {
+		t.Error("A value was stored with version 0, but the key already existed")
+	}
+
+	actualPtr := new(Foo)
+	version1, found, err := store.GetVersioned(key, actualPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("No value was found, but should have been")
+	}
+	if version1 == 0 {
+		t.Error("The version of an existing key-value pair was 0")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	// The current version must match, but only once
+	swapped, err = store.SetIfVersion(key, val2, version1)
+	if err != nil {
+		t.Error(err)
+	}
+	if !swapped {
+		t.Error("No value was stored, but the version was current")
+	}
+	swapped, err = store.SetIfVersion(key, val, version1)
+	if err != nil {
+		t.Error(err)
+	}
+	if swapped {
+		t.Error("A value was stored with an outdated version")
+	}
+	actualPtr = new(Foo)
+	found, err = store.Get(key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != val2 {
+		t.Errorf("Expected: %v, but was: %v", val2, *actualPtr)
+	}
+
+	// Set() must change the version as well
+	version2, _, err := store.GetVersioned(key, new(Foo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set(key, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swapped, err = store.SetIfVersion(key, val2, version2)
+	if err != nil {
+		t.Error(err)
+	}
+	if swapped {
+		t.Error("A value was stored with a version that was outdated by Set()")
+	}
+
+	err = store.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestCreateOnlyStore tests if SetNX() only stores a value if the key doesn't exist yet,
+// also when it's called concurrently.
+func TestCreateOnlyStore(store gokv.CreateOnlyStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+	val := Foo{
+		Bar: "baz",
+	}
+	val2 := Foo{
+		Bar: "qux",
+	}
+
+	created, err := store.SetNX(key, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Error("No value was stored for a missing key")
+	}
+	created, err = store.SetNX(key, val2)
+	if err != nil {
+		t.Error(err)
+	}
+	if created {
+		t.Error("A value was stored, but the key already existed")
+	}
+
+	// The existing value must not have been changed
+	actualPtr := new(Foo)
+	found, err := store.Get(key, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	// After deleting the key it must be possible to create it again
+	err = store.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err = store.SetNX(key, val2)
+	if err != nil {
+		t.Error(err)
+	}
+	if !created {
+		t.Error("No value was stored for a deleted key")
+	}
+	err = store.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Only one of multiple concurrent calls must succeed
+	concurrentKey := key + "-concurrent"
+	goroutineCount := 10
+	var createdCount int32
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(goroutineCount)
+	for i := 0; i < goroutineCount; i++ {
+		go func() {
+			defer waitGroup.Done()
+			created, err := store.SetNX(concurrentKey, val)
+			if err != nil {
+				t.Error(err)
+			}
+			if created {
+				atomic.AddInt32(&createdCount, 1)
+			}
+		}()
+	}
+	waitGroup.Wait()
+	if createdCount != 1 {
+		t.Errorf("Expected exactly 1 concurrent call to create the key, but was: %v", createdCount)
+	}
+	err = store.Delete(concurrentKey)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestTransactional tests if multiple operations can be executed in a transaction,
+// which is committed when the function returns nil and rolled back when it returns an error,
+// and that read-only transactions can't write.
+func TestTransactional(store gokv.Store, t *testing.T) {
+	transactional, ok := store.(gokv.Transactional)
+	if !ok {
+		t.Fatal("The store doesn't implement gokv.Transactional")
+	}
+
+	fromKey := strconv.FormatInt(rand.Int63(), 10)
+	toKey := fromKey + "-to"
+	val := Foo{
+		Bar: "baz",
+	}
+	err := store.Set(fromKey, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the value from one key to another
+	err = transactional.Update(func(tx gokv.Tx) error {
+		actualPtr := new(Foo)
+		found, err := tx.Get(fromKey, actualPtr)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("no value was found in the transaction, but should have been")
+		}
+		if err := tx.Set(toKey, *actualPtr); err != nil {
+			return err
+		}
+		// The transaction must see its own writes
+		found, err = tx.Get(toKey, new(Foo))
+		if err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("the value that was set in the transaction wasn't found in the transaction")
+		}
+		return tx.Delete(fromKey)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := store.Get(fromKey, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found for the key that was deleted in the transaction")
+	}
+	actualPtr := new(Foo)
+	found, err = store.Get(toKey, actualPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found for the key that was set in the transaction")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	// When the function returns an error, nothing must be written
+	errAbort := errors.New("abort")
+	err = transactional.Update(func(tx gokv.Tx) error {
+		if err := tx.Set(fromKey, val); err != nil {
+			return err
+		}
+		if err := tx.Delete(toKey); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Errorf("Expected the error of the function to be returned, but was: %v", err)
+	}
+	found, err = store.Get(fromKey, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found for a key that was set in a rolled back transaction")
+	}
+	found, err = store.Get(toKey, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found for a key that was deleted in a rolled back transaction")
+	}
+
+	// Read-only transactions must be able to read, but not to write
+	err = transactional.View(func(tx gokv.Tx) error {
+		actualPtr := new(Foo)
+		found, err := tx.Get(toKey, actualPtr)
+		if err != nil {
+			return err
+		}
+		if !found {
+			t.Error("No value was found in the read-only transaction, but should have been")
+		}
+		if *actualPtr != val {
+			t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+		}
+		if err := tx.Set(fromKey, val); !errors.Is(err, gokv.ErrReadOnlyTx) {
+			t.Errorf("Expected gokv.ErrReadOnlyTx when setting a value in a read-only transaction, but was: %v", err)
+		}
+		if err := tx.Delete(toKey); !errors.Is(err, gokv.ErrReadOnlyTx) {
+			t.Errorf("Expected gokv.ErrReadOnlyTx when deleting a value in a read-only transaction, but was: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = store.Delete(toKey)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestWatcher tests if events are sent for changes of a watched key and of keys with a watched prefix,
+// and that the channels are closed when the context is canceled.
+func TestWatcher(store gokv.Store, t *testing.T) {
+	watcher, ok := store.(gokv.Watcher)
+	if !ok {
+		t.Fatal("The store doesn't implement gokv.Watcher")
+	}
+
+	prefix := strconv.FormatInt(rand.Int63(), 10) + "-"
+	key := prefix + "a"
+	otherKey := strconv.FormatInt(rand.Int63(), 10)
+	val := Foo{
+		Bar: "baz",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keyEvents, err := watcher.Watch(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixEvents, err := watcher.WatchPrefix(ctx, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes of other keys must not lead to events
+	err = store.Set(otherKey, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Set(key, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, events := range map[string]<-chan gokv.Event{"key": keyEvents, "prefix": prefixEvents} {
+		event := receiveEvent(t, events)
+		if event.Type != gokv.EventSet || event.Key != key {
+			t.Errorf("Expected a set event for the key %v on the %v channel, but was: %v event for the key %v", key, name, event.Type, event.Key)
+		} else if event.Decode == nil {
+			t.Errorf("The set event on the %v channel doesn't have a Decode function", name)
+		} else {
+			// Some implementations retrieve the value on demand, in which case it's already deleted
+			actualPtr := new(Foo)
+			err := event.Decode(actualPtr)
+			if err != nil && !errors.Is(err, gokv.ErrValueUnavailable) {
+				t.Error(err)
+			}
+			if err == nil && *actualPtr != val {
+				t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+			}
+		}
+		event = receiveEvent(t, events)
+		if event.Type != gokv.EventDelete || event.Key != key {
+			t.Errorf("Expected a delete event for the key %v on the %v channel, but was: %v event for the key %v", key, name, event.Type, event.Key)
+		}
+	}
+
+	// After canceling the context the channels must be closed
+	cancel()
+	for name, events := range map[string]<-chan gokv.Event{"key": keyEvents, "prefix": prefixEvents} {
+		timeout := time.After(10 * time.Second)
+	drain:
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					break drain
+				}
+			case <-timeout:
+				t.Errorf("The %v channel wasn't closed after canceling the context", name)
+				break drain
+			}
+		}
+	}
+
+	err = store.Delete(otherKey)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// receiveEvent receives the next event from the channel, failing the test if it doesn't arrive in time.
+func receiveEvent(t *testing.T, events <-chan gokv.Event) gokv.Event {
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("The channel was closed, but an event was expected")
+		}
+		if event.Err != nil {
+			t.Fatal(event.Err)
+		}
+		return event
+	case <-time.After(10 * time.Second):
+		t.Fatal("No event was received in time")
+	}
+	return gokv.Event{}
+}
+
+// TestRawStore tests if data that's stored with SetRaw() is returned unchanged by GetRaw(),
+// and if it's compatible with the data of Set() and Get().
+func TestRawStore(store gokv.RawStore, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Arbitrary bytes, which aren't valid for any codec, must be stored as they are
+	data := []byte{0, 1, 2, 0x7f, 0x80, 0xfe, 0xff}
+	err := store.SetRaw(key, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing the passed slice must not change the stored data
+	expected := append([]byte(nil), data...)
+	data[0] = 42
+	actual, found, err := store.GetRaw(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("No data was found, but should have been")
+	}
+	if diff := deep.Equal(actual, expected); diff != nil {
+		t.Error(diff)
+	}
+
+	// Data of a value that's stored with Set() must be usable with SetRaw() and Get()
+	val := Foo{
+		Bar: "baz",
+	}
+	key2 := strconv.FormatInt(rand.Int63(), 10)
+	err = store.Set(key2, val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marshalled, found, err := store.GetRaw(key2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("No data was found, but should have been")
+	}
+	err = store.SetRaw(key, marshalled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actualPtr := new(Foo)
+	found, err = store.Get(key, actualPtr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("No value was found, but should have been")
+	}
+	if *actualPtr != val {
+		t.Errorf("Expected: %v, but was: %v", val, *actualPtr)
+	}
+
+	// Missing keys must not be found
+	err = store.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, found, err = store.GetRaw(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+	if actual != nil {
+		t.Errorf("Expected nil data, but was: %v", actual)
+	}
+
+	// Invalid arguments must lead to an error
+	err = store.SetRaw("", []byte("foo"))
+	if err == nil {
+		t.Error("An error was expected for an empty key")
+	}
+	err = store.SetRaw(key, nil)
+	if err == nil {
+		t.Error("An error was expected for nil data")
+	}
+	_, _, err = store.GetRaw("")
+	if err == nil {
+		t.Error("An error was expected for an empty key")
+	}
+
+	err = store.Delete(key2)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestDeleter tests if DeleteExisting() reports whether the deleted key-value pair existed.
+// The store must implement gokv.Deleter.
+func TestDeleter(store gokv.Store, t *testing.T) {
+	deleter, ok := store.(gokv.Deleter)
+	if !ok {
+		t.Fatal("The store doesn't implement gokv.Deleter")
+	}
+
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Deleting a non-existing key-value pair must not lead to an error, but return false
+	existed, err := deleter.DeleteExisting(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existed {
+		t.Error("The key-value pair was reported as existing, but it didn't exist")
+	}
+
+	err = store.Set(key, Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	existed, err = deleter.DeleteExisting(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !existed {
+		t.Error("The key-value pair was reported as non-existing, but it existed")
+	}
+	found, err := store.Get(key, new(Foo))
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but it should have been deleted")
+	}
+
+	// Deleting it again must return false
+	existed, err = deleter.DeleteExisting(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if existed {
+		t.Error("The key-value pair was reported as existing, but it was already deleted")
+	}
+
+	_, err = deleter.DeleteExisting("")
+	if err == nil {
+		t.Error("An error was expected for an empty key")
+	}
+}
+
+// TestExistenceChecker tests if Has() reports whether a value is stored for a key.
+// The store must implement gokv.ExistenceChecker.
+func TestExistenceChecker(store gokv.Store, t *testing.T) {
+	checker, ok := store.(gokv.ExistenceChecker)
+	if !ok {
+		t.Fatal("The store doesn't implement gokv.ExistenceChecker")
+	}
+
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	found, err := checker.Has(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("The key was reported as existing, but no value was stored for it")
+	}
+
+	err = store.Set(key, Foo{Bar: "baz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err = checker.Has(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Error("The key was reported as non-existing, but a value was stored for it")
+	}
+
+	err = store.Delete(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err = checker.Has(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("The key was reported as existing, but its value was deleted")
+	}
+
+	_, err = checker.Has("")
+	if err == nil {
+		t.Error("An error was expected for an empty key")
+	}
+}
+
+// TestSentinelErrors tests if invalid arguments and values that can't be decoded lead to the errors that gokv defines,
+// so that they can be checked with errors.Is().
+func TestSentinelErrors(store gokv.Store, t *testing.T) {
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	err := store.Set("", "foo")
+	if !errors.Is(err, gokv.ErrKeyEmpty) {
+		t.Errorf("Expected gokv.ErrKeyEmpty when setting an empty key, but was: %v", err)
+	}
+	err = store.Set(key, nil)
+	if !errors.Is(err, gokv.ErrValueNil) {
+		t.Errorf("Expected gokv.ErrValueNil when setting a nil value, but was: %v", err)
+	}
+	_, err = store.Get("", new(string))
+	if !errors.Is(err, gokv.ErrKeyEmpty) {
+		t.Errorf("Expected gokv.ErrKeyEmpty when getting an empty key, but was: %v", err)
+	}
+	_, err = store.Get(key, nil)
+	if !errors.Is(err, gokv.ErrValueNil) {
+		t.Errorf("Expected gokv.ErrValueNil when getting with a nil pointer, but was: %v", err)
+	}
+	err = store.Delete("")
+	if !errors.Is(err, gokv.ErrKeyEmpty) {
+		t.Errorf("Expected gokv.ErrKeyEmpty when deleting an empty key, but was: %v", err)
+	}
+
+	// A string can't be decoded into a struct
+	err = store.Set(key, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get(key, new(Foo))
+	if !gokv.IsDecode(err) {
+		t.Errorf("Expected an error for which gokv.IsDecode() returns true, but was: %v", err)
+	}
+
+	err = store.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestTypedStore is like TestStore, but uses the store via gokv.Typed.
+// It also tests the batch methods, and ScanPrefix() if the store implements gokv.PrefixScanner.
+func TestTypedStore(store gokv.Store, t *testing.T) {
+	typed := gokv.NewTyped[Foo](store)
+	key := strconv.FormatInt(rand.Int63(), 10)
+
+	// Initially the key shouldn't exist
+	actual, found, err := typed.Get(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+	if actual != (Foo{}) {
+		t.Errorf("Expected the zero value, but was: %v", actual)
+	}
+
+	// Deleting a non-existing key-value pair should NOT lead to an error
+	err = typed.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Store and retrieve an object
+	expected := Foo{
+		Bar: "baz",
+	}
+	err = typed.Set(key, expected)
+	if err != nil {
+		t.Error(err)
+	}
+	actual, found, err = typed.Get(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found, but should have been")
+	}
+	if actual != expected {
+		t.Errorf("Expected: %v, but was: %v", expected, actual)
+	}
+
+	// The value must be compatible with the untyped store
+	untypedPtr := new(Foo)
+	found, err = store.Get(key, untypedPtr)
+	if err != nil {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("No value was found via the untyped store, but should have been")
+	}
+	if *untypedPtr != expected {
+		t.Errorf("Expected: %v, but was: %v", expected, *untypedPtr)
+	}
+
+	// Batch methods
+	prefix := key + "_"
+	expectedMulti := map[string]Foo{
+		prefix + "a": {Bar: "a"},
+		prefix + "b": {Bar: "b"},
+	}
+	keys := []string{prefix + "a", prefix + "b", prefix + "missing"}
+	err = typed.SetMulti(expectedMulti)
+	if err != nil {
+		t.Error(err)
+	}
+	actualMulti, err := typed.GetMulti(keys)
+	if err != nil {
+		t.Error(err)
+	}
+	if diff := deep.Equal(actualMulti, expectedMulti); diff != nil {
+		t.Error(diff)
+	}
+
+	// ScanPrefix only works if the store supports it
+	actualScan := make(map[string]Foo)
+	err = typed.ScanPrefix(prefix, func(k string, v Foo) bool {
+		actualScan[k] = v
+		return true
+	})
+	if _, ok := store.(gokv.PrefixScanner); ok {
+		if err != nil {
+			t.Error(err)
+		}
+		if diff := deep.Equal(actualScan, expectedMulti); diff != nil {
+			t.Error(diff)
+		}
+	} else if !errors.Is(err, gokv.ErrNotSupported) {
+		t.Errorf("Expected gokv.ErrNotSupported, because the store doesn't implement gokv.PrefixScanner, but was: %v", err)
+	}
+
+	err = typed.DeleteMulti(keys)
+	if err != nil {
+		t.Error(err)
+	}
+	actualMulti, err = typed.GetMulti(keys)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(actualMulti) != 0 {
+		t.Errorf("Expected no values, but got %v", actualMulti)
+	}
+
+	// Delete
+	err = typed.Delete(key)
+	if err != nil {
+		t.Error(err)
+	}
+	_, found, err = typed.Get(key)
+	if err != nil {
+		t.Error(err)
+	}
+	if found {
+		t.Error("A value was found, but no value was expected")
+	}
+}
+
+// TestOpen tests if a store can be created with gokv.Open() and works properly.
+// The URL must select the gob codec with "codec=gob", which is checked via gokv.RawStore if the store implements it.
+// An unknown codec must lead to an error.
+func TestOpen(rawURL string, t *testing.T) {
+	if !strings.Contains(rawURL, "codec=gob") {
+		t.Fatalf("The URL must contain \"codec=gob\", but was: %v", rawURL)
+	}
+
+	_, err := gokv.Open(strings.Replace(rawURL, "codec=gob", "codec=unknown", 1))
+	if err == nil {
+		t.Error("An error should have been returned for an unknown codec")
+	}
+
+	store, err := gokv.Open(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	TestStore(store, t)
+
+	// The value must be gob encoded
+	rawStore, ok := store.(gokv.RawStore)
+	if !ok {
+		return
+	}
+	key := strconv.FormatInt(rand.Int63(), 10)
+	expected := Foo{
+		Bar: "baz",
+	}
+	err = store.Set(key, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = store.Delete(key) }()
+	data, found, err := rawStore.GetRaw(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found {
+		t.Fatal("No value was found, but should have been")
+	}
+	actual := Foo{}
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&actual)
+	if err != nil {
+		t.Errorf("The value should have been gob encoded, but decoding it failed: %v", err)
+	}
+	if actual != expected {
+		t.Errorf("Expected: %v, but was: %v", expected, actual)
+	}
+}
+
 // TestTypes tests if setting and getting values works with all Go types.
 func TestTypes(store gokv.Store, t *testing.T) {
 	boolVar := true
@@ -0,0 +1,11 @@
+/*
+Package tracing contains an implementation of the `gokv.Store` interface that creates OpenTelemetry spans
+for the operations of any other store.
+
+The spans follow the OpenTelemetry semantic conventions for database client spans,
+with "db.system.name", "db.operation.name", "db.namespace" and "error.type" attributes.
+Additionally they contain the key (optionally hashed), whether Get() found a value
+and the size of the marshalled value.
+The store implements `gokv.ContextStore`, so its spans are children of the span in the context of the caller.
+*/
+package tracing
@@ -0,0 +1,273 @@
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.32.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/util"
+)
+
+// Name of the tracer, which is the import path of this package, as recommended by OpenTelemetry.
+const tracerName = "github.com/philippgille/gokv/tracing"
+
+// Attribute keys that aren't part of the OpenTelemetry semantic conventions.
+const (
+	// KeyKey is the key of the attribute with the key of the key-value pair (or its hash, see Options.HashKeys).
+	KeyKey = attribute.Key("gokv.key")
+	// FoundKey is the key of the attribute that tells if Get() found a value.
+	FoundKey = attribute.Key("gokv.found")
+	// ValueSizeKey is the key of the attribute with the size of the marshalled value in bytes (see Options.Codec).
+	ValueSizeKey = attribute.Key("gokv.value.size")
+)
+
+// Store is a gokv.Store that creates an OpenTelemetry span for each operation of the wrapped store,
+// with attributes following the semantic conventions for database client spans.
+//
+// It implements gokv.ContextStore, so the spans can be children of the span in the context of the caller.
+// The context is passed on to the wrapped store if it implements gokv.ContextStore.
+// The methods without a context create root spans.
+type Store struct {
+	store  gokv.ContextStore
+	tracer trace.Tracer
+	// Attributes that are added to all spans
+	attributes []attribute.KeyValue
+	spanSuffix string
+	hashKeys   bool
+	codec      encoding.Codec
+}
+
+// Set stores the given value for the given key in a "Set" span.
+// The key must not be "" and the value must not be nil.
+func (s Store) Set(k string, v any) error {
+	return s.SetContext(context.Background(), k, v)
+}
+
+// SetContext stores the given value for the given key in a "Set" span that's a child of the span in ctx.
+// The key must not be "" and the value must not be nil.
+func (s Store) SetContext(ctx context.Context, k string, v any) error {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return err
+	}
+
+	ctx, span := s.start(ctx, "Set", k)
+	defer span.End()
+
+	s.setValueSize(span, v)
+	return s.fail(span, s.store.SetContext(ctx, k, v))
+}
+
+// Get retrieves the stored value for the given key in a "Get" span.
+// You need to pass a pointer to the value, so in case of a struct
+// the automatic unmarshalling can populate the fields of the object
+// that v points to with the values of the retrieved object's values.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) Get(k string, v any) (found bool, err error) {
+	return s.GetContext(context.Background(), k, v)
+}
+
+// GetContext retrieves the stored value for the given key in a "Get" span that's a child of the span in ctx.
+// If no value is found it returns (false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (s Store) GetContext(ctx context.Context, k string, v any) (found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	ctx, span := s.start(ctx, "Get", k)
+	defer span.End()
+
+	found, err = s.store.GetContext(ctx, k, v)
+	if err != nil {
+		return false, s.fail(span, err)
+	}
+	span.SetAttributes(FoundKey.Bool(found))
+	if found {
+		s.setValueSize(span, v)
+	}
+	return found, nil
+}
+
+// Delete deletes the stored value for the given key in a "Delete" span.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) Delete(k string) error {
+	return s.DeleteContext(context.Background(), k)
+}
+
+// DeleteContext deletes the stored value for the given key in a "Delete" span that's a child of the span in ctx.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (s Store) DeleteContext(ctx context.Context, k string) error {
+	if err := util.CheckKey(k); err != nil {
+		return err
+	}
+
+	ctx, span := s.start(ctx, "Delete", k)
+	defer span.End()
+
+	return s.fail(span, s.store.DeleteContext(ctx, k))
+}
+
+// Close closes the wrapped store in a "Close" span.
+func (s Store) Close() error {
+	return s.CloseContext(context.Background())
+}
+
+// CloseContext closes the wrapped store in a "Close" span that's a child of the span in ctx.
+func (s Store) CloseContext(ctx context.Context) error {
+	ctx, span := s.start(ctx, "Close", "")
+	defer span.End()
+
+	return s.fail(span, s.store.CloseContext(ctx))
+}
+
+// start starts a span for the operation, with the key as attribute unless it's "".
+func (s Store) start(ctx context.Context, operation, k string) (context.Context, trace.Span) {
+	attributes := make([]attribute.KeyValue, 0, len(s.attributes)+2)
+	attributes = append(attributes, s.attributes...)
+	attributes = append(attributes, semconv.DBOperationName(operation))
+	if k != "" {
+		attributes = append(attributes, KeyKey.String(s.key(k)))
+	}
+	return s.tracer.Start(ctx, operation+s.spanSuffix,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+func (s Store) key(k string) string {
+	if !s.hashKeys {
+		return k
+	}
+	hash := sha256.Sum256([]byte(k))
+	return hex.EncodeToString(hash[:])
+}
+
+// setValueSize sets the size of the value, marshalled with the codec, as attribute of the span, if there's a codec.
+func (s Store) setValueSize(span trace.Span, v any) {
+	if s.codec == nil {
+		return
+	}
+	if data, err := s.codec.Marshal(v); err == nil {
+		span.SetAttributes(ValueSizeKey.Int(len(data)))
+	}
+}
+
+// fail records the error in the span, if it's not nil, and returns it.
+func (s Store) fail(span trace.Span, err error) error {
+	if err == nil {
+		return nil
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err)))
+	return err
+}
+
+// errorType returns a low-cardinality description of the error for the "error.type" attribute.
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case gokv.IsTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case gokv.IsUnavailable(err):
+		return "unavailable"
+	case gokv.IsConflict(err):
+		return "conflict"
+	case gokv.IsEncode(err):
+		return "encode"
+	case gokv.IsDecode(err):
+		return "decode"
+	default:
+		return fmt.Sprintf("%T", err)
+	}
+}
+
+// Options are the options for the tracing store.
+type Options struct {
+	// The store whose operations are traced.
+	// Required.
+	Store gokv.Store
+	// The type of the wrapped store, for the "db.system.name" attribute, like "redis" or "postgresql".
+	// Optional (the name of the package of the wrapped store's type by default, like "gomap").
+	System string
+	// The name of the database, table, bucket etc. of the wrapped store, for the "db.namespace" attribute.
+	// It's also appended to the span names, like "Get sessions".
+	// Optional ("" by default, which means the attribute isn't set).
+	Namespace string
+	// Record a hex encoded SHA-256 hash of the keys instead of the keys themselves,
+	// for keys that contain sensitive data like email addresses.
+	// Optional (false by default).
+	HashKeys bool
+	// The codec that the wrapped store uses, for recording the sizes of the values.
+	// The values are marshalled with it an additional time, only for measuring their size.
+	// Optional (nil by default, which means that the value sizes aren't recorded).
+	Codec encoding.Codec
+	// The provider of the tracer that creates the spans.
+	// Optional (otel.GetTracerProvider() by default).
+	TracerProvider trace.TracerProvider
+}
+
+// DefaultOptions is an Options object with default values.
+// HashKeys: false, Codec: nil, TracerProvider: otel.GetTracerProvider()
+var DefaultOptions = Options{
+	// No need to set Store because it's required and doesn't have a default,
+	// and no need to set the other fields because their zero values are fine.
+	// The global TracerProvider is looked up in NewStore(), because it can change.
+}
+
+// NewStore creates a new tracing store.
+func NewStore(options Options) (Store, error) {
+	result := Store{}
+
+	if options.Store == nil {
+		return result, errors.New("the Store in the options must not be nil")
+	}
+
+	// Set default values
+	if options.System == "" {
+		options.System = systemName(options.Store)
+	}
+	if options.TracerProvider == nil {
+		options.TracerProvider = otel.GetTracerProvider()
+	}
+
+	result.store = gokv.AsContextStore(options.Store)
+	result.tracer = options.TracerProvider.Tracer(tracerName)
+	result.attributes = []attribute.KeyValue{semconv.DBSystemNameKey.String(options.System)}
+	if options.Namespace != "" {
+		result.attributes = append(result.attributes, semconv.DBNamespace(options.Namespace))
+		result.spanSuffix = " " + options.Namespace
+	}
+	result.hashKeys = options.HashKeys
+	result.codec = options.Codec
+
+	return result, nil
+}
+
+// systemName returns the name of the package of the store's type, like "redis" for a redis.Client.
+func systemName(store gokv.Store) string {
+	t := reflect.TypeOf(store)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.PkgPath() == "" {
+		return "other"
+	}
+	return path.Base(t.PkgPath())
+}
@@ -0,0 +1,308 @@
+package tracing_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/philippgille/gokv"
+	"github.com/philippgille/gokv/encoding"
+	"github.com/philippgille/gokv/gomap"
+	"github.com/philippgille/gokv/test"
+	"github.com/philippgille/gokv/tracing"
+)
+
+// TestStore tests if reading from, writing to and deleting from the store works properly.
+// A struct is used as value. See TestTypes() for a test that is simpler but tests all types.
+func TestStore(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, tracing.Options{Codec: encoding.JSON}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestStore(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, tracing.Options{Codec: encoding.Gob}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestStore(store, t)
+	})
+
+	// Test without a codec
+	t.Run("NoCodec", func(t *testing.T) {
+		store, _ := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+		test.TestStore(store, t)
+	})
+}
+
+// TestTypes tests if setting and getting values works with all Go types.
+func TestTypes(t *testing.T) {
+	// Test with JSON
+	t.Run("JSON", func(t *testing.T) {
+		store, _ := createStore(t, tracing.Options{Codec: encoding.JSON}, gomap.NewStore(gomap.Options{Codec: encoding.JSON}))
+		test.TestTypes(store, t)
+	})
+
+	// Test with gob
+	t.Run("gob", func(t *testing.T) {
+		store, _ := createStore(t, tracing.Options{Codec: encoding.Gob}, gomap.NewStore(gomap.Options{Codec: encoding.Gob}))
+		test.TestTypes(store, t)
+	})
+}
+
+// TestStoreConcurrent launches a bunch of goroutines that concurrently work with one store.
+func TestStoreConcurrent(t *testing.T) {
+	store, _ := createStore(t, tracing.Options{Codec: encoding.JSON}, gomap.NewStore(gomap.DefaultOptions))
+
+	goroutineCount := 1000
+
+	test.TestConcurrentInteractions(t, goroutineCount, store)
+}
+
+// TestSpans tests if a span with the expected name and attributes is created for each operation.
+func TestSpans(t *testing.T) {
+	store, exporter := createStore(t, tracing.Options{System: "gomap", Codec: encoding.JSON}, gomap.NewStore(gomap.DefaultOptions))
+
+	err := store.Set("foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Get("bar", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Delete("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 5 {
+		t.Fatalf("Expected 5 spans, but was %v", len(spans))
+	}
+	// The size of `"bar"` is 5
+	expected := []struct {
+		name       string
+		attributes []attribute.KeyValue
+	}{
+		{"Set", []attribute.KeyValue{operation("Set"), tracing.KeyKey.String("foo"), tracing.ValueSizeKey.Int(5)}},
+		{"Get", []attribute.KeyValue{operation("Get"), tracing.KeyKey.String("foo"), tracing.ValueSizeKey.Int(5), tracing.FoundKey.Bool(true)}},
+		{"Get", []attribute.KeyValue{operation("Get"), tracing.KeyKey.String("bar"), tracing.FoundKey.Bool(false)}},
+		{"Delete", []attribute.KeyValue{operation("Delete"), tracing.KeyKey.String("foo")}},
+		{"Close", []attribute.KeyValue{operation("Close")}},
+	}
+	for i, span := range spans {
+		if span.Name != expected[i].name {
+			t.Errorf("Expected the span name %q, but was %q", expected[i].name, span.Name)
+		}
+		if span.SpanKind != trace.SpanKindClient {
+			t.Errorf("Expected a client span, but was %v", span.SpanKind)
+		}
+		if span.Status.Code != codes.Unset {
+			t.Errorf("Expected no error status, but was %v", span.Status)
+		}
+		checkAttributes(t, span, append(expected[i].attributes, attribute.String("db.system.name", "gomap")))
+	}
+}
+
+// TestOptions tests the options that change the attributes and names of the spans.
+func TestOptions(t *testing.T) {
+	// The system is derived from the package of the wrapped store
+	store, exporter := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	_, err := store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkAttributes(t, exporter.GetSpans()[0], []attribute.KeyValue{attribute.String("db.system.name", "gomap")})
+
+	// With a namespace
+	store, exporter = createStore(t, tracing.Options{System: "redis", Namespace: "sessions"}, gomap.NewStore(gomap.DefaultOptions))
+	_, err = store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	span := exporter.GetSpans()[0]
+	if span.Name != "Get sessions" {
+		t.Errorf("Expected the span name %q, but was %q", "Get sessions", span.Name)
+	}
+	checkAttributes(t, span, []attribute.KeyValue{
+		attribute.String("db.system.name", "redis"),
+		attribute.String("db.namespace", "sessions"),
+	})
+
+	// With hashed keys
+	store, exporter = createStore(t, tracing.Options{HashKeys: true}, gomap.NewStore(gomap.DefaultOptions))
+	_, err = store.Get("foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte("foo"))
+	checkAttributes(t, exporter.GetSpans()[0], []attribute.KeyValue{tracing.KeyKey.String(hex.EncodeToString(hash[:]))})
+}
+
+// TestParentSpan tests if the spans of the methods that take a context are children of the span in the context.
+func TestParentSpan(t *testing.T) {
+	store, exporter := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "parent")
+
+	err := store.SetContext(ctx, "foo", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.GetContext(ctx, "foo", new(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DeleteContext(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CloseContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 5 {
+		t.Fatalf("Expected 5 spans, but was %v", len(spans))
+	}
+	for _, span := range spans[:4] {
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected the %q span to be a child of the parent span", span.Name)
+		}
+	}
+}
+
+// TestErrors tests some error cases.
+func TestErrors(t *testing.T) {
+	// Test empty key
+	store, exporter := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Set("", "bar")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	_, err = store.Get("", new(string))
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	err = store.Delete("")
+	if err == nil {
+		t.Error("Expected an error")
+	}
+	// Invalid arguments don't reach the wrapped store, so they're not traced
+	if len(exporter.GetSpans()) != 0 {
+		t.Errorf("Expected no spans, but was %v", len(exporter.GetSpans()))
+	}
+
+	// Test errors of the wrapped store, which are recorded in the spans
+	store, exporter = createStore(t, tracing.Options{}, test.FailingStore{Err: errFailing})
+	err = store.Set("foo", "bar")
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Expected the error of the wrapped store, but was: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.GetContext(ctx, "foo", new(string))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context error, but was: %v", err)
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, but was %v", len(spans))
+	}
+	for i, errorType := range []string{"unavailable", "canceled"} {
+		span := spans[i]
+		if span.Status.Code != codes.Error {
+			t.Errorf("Expected an error status, but was %v", span.Status)
+		}
+		if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+			t.Errorf("Expected an exception event, but was %v", span.Events)
+		}
+		checkAttributes(t, span, []attribute.KeyValue{attribute.String("error.type", errorType)})
+	}
+
+	// Test invalid options
+	_, err = tracing.NewStore(tracing.Options{})
+	if err == nil {
+		t.Error("Expected an error")
+	}
+}
+
+// TestSentinelErrors tests if the errors of the wrapped store can be checked with errors.Is().
+func TestSentinelErrors(t *testing.T) {
+	store, _ := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestSentinelErrors(store, t)
+}
+
+// TestTypedStore tests if the store works via gokv.Typed.
+func TestTypedStore(t *testing.T) {
+	store, _ := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	test.TestTypedStore(store, t)
+}
+
+// TestClose tests if the close method returns any errors.
+func TestClose(t *testing.T) {
+	store, _ := createStore(t, tracing.Options{}, gomap.NewStore(gomap.DefaultOptions))
+	err := store.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// createStore creates a tracing store for the wrapped store, with a tracer provider
+// that exports the spans to an in-memory exporter, which is returned as well.
+func createStore(t *testing.T, options tracing.Options, wrapped gokv.Store) (tracing.Store, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	options.Store = wrapped
+	options.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	store, err := tracing.NewStore(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, exporter
+}
+
+func operation(name string) attribute.KeyValue {
+	return attribute.String("db.operation.name", name)
+}
+
+func attributeValue(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+// checkAttributes checks if the span has the expected attributes, among others.
+func checkAttributes(t *testing.T, span tracetest.SpanStub, expected []attribute.KeyValue) {
+	t.Helper()
+	for _, kv := range expected {
+		actual, ok := attributeValue(span, kv.Key)
+		if !ok {
+			t.Errorf("Expected the attribute %q in the %q span", kv.Key, span.Name)
+		} else if actual != kv.Value {
+			t.Errorf("Expected %v for the attribute %q in the %q span, but was %v", kv.Value.Emit(), kv.Key, span.Name, actual.Emit())
+		}
+	}
+}
+
+var errFailing = gokv.WrapError(gokv.ErrUnavailable, errors.New("the store is failing"))
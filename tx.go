@@ -0,0 +1,42 @@
+package gokv
+
+import (
+	"errors"
+)
+
+// ErrReadOnlyTx is returned by Tx.Set() and Tx.Delete() when they're called within Transactional.View().
+var ErrReadOnlyTx = errors.New("the transaction is read-only")
+
+// Tx is a transaction that's passed to the function given to Transactional.Update() or Transactional.View().
+// Its methods work like the ones of Store, including the automatic (un-)marshalling of values,
+// but they're executed within the transaction.
+// A Tx is only valid during the call of that function and must not be used concurrently.
+type Tx interface {
+	// Set stores the given value for the given key within the transaction.
+	// The key must not be "" and the value must not be nil.
+	Set(k string, v any) error
+	// Get retrieves the stored value for the given key within the transaction.
+	// If no value is found it returns (false, nil).
+	// The key must not be "" and the pointer must not be nil.
+	Get(k string, v any) (found bool, err error)
+	// Delete deletes the stored value for the given key within the transaction.
+	// Deleting a non-existing key-value pair does NOT lead to an error.
+	// The key must not be "".
+	Delete(k string) error
+}
+
+// Transactional is implemented by stores that can execute multiple operations in a single transaction,
+// for example to atomically move a value from one key to another or to update several related keys.
+//
+// The isolation level and whether the function can be called multiple times
+// (when the transaction conflicts with a concurrent one) depend on the implementation.
+// So the function shouldn't have side effects other than working with the Tx.
+type Transactional interface {
+	// Update executes the given function within a read-write transaction.
+	// If the function returns nil, the transaction is committed.
+	// If it returns an error, the transaction is rolled back and the error is returned.
+	Update(fn func(tx Tx) error) error
+	// View executes the given function within a read-only transaction.
+	// Set() and Delete() of the Tx return ErrReadOnlyTx.
+	View(fn func(tx Tx) error) error
+}
@@ -0,0 +1,133 @@
+package gokv
+
+import (
+	"errors"
+)
+
+// Typed is a type-safe wrapper around a Store for values of type T.
+// Instead of passing any value to Set() and a pointer to Get(),
+// you pass and get values of type T, so passing a value of the wrong type is a compile-time error
+// instead of an unmarshal error at runtime.
+//
+// Usage:
+//
+//	users := gokv.NewTyped[User](store)
+//	err := users.Set("123", User{Name: "foo"})
+//	user, found, err := users.Get("123")
+//
+// The values are (un-)marshalled by the wrapped store, so they're compatible with the ones
+// that are stored and retrieved via the store directly.
+// Typed doesn't close the wrapped store, so call Close() on it or on the store itself when you're done.
+type Typed[T any] struct {
+	store Store
+}
+
+// NewTyped returns a Typed wrapper around the given store for values of type T.
+func NewTyped[T any](store Store) Typed[T] {
+	return Typed[T]{
+		store: store,
+	}
+}
+
+// Store returns the wrapped store, for example for checking if it implements one of the optional interfaces.
+func (t Typed[T]) Store() Store {
+	return t.store
+}
+
+// Set stores the given value for the given key.
+// The key must not be "". If T is a pointer or interface type, the value must not be nil.
+func (t Typed[T]) Set(k string, v T) error {
+	return t.store.Set(k, v)
+}
+
+// Get retrieves the stored value for the given key.
+// If no value is found it returns the zero value of T and false.
+// The key must not be "".
+func (t Typed[T]) Get(k string) (v T, found bool, err error) {
+	found, err = t.store.Get(k, &v)
+	if err != nil || !found {
+		var zero T
+		return zero, found, err
+	}
+	return v, true, nil
+}
+
+// Delete deletes the stored value for the given key.
+// Deleting a non-existing key-value pair does NOT lead to an error.
+// The key must not be "".
+func (t Typed[T]) Delete(k string) error {
+	return t.store.Delete(k)
+}
+
+// SetMulti stores all given key-value pairs.
+// It uses the bulk operation of the wrapped store if it implements BatchStore, see AsBatchStore().
+// No key must be "".
+func (t Typed[T]) SetMulti(kvs map[string]T) error {
+	anyKVs := make(map[string]any, len(kvs))
+	for k, v := range kvs {
+		anyKVs[k] = v
+	}
+	return AsBatchStore(t.store).SetMulti(anyKVs)
+}
+
+// GetMulti retrieves the values for the given keys.
+// Keys that aren't found are missing from the returned map.
+// It uses the bulk operation of the wrapped store if it implements BatchStore, see AsBatchStore().
+// No key must be "".
+func (t Typed[T]) GetMulti(keys []string) (map[string]T, error) {
+	anyResult, err := AsBatchStore(t.store).GetMulti(keys, func() any {
+		return new(T)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]T, len(anyResult))
+	for k, v := range anyResult {
+		result[k] = *(v.(*T))
+	}
+	return result, nil
+}
+
+// DeleteMulti deletes the stored values for the given keys.
+// Deleting non-existing key-value pairs does NOT lead to an error.
+// It uses the bulk operation of the wrapped store if it implements BatchStore, see AsBatchStore().
+// No key must be "".
+func (t Typed[T]) DeleteMulti(keys []string) error {
+	return AsBatchStore(t.store).DeleteMulti(keys)
+}
+
+// Keys calls fn for each key in the store.
+// The iteration stops when fn returns false.
+// It returns ErrNotSupported if the wrapped store doesn't implement Iterable.
+func (t Typed[T]) Keys(fn func(k string) bool) error {
+	iterable, ok := t.store.(Iterable)
+	if !ok {
+		return WrapError(ErrNotSupported, errors.New("keys aren't supported, because the store doesn't implement gokv.Iterable"))
+	}
+	return iterable.Keys(fn)
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix.
+// The iteration stops when fn returns false, or when a value can't be unmarshalled,
+// in which case the error is returned.
+// An empty prefix matches all keys.
+// It returns ErrNotSupported if the wrapped store doesn't implement PrefixScanner.
+func (t Typed[T]) ScanPrefix(prefix string, fn func(k string, v T) bool) error {
+	scanner, ok := t.store.(PrefixScanner)
+	if !ok {
+		return WrapError(ErrNotSupported, errors.New("prefix scans aren't supported, because the store doesn't implement gokv.PrefixScanner"))
+	}
+
+	var decodeErr error
+	err := scanner.ScanPrefix(prefix, func(k string, decode func(v any) error) bool {
+		var v T
+		if decodeErr = decode(&v); decodeErr != nil {
+			return false
+		}
+		return fn(k, v)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
@@ -0,0 +1,156 @@
+package util
+
+import (
+	"context"
+	"sync"
+)
+
+// Broadcaster sends published events to all subscribers whose key matches.
+// In-process implementations use it to support watching for changes.
+//
+// Publishing never blocks: Each subscriber has its own queue,
+// from which a goroutine sends the events to the subscriber's channel.
+// So when events are published while holding a lock, subscribers receive them in the same order
+// in which the changes were made, without slow subscribers blocking writers.
+//
+// The queues are bounded, so a subscriber that stops receiving without canceling its context
+// doesn't make the memory grow forever: When a queue is full, its events are dropped
+// and the subscriber receives the overflow event instead, after which its channel is closed.
+type Broadcaster[E any] struct {
+	lock        sync.Mutex
+	subs        map[*subscription[E]]struct{}
+	closed      chan struct{}
+	maxQueueLen int
+	overflow    E
+}
+
+type subscription[E any] struct {
+	match func(k string) bool
+	// Guarded by the broadcaster's lock
+	queue      []E
+	overflowed bool
+	notify     chan struct{}
+	out        chan E
+}
+
+// DefaultMaxQueueLen is the number of events that implementations queue for each subscriber by default.
+const DefaultMaxQueueLen = 10000
+
+// NewBroadcaster creates a new Broadcaster that queues up to maxQueueLen events for each subscriber.
+// A subscriber whose queue is full receives the overflow event as last event,
+// for example a gokv.Event whose Err is gokv.ErrEventsDropped.
+func NewBroadcaster[E any](maxQueueLen int, overflow E) *Broadcaster[E] {
+	return &Broadcaster[E]{
+		subs:        make(map[*subscription[E]]struct{}),
+		closed:      make(chan struct{}),
+		maxQueueLen: maxQueueLen,
+		overflow:    overflow,
+	}
+}
+
+// Subscribe returns a channel that receives the events that are published for keys for which match returns true.
+// The channel is closed when the context is canceled or the broadcaster is closed.
+func (b *Broadcaster[E]) Subscribe(ctx context.Context, match func(k string) bool) <-chan E {
+	sub := &subscription[E]{
+		match:  match,
+		notify: make(chan struct{}, 1),
+		out:    make(chan E),
+	}
+
+	b.lock.Lock()
+	select {
+	case <-b.closed:
+		b.lock.Unlock()
+		close(sub.out)
+		return sub.out
+	default:
+	}
+	b.subs[sub] = struct{}{}
+	b.lock.Unlock()
+
+	go b.forward(ctx, sub)
+	return sub.out
+}
+
+// HasSubscribers returns true if there's at least one subscriber.
+// It can be used to skip creating events when nobody receives them.
+func (b *Broadcaster[E]) HasSubscribers() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return len(b.subs) > 0
+}
+
+// Publish queues the event for all subscribers whose match function returns true for the given key.
+// Subscribers whose queue is full are unsubscribed, after they received the overflow event.
+func (b *Broadcaster[E]) Publish(k string, e E) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for sub := range b.subs {
+		if !sub.match(k) {
+			continue
+		}
+		if len(sub.queue) >= b.maxQueueLen {
+			sub.queue = []E{b.overflow}
+			sub.overflowed = true
+			delete(b.subs, sub)
+		} else {
+			sub.queue = append(sub.queue, e)
+		}
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close closes the channels of all subscribers.
+// Events that weren't received yet are dropped.
+// It's safe to call Close multiple times.
+func (b *Broadcaster[E]) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+}
+
+// forward sends the queued events of the subscriber to its channel until the context is canceled,
+// the broadcaster is closed or the overflow event was sent.
+func (b *Broadcaster[E]) forward(ctx context.Context, sub *subscription[E]) {
+	defer func() {
+		b.lock.Lock()
+		delete(b.subs, sub)
+		b.lock.Unlock()
+		close(sub.out)
+	}()
+
+	for {
+		b.lock.Lock()
+		queue, overflowed := sub.queue, sub.overflowed
+		sub.queue = nil
+		b.lock.Unlock()
+
+		for _, e := range queue {
+			select {
+			case sub.out <- e:
+			case <-ctx.Done():
+				return
+			case <-b.closed:
+				return
+			}
+		}
+		if overflowed {
+			return
+		}
+
+		select {
+		case <-sub.notify:
+		case <-ctx.Done():
+			return
+		case <-b.closed:
+			return
+		}
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/philippgille/gokv/util
 
 go 1.20
+
+require (
+	github.com/philippgille/gokv v0.7.0
+	github.com/philippgille/gokv/encoding v0.7.0
+)
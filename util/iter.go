@@ -0,0 +1,44 @@
+package util
+
+// KeySliceIterator is a gokv.KeyIterator over a slice of keys.
+// Implementations that can't offer a native cursor can take a snapshot
+// of their keys and iterate over it with this.
+type KeySliceIterator struct {
+	keys []string
+	pos  int
+}
+
+// NewKeySliceIterator creates a new KeySliceIterator for the given keys.
+func NewKeySliceIterator(keys []string) *KeySliceIterator {
+	return &KeySliceIterator{
+		keys: keys,
+		pos:  -1,
+	}
+}
+
+// Next advances the iterator to the next key.
+func (i *KeySliceIterator) Next() bool {
+	if i.pos < len(i.keys) {
+		i.pos++
+	}
+	return i.pos < len(i.keys)
+}
+
+// Key returns the key that the iterator currently points to.
+func (i *KeySliceIterator) Key() string {
+	if i.pos < 0 || i.pos >= len(i.keys) {
+		return ""
+	}
+	return i.keys[i.pos]
+}
+
+// Err always returns nil, because iterating over a slice can't fail.
+func (i *KeySliceIterator) Err() error {
+	return nil
+}
+
+// Close releases the slice of keys.
+func (i *KeySliceIterator) Close() error {
+	i.keys = nil
+	return nil
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// Janitor periodically calls a cleanup function in a background goroutine.
+// In-memory implementations that support TTLs use it to delete expired key-value pairs.
+// The goroutine is only started on the first call of Start(),
+// so stores that never use a TTL don't have a goroutine running.
+type Janitor struct {
+	interval  time.Duration
+	cleanup   func()
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+}
+
+// NewJanitor creates a new Janitor that calls cleanup in the given interval once it's started.
+func NewJanitor(interval time.Duration, cleanup func()) *Janitor {
+	return &Janitor{
+		interval: interval,
+		cleanup:  cleanup,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start starts the background goroutine, unless it was already started or the janitor was stopped.
+// It's safe to call Start multiple times and from multiple goroutines.
+func (j *Janitor) Start() {
+	j.startOnce.Do(func() {
+		select {
+		case <-j.stop:
+			return
+		default:
+		}
+		go j.run()
+	})
+}
+
+// Stop stops the background goroutine.
+// It's safe to call Stop multiple times and before Start.
+// A stopped janitor can't be started again.
+func (j *Janitor) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+}
+
+func (j *Janitor) run() {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			j.cleanup()
+		case <-j.stop:
+			return
+		}
+	}
+}
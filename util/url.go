@@ -0,0 +1,156 @@
+package util
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/philippgille/gokv/encoding"
+)
+
+// URLQuery reads the query parameters of a URL that's passed to a gokv.Opener,
+// converting them to the types of the fields of an implementation's Options struct.
+// Parameters that aren't set lead to the zero value, so that the implementation's defaults are used.
+// The first invalid value is remembered and returned by Err(), so the parameters can be read
+// without checking an error after each one.
+type URLQuery struct {
+	values url.Values
+	read   map[string]bool
+	err    error
+}
+
+// NewURLQuery creates a new URLQuery for the query parameters of the given URL.
+func NewURLQuery(u *url.URL) *URLQuery {
+	return &URLQuery{
+		values: u.Query(),
+		read:   make(map[string]bool),
+	}
+}
+
+// Has returns true if the parameter is set, even if it's set to "".
+func (q *URLQuery) Has(name string) bool {
+	_, ok := q.values[name]
+	return ok
+}
+
+// String returns the value of the parameter.
+func (q *URLQuery) String(name string) string {
+	q.read[name] = true
+	return q.values.Get(name)
+}
+
+// Int returns the value of the parameter as int.
+func (q *URLQuery) Int(name string) int {
+	return int(q.parseInt(name, 0))
+}
+
+// Int64 returns the value of the parameter as int64.
+func (q *URLQuery) Int64(name string) int64 {
+	return q.parseInt(name, 64)
+}
+
+func (q *URLQuery) parseInt(name string, bitSize int) int64 {
+	s := q.String(name)
+	if s == "" {
+		return 0
+	}
+	i, err := strconv.ParseInt(s, 10, bitSize)
+	if err != nil {
+		q.setErr(name, s, err)
+	}
+	return i
+}
+
+// Bool returns the value of the parameter as bool, see strconv.ParseBool() for the valid values.
+func (q *URLQuery) Bool(name string) bool {
+	s := q.String(name)
+	if s == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		q.setErr(name, s, err)
+	}
+	return b
+}
+
+// Duration returns the value of the parameter as time.Duration, see time.ParseDuration() for the valid values.
+func (q *URLQuery) Duration(name string) time.Duration {
+	s := q.String(name)
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		q.setErr(name, s, err)
+	}
+	return d
+}
+
+// Codec returns the codec that's registered with the name in the "codec" parameter, see encoding.ByName().
+// It returns nil if the parameter isn't set, so that the implementation's default codec is used.
+func (q *URLQuery) Codec() encoding.Codec {
+	s := q.String("codec")
+	if s == "" {
+		return nil
+	}
+	codec, err := encoding.ByName(s)
+	if err != nil && q.err == nil {
+		q.err = err
+	}
+	return codec
+}
+
+// Rest returns the parameters that weren't read yet and marks them as read.
+// It's for implementations that pass unknown parameters on to the backend,
+// like the options in a PostgreSQL connection URL.
+func (q *URLQuery) Rest() url.Values {
+	rest := make(url.Values)
+	for name, values := range q.values {
+		if !q.read[name] {
+			rest[name] = values
+			q.read[name] = true
+		}
+	}
+	return rest
+}
+
+// Err returns the error for the first invalid parameter value,
+// or an error that lists the parameters that weren't read, because they're unknown to the implementation.
+func (q *URLQuery) Err() error {
+	if q.err != nil {
+		return q.err
+	}
+	var unknown []string
+	for name := range q.values {
+		if !q.read[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown URL query parameter(s): %v", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func (q *URLQuery) setErr(name, value string, err error) {
+	if q.err == nil {
+		q.err = fmt.Errorf("invalid value %q for the URL query parameter %q: %w", value, name, err)
+	}
+}
+
+// URLPath returns the file system path of a URL like "bbolt:///tmp/gokv.db" (absolute path)
+// or "bbolt://gokv.db" and "bbolt:gokv.db" (relative paths).
+func URLPath(u *url.URL) string {
+	if u.Opaque != "" {
+		if p, err := url.PathUnescape(u.Opaque); err == nil {
+			return p
+		}
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
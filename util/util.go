@@ -1,7 +1,13 @@
 package util
 
 import (
+	"context"
 	"errors"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/philippgille/gokv"
 )
 
 // CheckKeyAndValue returns an error if k == "" or if v == nil
@@ -12,18 +18,100 @@ func CheckKeyAndValue(k string, v any) error {
 	return CheckVal(v)
 }
 
-// CheckKey returns an error if k == ""
+// CheckKey returns gokv.ErrKeyEmpty if k == ""
 func CheckKey(k string) error {
 	if k == "" {
-		return errors.New("the passed key is an empty string, which is invalid")
+		return gokv.ErrKeyEmpty
 	}
 	return nil
 }
 
-// CheckVal returns an error if v == nil
+// CheckVal returns gokv.ErrValueNil if v == nil
 func CheckVal(v any) error {
 	if v == nil {
-		return errors.New("the passed value is nil, which is not allowed")
+		return gokv.ErrValueNil
+	}
+	return nil
+}
+
+// CheckKeyAndData returns an error if k == "" or if data == nil
+func CheckKeyAndData(k string, data []byte) error {
+	if err := CheckKey(k); err != nil {
+		return err
+	}
+	if data == nil {
+		return gokv.ErrValueNil
 	}
 	return nil
 }
+
+// CheckTTL returns an error if ttl <= 0
+func CheckTTL(ttl time.Duration) error {
+	if ttl <= 0 {
+		return errors.New("the passed TTL is not positive, which is invalid")
+	}
+	return nil
+}
+
+// TTLSeconds returns the given TTL in seconds, rounded up to the next full second,
+// for backends that only support a TTL with a precision of seconds.
+func TTLSeconds(ttl time.Duration) int64 {
+	return int64((ttl + time.Second - 1) / time.Second)
+}
+
+// CheckKeys returns an error if any of the keys is ""
+func CheckKeys(keys []string) error {
+	for _, k := range keys {
+		if err := CheckKey(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalMulti checks all key-value pairs and marshals the values with the given function.
+// It returns an error if any key is "", any value is nil or any value can't be marshalled,
+// so that implementations can validate a whole batch before writing any of it.
+func MarshalMulti(kvs map[string]any, marshal func(v any) ([]byte, error)) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(kvs))
+	for k, v := range kvs {
+		if err := CheckKeyAndValue(k, v); err != nil {
+			return nil, err
+		}
+		data, err := marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = data
+	}
+	return result, nil
+}
+
+// ClassifyError wraps the given error with gokv.WrapError() if it belongs to a class
+// that's independent of the backend:
+// Exceeded context deadlines and network timeouts are classified as gokv.ErrTimeout,
+// refused, reset and aborted connections as gokv.ErrUnavailable.
+// Other errors (and nil) are returned as they are.
+// Implementations use it for the errors that their backend-specific classification doesn't cover.
+func ClassifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return gokv.WrapError(gokv.ErrTimeout, err)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, net.ErrClosed) {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	}
+	return err
+}
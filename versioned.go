@@ -0,0 +1,30 @@
+package gokv
+
+// VersionedStore is a Store that supports optimistic concurrency control (compare-and-swap).
+// It can be used to prevent read-modify-write races between multiple processes that share a store:
+// Read a value with GetVersioned(), modify it and store it with SetIfVersion().
+// If another process changed the value in the meantime, SetIfVersion() doesn't store it
+// and you can start over.
+//
+// Versions are opaque values that are only meaningful for the key they were retrieved for.
+// Don't make any assumptions about them, except that 0 means that the key doesn't exist.
+// Depending on the implementation they're the backend's native version numbers
+// (like Consul's ModifyIndex or etcd's ModRevision), a version column or attribute,
+// or a hash of the value (in which case a value that's changed and then changed back
+// has the same version as before).
+//
+// Writes with Set() and other methods change the version of a key-value pair as well.
+type VersionedStore interface {
+	Store
+	// GetVersioned retrieves the value for the given key, like Get(),
+	// and additionally returns the current version of the key-value pair.
+	// If no value is found it returns (0, false, nil).
+	// The key must not be "" and the pointer must not be nil.
+	GetVersioned(k string, v any) (version uint64, found bool, err error)
+	// SetIfVersion stores the given value for the given key,
+	// but only if the current version of the key-value pair is the given version.
+	// Use the version 0 to store the value only if the key doesn't exist yet.
+	// It returns false (and no error) if the version didn't match.
+	// The key must not be "" and the value must not be nil.
+	SetIfVersion(k string, v any, version uint64) (swapped bool, err error)
+}
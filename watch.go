@@ -0,0 +1,78 @@
+package gokv
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrValueUnavailable is returned by Event.Decode() when the value of a key-value pair
+// that an event was sent for isn't available anymore.
+// This can happen with implementations that retrieve the value on demand,
+// when the key-value pair was deleted in the meantime.
+var ErrValueUnavailable = errors.New("the value isn't available anymore")
+
+// ErrEventsDropped is the error of the last event that implementations send when a watcher
+// doesn't receive the events fast enough and they can't be buffered anymore.
+var ErrEventsDropped = errors.New("the events weren't received in time and were dropped")
+
+// EventType is the type of change that an Event describes.
+type EventType int
+
+const (
+	// EventSet means that a value was stored for the key.
+	EventSet EventType = iota + 1
+	// EventDelete means that the key-value pair was deleted.
+	// Depending on the implementation it's also sent when the key-value pair expired.
+	EventDelete
+)
+
+// String returns "set", "delete" or "unknown".
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change of a key-value pair. It's sent by a Watcher.
+type Event struct {
+	// Type is the type of the change.
+	Type EventType
+	// Key is the key of the key-value pair that was changed.
+	Key string
+	// Revision is the backend's revision of the change, like etcd's ModRevision or Consul's ModifyIndex.
+	// It's 0 if the backend doesn't provide one.
+	Revision uint64
+	// Decode unmarshals the new value into v, which must be a pointer like the one you'd pass to Get().
+	// It's nil for EventDelete.
+	// Depending on the implementation the value is either part of the notification,
+	// or it's retrieved from the backend when Decode is called, in which case it can be newer than the change
+	// and ErrValueUnavailable is returned if the key-value pair was deleted in the meantime.
+	Decode func(v any) error
+	// Err is set if watching failed. The event with the error is the last one before the channel is closed.
+	// The other fields are empty in this case.
+	Err error
+}
+
+// Watcher is implemented by stores that can notify about changes of key-value pairs,
+// for example to reload configuration or to invalidate caches.
+//
+// Events are sent for all changes that the backend notifies about,
+// including changes that are made by other clients, unless the implementation says otherwise.
+// Depending on the backend, rapid successive changes can be collapsed into fewer events.
+// Events are only sent for changes that happen after the Watch method returned.
+type Watcher interface {
+	// Watch sends an event to the returned channel for each change of the given key.
+	// Watching stops and the channel is closed when the context is canceled.
+	// Receive from the channel in a timely manner, because some implementations
+	// buffer the events or even block until they're received,
+	// and others stop watching with an event whose Err is ErrEventsDropped when their buffer is full.
+	Watch(ctx context.Context, k string) (<-chan Event, error)
+	// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+	// An empty prefix matches all keys.
+	WatchPrefix(ctx context.Context, prefix string) (<-chan Event, error)
+}
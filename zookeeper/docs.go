@@ -1,4 +1,7 @@
 /*
 Package zookeeper contains an implementation of the `gokv.Store` interface for Apache ZooKeeper.
+
+Importing the package registers it for gokv.Open(), so a client can also be created from a URL like
+"zookeeper://host1:2181,host2:2181/gokv/?codec=gob" (the path is the PathPrefix).
 */
 package zookeeper
@@ -1,12 +1,16 @@
 package zookeeper
 
 import (
+	"context"
 	"errors"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/samuel/go-zookeeper/zk"
 
+	"github.com/philippgille/gokv"
 	"github.com/philippgille/gokv/encoding"
 	"github.com/philippgille/gokv/util"
 )
@@ -36,11 +40,29 @@ func (c Client) Set(k string, v any) error {
 	acl := zk.WorldACL(zk.PermAll)
 	_, err = c.c.Create(k, data, 0, acl)
 	if err != nil {
-		if err.Error() == "zk: node already exists" {
+		if errors.Is(err, zk.ErrNodeExists) {
 			_, err = c.c.Set(k, data, -1)
 		}
 	}
-	return err
+	return classifyError(err)
+}
+
+// SetRaw stores the given data for the given key, without marshalling it.
+// The key must not be "" and the data must not be nil.
+func (c Client) SetRaw(k string, data []byte) error {
+	if err := util.CheckKeyAndData(k, data); err != nil {
+		return err
+	}
+
+	k = c.pathPrefix + k
+	acl := zk.WorldACL(zk.PermAll)
+	_, err := c.c.Create(k, data, 0, acl)
+	if err != nil {
+		if errors.Is(err, zk.ErrNodeExists) {
+			_, err = c.c.Set(k, data, -1)
+		}
+	}
+	return classifyError(err)
 }
 
 // Get retrieves the stored value for the given key.
@@ -57,15 +79,98 @@ func (c Client) Get(k string, v any) (found bool, err error) {
 	k = c.pathPrefix + k
 	data, _, err := c.c.Get(k)
 	if err != nil {
-		if err.Error() == "zk: node does not exist" {
+		if errors.Is(err, zk.ErrNoNode) {
 			return false, nil
 		}
-		return false, err
+		return false, classifyError(err)
 	}
 
 	return true, c.codec.Unmarshal(data, v)
 }
 
+// GetRaw retrieves the stored data for the given key, without unmarshalling it.
+// If no value is found it returns (nil, false, nil).
+// The key must not be "".
+func (c Client) GetRaw(k string) (data []byte, found bool, err error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, false, err
+	}
+
+	k = c.pathPrefix + k
+	data, _, err = c.c.Get(k)
+	if err != nil {
+		if errors.Is(err, zk.ErrNoNode) {
+			return nil, false, nil
+		}
+		return nil, false, classifyError(err)
+	}
+
+	return data, true, nil
+}
+
+// GetVersioned retrieves the stored value for the given key,
+// and additionally returns its version, which is the node's Stat.Version + 1
+// (because ZooKeeper starts counting at 0, but 0 means that the key doesn't exist).
+// If no value is found it returns (0, false, nil).
+// The key must not be "" and the pointer must not be nil.
+func (c Client) GetVersioned(k string, v any) (version uint64, found bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return 0, false, err
+	}
+
+	k = c.pathPrefix + k
+	data, stat, err := c.c.Get(k)
+	if err != nil {
+		if errors.Is(err, zk.ErrNoNode) {
+			return 0, false, nil
+		}
+		return 0, false, classifyError(err)
+	}
+
+	return uint64(stat.Version) + 1, true, c.codec.Unmarshal(data, v)
+}
+
+// SetIfVersion stores the given value for the given key,
+// but only if the node's version matches the given version (see GetVersioned()).
+// Use the version 0 to store the value only if the key doesn't exist yet.
+// It returns false (and no error) if the version didn't match.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetIfVersion(k string, v any, version uint64) (swapped bool, err error) {
+	if err := util.CheckKeyAndValue(k, v); err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return false, err
+	}
+
+	k = c.pathPrefix + k
+	if version == 0 {
+		_, err = c.c.Create(k, data, 0, zk.WorldACL(zk.PermAll))
+		if errors.Is(err, zk.ErrNodeExists) {
+			return false, nil
+		}
+	} else {
+		_, err = c.c.Set(k, data, int32(version-1))
+		if errors.Is(err, zk.ErrBadVersion) || errors.Is(err, zk.ErrNoNode) {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, classifyError(err)
+	}
+	return true, nil
+}
+
+// SetNX stores the given value for the given key by creating its node,
+// but only if the node doesn't exist yet.
+// It returns false (and no error) if the key already exists.
+// The key must not be "" and the value must not be nil.
+func (c Client) SetNX(k string, v any) (created bool, err error) {
+	return c.SetIfVersion(k, v, 0)
+}
+
 // Delete deletes the stored value for the given key.
 // Deleting a non-existing key-value pair does NOT lead to an error.
 // The key must not be "".
@@ -76,10 +181,285 @@ func (c Client) Delete(k string) error {
 
 	k = c.pathPrefix + k
 	err := c.c.Delete(k, -1)
-	if err != nil && err.Error() == "zk: node does not exist" {
+	if errors.Is(err, zk.ErrNoNode) {
 		return nil
 	}
-	return err
+	return classifyError(err)
+}
+
+// ScanPrefix calls fn for each key-value pair whose key starts with the given prefix, in byte-sorted order.
+// The iteration stops when fn returns false.
+// The keys are looked up among the children of the node that the PathPrefix points to,
+// and each value is retrieved with a separate request.
+// Key-value pairs that are deleted during the scan are skipped.
+func (c Client) ScanPrefix(prefix string, fn func(k string, decode func(v any) error) bool) error {
+	parent, parentNode, namePrefix := c.splitPathPrefix()
+	children, _, err := c.c.Children(parentNode)
+	if err != nil {
+		return classifyError(err)
+	}
+	sort.Strings(children)
+
+	for _, child := range children {
+		if !strings.HasPrefix(child, namePrefix+prefix) {
+			continue
+		}
+		data, _, err := c.c.Get(parent + child)
+		if err != nil {
+			if errors.Is(err, zk.ErrNoNode) {
+				continue
+			}
+			return classifyError(err)
+		}
+		decode := func(v any) error {
+			return c.codec.Unmarshal(data, v)
+		}
+		if !fn(strings.TrimPrefix(child, namePrefix), decode) {
+			break
+		}
+	}
+	return nil
+}
+
+// splitPathPrefix splits the PathPrefix into the path of the parent node of the keys' nodes
+// (with and without trailing "/") and the prefix of the nodes' names.
+// The PathPrefix can end with a partial node name, like "/foo/bar" for keys like "/foo/barKey",
+// in which case the "bar" must be part of the prefix of the children's names.
+func (c Client) splitPathPrefix() (parent, parentNode, namePrefix string) {
+	lastSlash := strings.LastIndex(c.pathPrefix, "/")
+	parent = c.pathPrefix[:lastSlash+1]
+	namePrefix = c.pathPrefix[lastSlash+1:]
+	parentNode = strings.TrimSuffix(parent, "/")
+	if parentNode == "" {
+		parentNode = "/"
+	}
+	return parent, parentNode, namePrefix
+}
+
+// Watch sends an event to the returned channel for each change of the given key, using ZooKeeper watches.
+// The events' Revision is the node's Mzxid for set events and 0 for delete events.
+// The value is retrieved when the watch is registered again after a change,
+// so changes that happen in quick succession can be collapsed into one event.
+// Watching stops and the channel is closed when the context is canceled.
+// The key must not be "".
+func (c Client) Watch(ctx context.Context, k string) (<-chan gokv.Event, error) {
+	if err := util.CheckKey(k); err != nil {
+		return nil, err
+	}
+
+	w := newNodeWatcher(ctx, c, true)
+	if err := w.update(k, false); err != nil {
+		return nil, classifyError(err)
+	}
+	w.quiet = false
+	go w.run(nil)
+	return w.events, nil
+}
+
+// WatchPrefix is like Watch, but sends events for all keys that start with the given prefix.
+// The keys are looked up among the children of the node that the PathPrefix points to,
+// which are watched as well to detect new keys.
+func (c Client) WatchPrefix(ctx context.Context, prefix string) (<-chan gokv.Event, error) {
+	w := newNodeWatcher(ctx, c, false)
+	w.prefix = prefix
+	childrenEvents, err := w.updateChildren()
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	w.quiet = false
+	go w.run(childrenEvents)
+	return w.events, nil
+}
+
+// nodeWatcher watches nodes and sends events for their changes.
+// ZooKeeper's watches only trigger once, so they're registered again after each change,
+// and the node's state is compared to the previous one to determine the events.
+// All state is owned by the goroutine that executes run().
+type nodeWatcher struct {
+	ctx context.Context
+	c   Client
+	// The key prefix for watching the children of the parent node
+	prefix string
+	// When watching a single key, it's also watched while it doesn't exist.
+	// When watching a prefix, new nodes are detected via the children watch instead.
+	watchAbsent bool
+	// Don't send events while the initial state is determined
+	quiet    bool
+	nodes    map[string]nodeState
+	zkEvents chan keyedZkEvent
+	events   chan gokv.Event
+}
+
+type nodeState struct {
+	exists bool
+	mzxid  int64
+}
+
+type keyedZkEvent struct {
+	key string
+	zk.Event
+}
+
+func newNodeWatcher(ctx context.Context, c Client, watchAbsent bool) *nodeWatcher {
+	return &nodeWatcher{
+		ctx:         ctx,
+		c:           c,
+		watchAbsent: watchAbsent,
+		quiet:       true,
+		nodes:       make(map[string]nodeState),
+		zkEvents:    make(chan keyedZkEvent),
+		events:      make(chan gokv.Event),
+	}
+}
+
+func (w *nodeWatcher) run(childrenEvents <-chan zk.Event) {
+	defer close(w.events)
+	for {
+		var err error
+		select {
+		case <-w.ctx.Done():
+			return
+		case ev := <-w.zkEvents:
+			err = checkZkEvent(ev.Event)
+			if err == nil {
+				err = w.update(ev.key, ev.Type == zk.EventNodeDeleted)
+			}
+		case ev := <-childrenEvents:
+			err = checkZkEvent(ev)
+			if err == nil {
+				childrenEvents, err = w.updateChildren()
+			}
+		}
+		if err != nil {
+			w.send(gokv.Event{Err: classifyError(err)})
+			return
+		}
+	}
+}
+
+// checkZkEvent returns an error if the event means that the watch doesn't work anymore,
+// for example because the session expired.
+func checkZkEvent(ev zk.Event) error {
+	if ev.Err != nil {
+		return ev.Err
+	}
+	if ev.Type == zk.EventNotWatching {
+		return errors.New("the ZooKeeper watch was removed")
+	}
+	return nil
+}
+
+// update registers a watch for the node of the given key and sends events for the changes
+// compared to the previous state.
+func (w *nodeWatcher) update(key string, deleted bool) error {
+	data, stat, err := w.register(key)
+	if err != nil {
+		return classifyError(err)
+	}
+
+	prev := w.nodes[key]
+	if prev.exists && (deleted || stat == nil) {
+		w.send(gokv.Event{
+			Type: gokv.EventDelete,
+			Key:  key,
+		})
+	}
+	if stat != nil && (!prev.exists || deleted || stat.Mzxid != prev.mzxid) {
+		w.send(gokv.Event{
+			Type:     gokv.EventSet,
+			Key:      key,
+			Revision: uint64(stat.Mzxid),
+			Decode: func(v any) error {
+				return w.c.codec.Unmarshal(data, v)
+			},
+		})
+	}
+
+	if stat == nil && !w.watchAbsent {
+		delete(w.nodes, key)
+	} else {
+		state := nodeState{exists: stat != nil}
+		if stat != nil {
+			state.mzxid = stat.Mzxid
+		}
+		w.nodes[key] = state
+	}
+	return nil
+}
+
+// updateChildren registers a watch for the children of the parent node
+// and starts watching the nodes of new keys with the prefix.
+func (w *nodeWatcher) updateChildren() (<-chan zk.Event, error) {
+	_, parentNode, namePrefix := w.c.splitPathPrefix()
+	children, _, childrenEvents, err := w.c.c.ChildrenW(parentNode)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	for _, child := range children {
+		if !strings.HasPrefix(child, namePrefix+w.prefix) {
+			continue
+		}
+		key := strings.TrimPrefix(child, namePrefix)
+		if _, ok := w.nodes[key]; ok {
+			continue
+		}
+		if err := w.update(key, false); err != nil {
+			return nil, classifyError(err)
+		}
+	}
+	return childrenEvents, nil
+}
+
+// register retrieves the node of the given key and registers a watch for its next change.
+// It returns a nil stat if the node doesn't exist.
+func (w *nodeWatcher) register(key string) ([]byte, *zk.Stat, error) {
+	path := w.c.pathPrefix + key
+	for {
+		data, stat, ch, err := w.c.c.GetW(path)
+		if err == nil {
+			w.forward(key, ch)
+			return data, stat, nil
+		} else if !errors.Is(err, zk.ErrNoNode) {
+			return nil, nil, classifyError(err)
+		}
+		if !w.watchAbsent {
+			return nil, nil, nil
+		}
+		exists, _, ch, err := w.c.c.ExistsW(path)
+		if err != nil {
+			return nil, nil, classifyError(err)
+		}
+		w.forward(key, ch)
+		if !exists {
+			return nil, nil, nil
+		}
+		// The node was created in the meantime, so get its data.
+		// Events for the additional watch don't lead to duplicate events, because the state doesn't change.
+	}
+}
+
+// forward sends the event of the given one-time watch to the run() goroutine.
+func (w *nodeWatcher) forward(key string, ch <-chan zk.Event) {
+	go func() {
+		select {
+		case ev := <-ch:
+			select {
+			case w.zkEvents <- keyedZkEvent{key: key, Event: ev}:
+			case <-w.ctx.Done():
+			}
+		case <-w.ctx.Done():
+		}
+	}()
+}
+
+func (w *nodeWatcher) send(event gokv.Event) {
+	if w.quiet {
+		return
+	}
+	select {
+	case w.events <- event:
+	case <-w.ctx.Done():
+	}
 }
 
 // Close closes the client.
@@ -137,13 +517,13 @@ func NewClient(options Options) (Client, error) {
 
 	c, _, err := zk.Connect(options.Servers, 2*time.Second, zk.WithLogInfo(false))
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	// Check connection
 	_, _, err = c.Children("/")
 	if err != nil {
-		return result, err
+		return result, classifyError(err)
 	}
 
 	// Create node if it doesn't exist (or even multiple nodes).
@@ -173,13 +553,13 @@ func NewClient(options Options) (Client, error) {
 				nodeToCreate += pathElem
 				_, _, err = c.Get(nodeToCreate)
 				if err != nil {
-					if err.Error() == "zk: node does not exist" {
+					if errors.Is(err, zk.ErrNoNode) {
 						_, err = c.Create(nodeToCreate, nil, 0, acl)
 						if err != nil {
-							return result, err
+							return result, classifyError(err)
 						}
 					} else {
-						return result, err
+						return result, classifyError(err)
 					}
 				}
 				nodeToCreate += "/"
@@ -193,3 +573,42 @@ func NewClient(options Options) (Client, error) {
 
 	return result, nil
 }
+
+func init() {
+	gokv.Register("zookeeper", open)
+}
+
+// open creates a client from a URL like "zookeeper://host1:2181,host2:2181/gokv/?codec=gob".
+// The URL path is the PathPrefix.
+func open(u *url.URL) (gokv.Store, error) {
+	q := util.NewURLQuery(u)
+	options := Options{
+		PathPrefix: u.Path,
+		Codec:      q.Codec(),
+	}
+	if u.Host != "" {
+		options.Servers = strings.Split(u.Host, ",")
+	}
+	if err := q.Err(); err != nil {
+		return nil, err
+	}
+	client, err := NewClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// classifyError wraps errors of the ZooKeeper client with the matching gokv error, like gokv.ErrUnavailable.
+func classifyError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, zk.ErrNoServer), errors.Is(err, zk.ErrConnectionClosed), errors.Is(err, zk.ErrSessionExpired),
+		errors.Is(err, zk.ErrSessionMoved), errors.Is(err, zk.ErrClosing):
+		return gokv.WrapError(gokv.ErrUnavailable, err)
+	case errors.Is(err, zk.ErrBadVersion), errors.Is(err, zk.ErrNodeExists), errors.Is(err, zk.ErrNotEmpty):
+		return gokv.WrapError(gokv.ErrConflict, err)
+	}
+	return util.ClassifyError(err)
+}